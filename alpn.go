@@ -0,0 +1,170 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/google/martian/v3/log"
+	"golang.org/x/net/http2"
+)
+
+// MITMALPNPolicy decides how MITM handles clients that negotiate HTTP/2 with
+// ALPN, which the proxy cannot parse unless an HTTP/2 observer is set with
+// SetHTTP2Observer. It does not apply while an observer is set.
+type MITMALPNPolicy int
+
+const (
+	// MITMALPNDowngrade removes HTTP/2 from the protocols offered to clients
+	// in the MITM handshake, including any added by a function set with
+	// mitm.Config.SetServerConfigFunc, so that clients fall back to HTTP/1.1.
+	// It is the default.
+	MITMALPNDowngrade MITMALPNPolicy = iota
+	// MITMALPNTunnel tunnels the connections of clients that offer HTTP/2 to
+	// the origin without MITM, as if MITM were disabled for them. Clients that
+	// only offer HTTP/1.1 are MITM'd.
+	MITMALPNTunnel
+)
+
+// errHelloSniffed ends the handshake used to read a ClientHello.
+var errHelloSniffed = errors.New("martian: ClientHello read")
+
+// SetMITMALPNPolicy sets how MITM handles clients that negotiate HTTP/2.
+func (p *Proxy) SetMITMALPNPolicy(policy MITMALPNPolicy) {
+	p.mitmALPNPolicy = policy
+}
+
+// downgradeALPN removes HTTP/2 from the protocols offered by cfg and by the
+// configs returned by its GetConfigForClient.
+func downgradeALPN(cfg *tls.Config) {
+	cfg.NextProtos = withoutHTTP2(cfg.NextProtos)
+
+	getConfig := cfg.GetConfigForClient
+	if getConfig == nil {
+		return
+	}
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		c, err := getConfig(hello)
+		if c == nil || err != nil {
+			return c, err
+		}
+
+		c = c.Clone()
+		c.NextProtos = withoutHTTP2(c.NextProtos)
+
+		return c, nil
+	}
+}
+
+// withoutHTTP2 returns protos without HTTP/2.
+func withoutHTTP2(protos []string) []string {
+	var out []string
+	for _, proto := range protos {
+		if proto != http2.NextProtoTLS {
+			out = append(out, proto)
+		}
+	}
+
+	return out
+}
+
+// helloConn reads a ClientHello from r on behalf of conn, recording the bytes
+// read and discarding the alert written when the handshake is aborted.
+type helloConn struct {
+	net.Conn
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+func (c *helloConn) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.buf.Write(b[:n])
+
+	return n, err
+}
+
+func (c *helloConn) Write(b []byte) (int, error) { return len(b), nil }
+
+// sniffALPN reads the ClientHello of a TLS handshake from r, the reader of
+// conn. It returns the protocols offered by the client with ALPN, the server
+// name it sent, and the bytes read from r, which are to be read again by the
+// actual handshake.
+func sniffALPN(conn net.Conn, r io.Reader) ([]string, string, []byte) {
+	hc := &helloConn{Conn: conn, r: r}
+
+	var protos []string
+	var serverName string
+	tls.Server(hc, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			protos = append(protos, hello.SupportedProtos...)
+			serverName = hello.ServerName
+			return nil, errHelloSniffed
+		},
+	}).Handshake()
+
+	return protos, serverName, hc.buf.Bytes()
+}
+
+// offersHTTP2 returns whether protos, as offered by a client, include HTTP/2.
+func offersHTTP2(protos []string) bool {
+	for _, proto := range protos {
+		if proto == http2.NextProtoTLS {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tunnelHello tunnels conn to the origin of the CONNECT request req, sending
+// the bytes of the ClientHello already read from conn first.
+func (p *Proxy) tunnelHello(session *Session, req *http.Request, conn net.Conn, hello []byte) error {
+	host := hostname(req.Host)
+
+	if !p.acquireTunnel() {
+		log.HostErrorf(host, "martian: refusing HTTP/2 tunnel, too many open tunnels: %s", req.URL.Host)
+		return errClose
+	}
+	defer p.releaseTunnel()
+
+	res, cconn, err := p.connect(req)
+	if err != nil {
+		log.HostErrorf(host, "martian: failed to connect to HTTP/2 origin %s: %v", req.URL.Host, err)
+		return errClose
+	}
+	if cconn == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		log.HostErrorf(host, "martian: failed to connect to HTTP/2 origin %s: %s", req.URL.Host, res.Status)
+		return errClose
+	}
+	defer cconn.Close()
+
+	if _, err := cconn.Write(hello); err != nil {
+		log.HostErrorf(host, "martian: failed to write ClientHello to HTTP/2 tunnel: %v", err)
+		return errClose
+	}
+
+	log.HostDebugf(host, "martian: tunneling HTTP/2 connection to %s", req.URL.Host)
+	p.copyTunnel(session, host, req, conn, cconn)
+
+	return errClose
+}
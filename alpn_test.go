@@ -0,0 +1,271 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/mitm"
+	"golang.org/x/net/http2"
+)
+
+// alpnHandshake sends a CONNECT request for target through the proxy at addr,
+// completes a TLS handshake offering protos and returns the state of the
+// connection.
+func alpnHandshake(addr, target string, roots *x509.CertPool, protos []string) (tls.ConnectionState, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest("CONNECT", "//"+target, nil)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	if err := req.Write(conn); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	if res.StatusCode != 200 {
+		return tls.ConnectionState{}, fmt.Errorf("CONNECT: got status %d, want 200", res.StatusCode)
+	}
+
+	tlsconn := tls.Client(conn, &tls.Config{
+		ServerName: "example.com",
+		RootCAs:    roots,
+		NextProtos: protos,
+	})
+	tlsconn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := tlsconn.Handshake(); err != nil {
+		return tls.ConnectionState{}, err
+	}
+
+	return tlsconn.ConnectionState(), nil
+}
+
+// newALPNProxy returns a MITM proxy serving on a new listener, and the
+// authority of its MITM certificates.
+func newALPNProxy(t *testing.T) (*Proxy, net.Listener, *mitm.Config, *x509.CertPool) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	p.SetTimeout(5 * time.Second)
+
+	ca, priv, err := mitm.NewAuthority("martian.proxy", "Martian Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	mc, err := mitm.NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	mc.SkipTLSVerify(true)
+	p.SetMITM(mc)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	return p, l, mc, roots
+}
+
+func TestIntegrationMITMALPNTunnel(t *testing.T) {
+	t.Parallel()
+
+	// The origin speaks HTTP/2 over TLS with a certificate from its own
+	// authority, which the client trusts only when it is tunneled.
+	oca, opriv, err := mitm.NewAuthority("origin", "Origin Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	omc, err := mitm.NewConfig(oca, opriv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	ocfg := omc.TLSForHost("example.com")
+	ocfg.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+
+	ol, err := tls.Listen("tcp", "[::]:0", ocfg)
+	if err != nil {
+		t.Fatalf("tls.Listen(): got %v, want no error", err)
+	}
+	defer ol.Close()
+
+	go func() {
+		for {
+			conn, err := ol.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+				conn.Read(make([]byte, 1))
+			}()
+		}
+	}()
+
+	p, l, _, roots := newALPNProxy(t)
+	defer p.Close()
+
+	p.SetMITMALPNPolicy(MITMALPNTunnel)
+	go p.Serve(l)
+
+	oroots := x509.NewCertPool()
+	oroots.AddCert(oca)
+
+	// Clients offering HTTP/2 are tunneled to the origin.
+	state, err := alpnHandshake(l.Addr().String(), ol.Addr().String(), oroots, []string{http2.NextProtoTLS, "http/1.1"})
+	if err != nil {
+		t.Fatalf("alpnHandshake(): got %v, want no error", err)
+	}
+	if got, want := state.NegotiatedProtocol, http2.NextProtoTLS; got != want {
+		t.Errorf("state.NegotiatedProtocol: got %q, want %q", got, want)
+	}
+
+	// Clients offering only HTTP/1.1 are MITM'd.
+	state, err = alpnHandshake(l.Addr().String(), ol.Addr().String(), roots, []string{"http/1.1"})
+	if err != nil {
+		t.Fatalf("alpnHandshake(): got %v, want no error", err)
+	}
+	if got, want := state.NegotiatedProtocol, "http/1.1"; got != want {
+		t.Errorf("state.NegotiatedProtocol: got %q, want %q", got, want)
+	}
+}
+
+func TestIntegrationMITMALPNDowngrade(t *testing.T) {
+	t.Parallel()
+
+	p, l, mc, roots := newALPNProxy(t)
+	defer p.Close()
+
+	// The server config offers HTTP/2, which the proxy removes.
+	mc.SetServerConfigFunc(func(_ *tls.ClientHelloInfo, base *tls.Config) (*tls.Config, error) {
+		cfg := base.Clone()
+		cfg.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+		return cfg, nil
+	})
+	go p.Serve(l)
+
+	state, err := alpnHandshake(l.Addr().String(), "example.com:443", roots, []string{http2.NextProtoTLS, "http/1.1"})
+	if err != nil {
+		t.Fatalf("alpnHandshake(): got %v, want no error", err)
+	}
+	if got, want := state.NegotiatedProtocol, "http/1.1"; got != want {
+		t.Errorf("state.NegotiatedProtocol: got %q, want %q", got, want)
+	}
+}
+
+func TestIntegrationMITMALPNTunnelMaxDuration(t *testing.T) {
+	t.Parallel()
+
+	oca, opriv, err := mitm.NewAuthority("origin", "Origin Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	omc, err := mitm.NewConfig(oca, opriv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	ocfg := omc.TLSForHost("example.com")
+	ocfg.NextProtos = []string{http2.NextProtoTLS}
+
+	ol, err := tls.Listen("tcp", "[::]:0", ocfg)
+	if err != nil {
+		t.Fatalf("tls.Listen(): got %v, want no error", err)
+	}
+	defer ol.Close()
+
+	// The origin holds the connection open until the proxy closes it.
+	closed := make(chan struct{})
+	go func() {
+		conn, err := ol.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+		conn.Read(make([]byte, 1))
+		close(closed)
+	}()
+
+	p, l, _, _ := newALPNProxy(t)
+	defer p.Close()
+
+	p.SetMITMALPNPolicy(MITMALPNTunnel)
+	p.SetMaxTunnelDuration(100 * time.Millisecond)
+	go p.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest("CONNECT", "//"+ol.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("req.Write(): got %v, want no error", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, 200; got != want {
+		t.Fatalf("res.StatusCode: got %d, want %d", got, want)
+	}
+
+	oroots := x509.NewCertPool()
+	oroots.AddCert(oca)
+	tlsconn := tls.Client(conn, &tls.Config{
+		ServerName: "example.com",
+		RootCAs:    oroots,
+		NextProtos: []string{http2.NextProtoTLS},
+	})
+	tlsconn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := tlsconn.Handshake(); err != nil {
+		t.Fatalf("tlsconn.Handshake(): got %v, want no error", err)
+	}
+
+	// Both sides of the tunnel are closed once it exceeds its maximum
+	// duration.
+	if _, err := tlsconn.Read(make([]byte, 1)); err == nil {
+		t.Error("tlsconn.Read(): got no error, want closed tunnel")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Errorf("tlsconn.Read(): got %v, want closed tunnel before the deadline", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("origin connection: still open, want closed")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/martianurl"
+)
+
+const injectorErrorKey = "auth.InjectorError"
+
+// Injector is a martian.RequestResponseModifier that sets the Authorization
+// header of requests to matched hosts with a token from a token source.
+type Injector struct {
+	tokenSource func(*http.Request) (string, error)
+	scheme      string
+	hosts       []string
+}
+
+// NewAuthInjector returns an Injector that sets the Authorization header of
+// requests to hosts to "<scheme> <token>", where token is returned by
+// tokenSource. Hosts may contain wildcards, such as "*.example.com"; requests
+// to any other host are left unmodified so that tokens are not leaked. An
+// empty scheme sets the Authorization header to the token alone.
+//
+// tokenSource is called for every matched request and may be called
+// concurrently; it is responsible for caching and refreshing tokens. If it
+// returns an error the round trip is skipped and the client receives a 502 Bad
+// Gateway response describing the error.
+func NewAuthInjector(tokenSource func(*http.Request) (string, error), scheme string, hosts ...string) *Injector {
+	return &Injector{
+		tokenSource: tokenSource,
+		scheme:      scheme,
+		hosts:       hosts,
+	}
+}
+
+// ModifyRequest sets the Authorization header on requests to matched hosts.
+func (i *Injector) ModifyRequest(req *http.Request) error {
+	if req.Method == "CONNECT" || !i.matches(req.URL.Hostname()) {
+		return nil
+	}
+
+	token, err := i.tokenSource(req)
+	if err != nil {
+		log.Errorf("auth.Injector: token source failed for %s: %v", req.URL, err)
+
+		ctx := martian.NewContext(req)
+		ctx.Set(injectorErrorKey, err)
+		ctx.SkipRoundTrip()
+
+		return nil
+	}
+
+	if i.scheme != "" {
+		token = i.scheme + " " + token
+	}
+	req.Header.Set("Authorization", token)
+
+	return nil
+}
+
+// ModifyResponse replaces the response to a request whose token could not be
+// retrieved with a 502 Bad Gateway.
+func (i *Injector) ModifyResponse(res *http.Response) error {
+	ctx := martian.NewContext(res.Request)
+	if ctx == nil {
+		return nil
+	}
+	v, ok := ctx.Get(injectorErrorKey)
+	if !ok {
+		return nil
+	}
+
+	if res.Body != nil {
+		res.Body.Close()
+	}
+
+	body := fmt.Sprintf("auth: failed to retrieve token: %v", v)
+
+	res.StatusCode = http.StatusBadGateway
+	res.Status = fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode))
+	res.Header = http.Header{}
+	res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	res.ContentLength = int64(len(body))
+	res.Body = ioutil.NopCloser(strings.NewReader(body))
+
+	return nil
+}
+
+// matches returns whether host is one of the injector's hosts.
+func (i *Injector) matches(host string) bool {
+	for _, h := range i.hosts {
+		if martianurl.MatchHost(host, h) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestInjectorSetsAuthorization(t *testing.T) {
+	calls := 0
+	inj := NewAuthInjector(func(*http.Request) (string, error) {
+		calls++
+		return "secret", nil
+	}, "Bearer", "api.example.com", "*.example.org")
+
+	tt := []struct {
+		url  string
+		want string
+	}{
+		{"http://api.example.com/v1", "Bearer secret"},
+		{"https://api.example.com:8443/v1", "Bearer secret"},
+		{"http://www.example.org/", "Bearer secret"},
+		{"http://example.com/", ""},
+		{"http://api.example.com.evil.com/", ""},
+	}
+
+	for i, tc := range tt {
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		_, remove, err := martian.TestContext(req, nil, nil)
+		if err != nil {
+			t.Fatalf("%d. martian.TestContext(): got %v, want no error", i, err)
+		}
+
+		if err := inj.ModifyRequest(req); err != nil {
+			t.Fatalf("%d. ModifyRequest(): got %v, want no error", i, err)
+		}
+		if got := req.Header.Get("Authorization"); got != tc.want {
+			t.Errorf("%d. req.Header.Get(%q): got %q, want %q", i, "Authorization", got, tc.want)
+		}
+		remove()
+	}
+
+	if got, want := calls, 3; got != want {
+		t.Errorf("token source calls: got %d, want %d", got, want)
+	}
+}
+
+func TestInjectorTokenSourceError(t *testing.T) {
+	inj := NewAuthInjector(func(*http.Request) (string, error) {
+		return "", errors.New("token expired")
+	}, "Bearer", "example.com")
+
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := inj.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("req.Header.Get(%q): got %q, want empty", "Authorization", got)
+	}
+	if !ctx.SkippingRoundTrip() {
+		t.Error("ctx.SkippingRoundTrip(): got false, want true")
+	}
+
+	res := proxyutil.NewResponse(200, nil, req)
+	if err := inj.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, http.StatusBadGateway; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if !strings.Contains(string(got), "token expired") {
+		t.Errorf("res.Body: got %q, want to contain %q", got, "token expired")
+	}
+}
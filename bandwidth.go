@@ -0,0 +1,187 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/trafficshape"
+)
+
+// bandwidth holds the buckets shared by all client connections to cap the
+// throughput of the proxy. The buckets are created with the first cap and
+// then kept, changing only their capacity, so that the connections holding
+// them are never left with a closed bucket; they are closed by Proxy.Close.
+type bandwidth struct {
+	mu         sync.RWMutex
+	write      *trafficshape.Bucket
+	read       *trafficshape.Bucket
+	writeLimit int64
+	readLimit  int64
+}
+
+// SetGlobalBandwidth caps the total rate at which the proxy writes to its
+// clients at bytesPerSec, shared by all client connections, without the URL
+// matching of trafficshape. It covers responses and the client side of CONNECT
+// tunnels, which are then copied through userspace buffers rather than by the
+// connections themselves. Bytes are counted as sent to the client, after TLS
+// decryption for MITMed connections. Connections accepted by a
+// trafficshape.Listener are limited by both, so the lower rate applies.
+//
+// Changing the cap applies to the connections already throttled by it. A
+// bytesPerSec of 0, the default, removes the cap.
+func (p *Proxy) SetGlobalBandwidth(bytesPerSec int64) {
+	p.bandwidth.mu.Lock()
+	defer p.bandwidth.mu.Unlock()
+
+	p.bandwidth.write = setBucket(p.bandwidth.write, bytesPerSec)
+	p.bandwidth.writeLimit = bytesPerSec
+}
+
+// SetGlobalReadBandwidth caps the total rate at which the proxy reads from its
+// clients at bytesPerSec, as SetGlobalBandwidth does for writes. A bytesPerSec
+// of 0, the default, removes the cap.
+func (p *Proxy) SetGlobalReadBandwidth(bytesPerSec int64) {
+	p.bandwidth.mu.Lock()
+	defer p.bandwidth.mu.Unlock()
+
+	p.bandwidth.read = setBucket(p.bandwidth.read, bytesPerSec)
+	p.bandwidth.readLimit = bytesPerSec
+}
+
+// setBucket sets the capacity of b, drained every second, to bytesPerSec,
+// creating b if it is nil, and returns it. If bytesPerSec is not positive, the
+// capacity of an existing b is lifted so that its waiting connections proceed,
+// and a nil b is left nil.
+func setBucket(b *trafficshape.Bucket, bytesPerSec int64) *trafficshape.Bucket {
+	if bytesPerSec <= 0 {
+		if b != nil {
+			log.Debugf("martian: removing global bandwidth cap")
+			b.SetCapacity(math.MaxInt64)
+		}
+		return b
+	}
+
+	log.Debugf("martian: capping global bandwidth at %d bytes per second", bytesPerSec)
+	if b == nil {
+		return trafficshape.NewBucket(bytesPerSec, time.Second)
+	}
+	b.SetCapacity(bytesPerSec)
+
+	return b
+}
+
+// buckets returns the write and read buckets of the current caps; the bucket
+// of a direction without a cap is nil.
+func (bw *bandwidth) buckets() (write, read *trafficshape.Bucket) {
+	bw.mu.RLock()
+	defer bw.mu.RUnlock()
+
+	if bw.writeLimit > 0 {
+		write = bw.write
+	}
+	if bw.readLimit > 0 {
+		read = bw.read
+	}
+
+	return write, read
+}
+
+// close closes the buckets, which stops their drain goroutines, and removes
+// the caps.
+func (bw *bandwidth) close() {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	for _, b := range []*trafficshape.Bucket{bw.write, bw.read} {
+		if b != nil {
+			b.Close()
+		}
+	}
+	bw.write, bw.read = nil, nil
+	bw.writeLimit, bw.readLimit = 0, 0
+}
+
+// throttle returns conn limited by the global bandwidth caps, or conn itself if
+// there are none.
+func (p *Proxy) throttle(conn net.Conn) net.Conn {
+	if write, read := p.bandwidth.buckets(); write == nil && read == nil {
+		return conn
+	}
+
+	return &throttledConn{
+		Conn: conn,
+		bw:   &p.bandwidth,
+	}
+}
+
+// throttledConn is a net.Conn whose reads and writes are limited by the
+// buckets of the current global bandwidth caps, looked up for each call.
+type throttledConn struct {
+	net.Conn
+	bw *bandwidth
+}
+
+func (c *throttledConn) Read(b []byte) (int, error) {
+	_, read := c.bw.buckets()
+	if read == nil {
+		return c.Conn.Read(b)
+	}
+
+	n, err := read.FillThrottle(func(remaining int64) (int64, error) {
+		if l := int64(len(b)); remaining > l {
+			remaining = l
+		}
+
+		n, err := c.Conn.Read(b[:remaining])
+		return int64(n), err
+	})
+
+	return int(n), err
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	var total int
+	for len(b) > 0 {
+		write, _ := c.bw.buckets()
+		if write == nil {
+			n, err := c.Conn.Write(b)
+			return total + n, err
+		}
+
+		var max int64
+		n, err := write.FillThrottle(func(remaining int64) (int64, error) {
+			max = remaining
+			if l := int64(len(b)); max > l {
+				max = l
+			}
+
+			n, err := c.Conn.Write(b[:max])
+			return int64(n), err
+		})
+
+		total += int(n)
+		if err != nil {
+			return total, err
+		}
+		b = b[max:]
+	}
+
+	return total, nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package blocklist provides a modifier that denies requests to the hosts and
+// URLs of a large blocklist.
+package blocklist
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+const blockedKey = "blocklist.Blocked"
+
+func init() {
+	parse.Register("blocklist.Modifier", modifierFromJSON)
+}
+
+// Matcher matches requests against a list of blocked hosts and URLs. Lookups
+// are checked against a Bloom filter first, so that the vast majority of
+// requests, which are not blocked, are rejected without touching the entries;
+// a possible match is confirmed against the exact entries to rule out false
+// positives.
+//
+// An entry without a path, such as "example.com", blocks the host and all of
+// its subdomains. An entry with a path blocks the URLs with that host and path,
+// or, if the path ends in a slash as in "example.com/ads/", the URLs with that
+// host and a path under it. The scheme, port and query are ignored.
+type Matcher struct {
+	mu      sync.RWMutex
+	filter  *bloom
+	entries map[string]bool
+}
+
+// NewMatcher returns an empty blocklist matcher.
+func NewMatcher() *Matcher {
+	return &Matcher{
+		filter:  newBloom(1024),
+		entries: make(map[string]bool),
+	}
+}
+
+// Add adds entry to the blocklist.
+func (m *Matcher) Add(entry string) {
+	entry = normalize(entry)
+	if entry == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries[entry] {
+		return
+	}
+	m.entries[entry] = true
+
+	// Rebuild the filter at twice the size once it is full, to keep the false
+	// positive rate low.
+	if len(m.entries)*bloomBitsPerEntry > int(m.filter.n) {
+		m.filter = newBloom(2 * len(m.entries))
+		for e := range m.entries {
+			m.filter.add(e)
+		}
+		return
+	}
+	m.filter.add(entry)
+}
+
+// Load adds the entries read from r, one per line. Blank lines and lines
+// starting with # are ignored.
+func (m *Matcher) Load(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m.Add(line)
+	}
+
+	return s.Err()
+}
+
+// Len returns the number of entries in the blocklist.
+func (m *Matcher) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.entries)
+}
+
+// MatchRequest returns whether req is blocked.
+func (m *Matcher) MatchRequest(req *http.Request) bool {
+	host := req.URL.Hostname()
+	if host == "" {
+		host = req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+
+	return m.match(strings.ToLower(host), req.URL.Path)
+}
+
+// MatchResponse returns whether the request of res is blocked.
+func (m *Matcher) MatchResponse(res *http.Response) bool {
+	if res.Request == nil {
+		return false
+	}
+
+	return m.MatchRequest(res.Request)
+}
+
+func (m *Matcher) match(host, path string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// The host and each of its parent domains.
+	for h := host; h != ""; {
+		if m.contains(h) {
+			return true
+		}
+
+		i := strings.IndexByte(h, '.')
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+
+	// Each prefix of the path ending in a slash, and the full path.
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && m.contains(host+path[:i+1]) {
+			return true
+		}
+	}
+
+	return path != "" && m.contains(host+path)
+}
+
+// contains returns whether entry is in the blocklist. m.mu must be held.
+func (m *Matcher) contains(entry string) bool {
+	return m.filter.mayContain(entry) && m.entries[entry]
+}
+
+// normalize returns entry as it is stored: lowercase host, without a scheme,
+// port, query or trailing dot.
+func normalize(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if i := strings.Index(entry, "://"); i >= 0 {
+		entry = entry[i+3:]
+	}
+	if i := strings.IndexAny(entry, "?#"); i >= 0 {
+		entry = entry[:i]
+	}
+
+	host, path := entry, ""
+	if i := strings.IndexByte(entry, '/'); i >= 0 {
+		host, path = entry[:i], entry[i:]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return ""
+	}
+
+	// A bare slash blocks the whole host.
+	if path == "/" {
+		path = ""
+	}
+
+	return host + path
+}
+
+// Modifier is a martian.RequestResponseModifier that denies requests matched
+// by its blocklist without a round trip. CONNECT requests are let through, so
+// HTTPS requests are only denied when the proxy is configured to MITM them.
+type Modifier struct {
+	*Matcher
+
+	status int
+	body   []byte
+}
+
+type modifierJSON struct {
+	Entries    []string             `json:"entries"`
+	File       string               `json:"file"`
+	StatusCode int                  `json:"statusCode"`
+	Body       string               `json:"body"`
+	Scope      []parse.ModifierType `json:"scope"`
+}
+
+// NewModifier returns a modifier with an empty blocklist that denies matched
+// requests with a 403 Forbidden response.
+func NewModifier() *Modifier {
+	return &Modifier{
+		Matcher: NewMatcher(),
+		status:  http.StatusForbidden,
+	}
+}
+
+// SetResponse sets the status code and body of the response to denied
+// requests.
+func (m *Modifier) SetResponse(status int, body []byte) {
+	m.status = status
+	m.body = body
+}
+
+// modifierFromJSON builds a blocklist.Modifier from JSON. Entries may be given
+// inline, loaded from a file, or both.
+//
+// Example JSON:
+// {
+//   "blocklist.Modifier": {
+//     "scope": ["request", "response"],
+//     "entries": ["ads.example.com", "example.com/tracking/"],
+//     "file": "/etc/martian/blocklist.txt",
+//     "statusCode": 403,
+//     "body": "blocked"
+//   }
+// }
+func modifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &modifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	mod := NewModifier()
+	for _, e := range msg.Entries {
+		mod.Add(e)
+	}
+	if msg.File != "" {
+		f, err := os.Open(msg.File)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if err := mod.Load(f); err != nil {
+			return nil, fmt.Errorf("blocklist.Modifier: failed to load %s: %v", msg.File, err)
+		}
+	}
+
+	status := msg.StatusCode
+	if status == 0 {
+		status = http.StatusForbidden
+	}
+	mod.SetResponse(status, []byte(msg.Body))
+
+	return parse.NewResult(mod, msg.Scope)
+}
+
+// ModifyRequest skips the round trip of blocked requests.
+func (m *Modifier) ModifyRequest(req *http.Request) error {
+	if req.Method == "CONNECT" || !m.MatchRequest(req) {
+		return nil
+	}
+
+	log.Debugf("blocklist.ModifyRequest: blocking %s", req.URL)
+
+	ctx := martian.NewContext(req)
+	ctx.Set(blockedKey, true)
+	ctx.SkipRoundTrip()
+
+	return nil
+}
+
+// ModifyResponse replaces the response to a blocked request with the denial
+// response.
+func (m *Modifier) ModifyResponse(res *http.Response) error {
+	ctx := martian.NewContext(res.Request)
+	if ctx == nil {
+		return nil
+	}
+	if _, ok := ctx.Get(blockedKey); !ok {
+		return nil
+	}
+
+	if res.Body != nil {
+		res.Body.Close()
+	}
+
+	res.StatusCode = m.status
+	res.Status = fmt.Sprintf("%d %s", m.status, http.StatusText(m.status))
+	res.Header = http.Header{}
+	if len(m.body) > 0 {
+		res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	res.Header.Set("Content-Length", strconv.Itoa(len(m.body)))
+	res.ContentLength = int64(len(m.body))
+	res.Body = ioutil.NopCloser(strings.NewReader(string(m.body)))
+
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocklist
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher()
+	if err := m.Load(strings.NewReader(`
+# Hosts
+ads.example.com
+Tracker.Example.ORG.
+
+# URLs
+https://example.net/ads/
+example.net/pixel.gif?id=1
+`)); err != nil {
+		t.Fatalf("m.Load(): got %v, want no error", err)
+	}
+
+	if got, want := m.Len(), 4; got != want {
+		t.Errorf("m.Len(): got %d, want %d", got, want)
+	}
+
+	tt := []struct {
+		url  string
+		want bool
+	}{
+		{"http://ads.example.com/", true},
+		{"https://ads.example.com:8443/banner", true},
+		{"http://cdn.ads.example.com/", true},
+		{"http://example.com/", false},
+		{"http://notads.example.com/", false},
+		{"http://tracker.example.org/t", true},
+		{"http://example.net/ads/banner.png", true},
+		{"http://example.net/ads/", true},
+		{"http://example.net/ads", false},
+		{"http://example.net/pixel.gif?id=2", true},
+		{"http://example.net/pixel.gif.bak", false},
+		{"http://example.net/", false},
+		{"http://sub.example.net/ads/banner.png", false},
+	}
+
+	for _, tc := range tt {
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+
+		if got := m.MatchRequest(req); got != tc.want {
+			t.Errorf("MatchRequest(%s): got %t, want %t", tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestMatcherLarge(t *testing.T) {
+	m := NewMatcher()
+	for i := 0; i < 100000; i++ {
+		m.Add(fmt.Sprintf("host%d.example.com", i))
+	}
+
+	for _, i := range []int{0, 1, 5000, 99999} {
+		req, err := http.NewRequest("GET", fmt.Sprintf("http://host%d.example.com/", i), nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		if !m.MatchRequest(req) {
+			t.Errorf("MatchRequest(host%d): got false, want true", i)
+		}
+	}
+
+	// Requests for hosts that are not blocked are rejected by the filter
+	// nearly always, and never matched.
+	positives := 0
+	for i := 100000; i < 110000; i++ {
+		host := fmt.Sprintf("host%d.example.com", i)
+		if m.filter.mayContain(host) {
+			positives++
+		}
+
+		req, err := http.NewRequest("GET", "http://"+host+"/", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		if m.MatchRequest(req) {
+			t.Fatalf("MatchRequest(%s): got true, want false", host)
+		}
+	}
+	if positives > 500 {
+		t.Errorf("false positives: got %d of 10000, want at most 500", positives)
+	}
+}
+
+func TestModifierBlocks(t *testing.T) {
+	mod := NewModifier()
+	mod.Add("blocked.example.com")
+	mod.SetResponse(451, []byte("unavailable"))
+
+	for _, tc := range []struct {
+		url     string
+		blocked bool
+	}{
+		{"http://blocked.example.com/", true},
+		{"http://allowed.example.com/", false},
+	} {
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		ctx, remove, err := martian.TestContext(req, nil, nil)
+		if err != nil {
+			t.Fatalf("martian.TestContext(): got %v, want no error", err)
+		}
+		defer remove()
+
+		if err := mod.ModifyRequest(req); err != nil {
+			t.Fatalf("ModifyRequest(): got %v, want no error", err)
+		}
+		if got := ctx.SkippingRoundTrip(); got != tc.blocked {
+			t.Errorf("%s: ctx.SkippingRoundTrip(): got %t, want %t", tc.url, got, tc.blocked)
+		}
+
+		res := proxyutil.NewResponse(200, nil, req)
+		if err := mod.ModifyResponse(res); err != nil {
+			t.Fatalf("ModifyResponse(): got %v, want no error", err)
+		}
+
+		want := 200
+		if tc.blocked {
+			want = 451
+		}
+		if got := res.StatusCode; got != want {
+			t.Errorf("%s: res.StatusCode: got %d, want %d", tc.url, got, want)
+		}
+		if tc.blocked {
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+			}
+			if got, want := string(body), "unavailable"; got != want {
+				t.Errorf("res.Body: got %q, want %q", got, want)
+			}
+		}
+	}
+}
+
+func TestModifierFromJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blocklist")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "blocklist.txt")
+	if err := ioutil.WriteFile(file, []byte("fromfile.example.com\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile(): got %v, want no error", err)
+	}
+
+	msg := []byte(fmt.Sprintf(`{
+		"blocklist.Modifier": {
+			"scope": ["request", "response"],
+			"entries": ["inline.example.com"],
+			"file": %q
+		}
+	}`, file))
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	mod, ok := r.RequestModifier().(*Modifier)
+	if !ok {
+		t.Fatal("r.RequestModifier(): got not *Modifier, want *Modifier")
+	}
+	if got, want := mod.Len(), 2; got != want {
+		t.Errorf("mod.Len(): got %d, want %d", got, want)
+	}
+	if got, want := mod.status, 403; got != want {
+		t.Errorf("mod.status: got %d, want %d", got, want)
+	}
+}
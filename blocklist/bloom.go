@@ -0,0 +1,77 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blocklist
+
+import (
+	"hash/fnv"
+)
+
+// bloomBitsPerEntry and bloomHashes give a false positive rate of about 1%.
+const (
+	bloomBitsPerEntry = 10
+	bloomHashes       = 7
+)
+
+// bloom is a Bloom filter of strings sized for a given number of entries.
+type bloom struct {
+	bits []uint64
+	n    uint64
+}
+
+// newBloom returns a Bloom filter for up to capacity entries.
+func newBloom(capacity int) *bloom {
+	if capacity < 1 {
+		capacity = 1
+	}
+	n := uint64(capacity * bloomBitsPerEntry)
+
+	return &bloom{
+		bits: make([]uint64, (n+63)/64),
+		n:    n,
+	}
+}
+
+// add adds s to the filter.
+func (b *bloom) add(s string) {
+	h1, h2 := bloomHash(s)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % b.n
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain returns false if s has not been added to the filter, and true if
+// it probably has.
+func (b *bloom) mayContain(s string) bool {
+	h1, h2 := bloomHash(s)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % b.n
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloomHash returns the two hashes of s combined to derive the bit positions
+// of s (Kirsch and Mitzenmacher).
+func bloomHash(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	sum := h.Sum64()
+
+	return sum, sum>>33 | 1
+}
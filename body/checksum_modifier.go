@@ -0,0 +1,200 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+const checksumKey = "body.Checksum"
+
+func init() {
+	parse.Register("body.ChecksumModifier", checksumModifierFromJSON)
+}
+
+// ChecksumOptions configures a ChecksumModifier.
+type ChecksumOptions struct {
+	// Expected is the SHA-256 digest the response bodies must have. If it is
+	// nil, digests are computed but not verified.
+	Expected []byte
+
+	// Header is the name of a trailer, such as "Digest", in which the digest
+	// is sent to the client as "SHA-256=" followed by the base64 digest. If it
+	// is empty, no trailer is sent.
+	Header string
+}
+
+// ChecksumModifier computes the SHA-256 digest of response bodies as they are
+// sent to the client.
+type ChecksumModifier struct {
+	opts ChecksumOptions
+}
+
+type checksumModifierJSON struct {
+	Expected string               `json:"expected"`
+	Header   string               `json:"header"`
+	Scope    []parse.ModifierType `json:"scope"`
+}
+
+// NewChecksumModifier returns a modifier that computes the SHA-256 digest of
+// response bodies. The body is hashed as it is streamed to the client, without
+// buffering it, so the digest is only known once the body has been sent. It is
+// stored on the context of the request, where Checksum retrieves it.
+//
+// The digest covers the body as sent to the client: after the transfer coding,
+// such as chunked, has been removed, but with any content coding, such as gzip,
+// still applied. This matches the Digest header of RFC 3230.
+//
+// Since the digest is not known before the body is sent, it can only be sent to
+// the client in a trailer. Responses are therefore sent chunked when
+// opts.Header is set, except to HTTP/1.0 clients, which do not support
+// trailers. If opts.Expected is set and the digest does not match, the body is
+// cut off with an error once it has been read, so that the client sees an
+// incomplete response.
+func NewChecksumModifier(opts ChecksumOptions) *ChecksumModifier {
+	return &ChecksumModifier{
+		opts: opts,
+	}
+}
+
+// checksumModifierFromJSON builds a body.ChecksumModifier from JSON. The
+// expected digest is hex encoded.
+//
+// Example JSON:
+// {
+//   "body.ChecksumModifier": {
+//     "scope": ["response"],
+//     "expected": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+//     "header": "Digest"
+//   }
+// }
+func checksumModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &checksumModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	opts := ChecksumOptions{
+		Header: msg.Header,
+	}
+	if msg.Expected != "" {
+		expected, err := hex.DecodeString(msg.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("body.ChecksumModifier: invalid expected digest: %v", err)
+		}
+		if len(expected) != sha256.Size {
+			return nil, fmt.Errorf("body.ChecksumModifier: expected digest has %d bytes, want %d", len(expected), sha256.Size)
+		}
+		opts.Expected = expected
+	}
+
+	return parse.NewResult(NewChecksumModifier(opts), msg.Scope)
+}
+
+// Checksum returns the SHA-256 digest of the body of the response to the
+// request of ctx, computed by a ChecksumModifier. It returns false until the
+// body has been sent in full.
+func Checksum(ctx *martian.Context) ([]byte, bool) {
+	v, ok := ctx.Get(checksumKey)
+	if !ok {
+		return nil, false
+	}
+
+	return v.([]byte), true
+}
+
+// ModifyResponse wraps the body of res to compute its digest.
+func (m *ChecksumModifier) ModifyResponse(res *http.Response) error {
+	if res.Body == nil || res.Body == http.NoBody {
+		return nil
+	}
+	if res.Request != nil && res.Request.Method == "HEAD" {
+		return nil
+	}
+	switch res.StatusCode {
+	case http.StatusNoContent, http.StatusNotModified:
+		return nil
+	}
+
+	cb := &checksumBody{
+		ReadCloser: res.Body,
+		h:          sha256.New(),
+		res:        res,
+		opts:       m.opts,
+	}
+	res.Body = cb
+
+	if m.opts.Header != "" && (res.Request == nil || res.Request.ProtoAtLeast(1, 1)) {
+		res.ContentLength = -1
+		res.Header.Del("Content-Length")
+		res.TransferEncoding = []string{"chunked"}
+		if res.Trailer == nil {
+			res.Trailer = http.Header{}
+		}
+		res.Trailer[http.CanonicalHeaderKey(m.opts.Header)] = nil
+		cb.trailer = true
+	}
+
+	return nil
+}
+
+// checksumBody hashes a response body as it is read.
+type checksumBody struct {
+	io.ReadCloser
+	h       hash.Hash
+	res     *http.Response
+	opts    ChecksumOptions
+	trailer bool
+	done    bool
+}
+
+func (b *checksumBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.h.Write(p[:n])
+
+	if err != io.EOF || b.done {
+		return n, err
+	}
+	b.done = true
+
+	sum := b.h.Sum(nil)
+	if b.res.Request != nil {
+		if ctx := martian.NewContext(b.res.Request); ctx != nil {
+			ctx.Set(checksumKey, sum)
+		}
+	}
+	if b.trailer {
+		b.res.Trailer.Set(b.opts.Header, "SHA-256="+base64.StdEncoding.EncodeToString(sum))
+	}
+
+	if b.opts.Expected != nil && !bytes.Equal(sum, b.opts.Expected) {
+		log.Errorf("body.ChecksumModifier: digest mismatch: got %x, want %x", sum, b.opts.Expected)
+		return n, fmt.Errorf("body.ChecksumModifier: SHA-256 digest mismatch: got %x, want %x", sum, b.opts.Expected)
+	}
+
+	return n, err
+}
@@ -0,0 +1,177 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestChecksumModifier(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	res := chunkedResponse(t, "text/plain")
+	res.Request = req
+
+	mod := NewChecksumModifier(ChecksumOptions{})
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	if _, ok := Checksum(ctx); ok {
+		t.Fatal("Checksum(): got digest before body was read, want none")
+	}
+
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if want := "hello world"; string(got) != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+
+	want := sha256.Sum256([]byte("hello world"))
+	sum, ok := Checksum(ctx)
+	if !ok {
+		t.Fatal("Checksum(): got no digest, want digest")
+	}
+	if !bytes.Equal(sum, want[:]) {
+		t.Errorf("Checksum(): got %x, want %x", sum, want)
+	}
+	if got := res.Trailer; got != nil {
+		t.Errorf("res.Trailer: got %v, want nil", got)
+	}
+}
+
+func TestChecksumModifierTrailer(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	res := proxyutil.NewResponse(200, strings.NewReader("hello world"), req)
+	res.ContentLength = 11
+	res.Header.Set("Content-Length", "11")
+
+	mod := NewChecksumModifier(ChecksumOptions{Header: "Digest"})
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := res.Write(buf); err != nil {
+		t.Fatalf("res.Write(): got %v, want no error", err)
+	}
+
+	got, err := http.ReadResponse(bufio.NewReader(buf), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	if _, err := ioutil.ReadAll(got.Body); err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+
+	sum := sha256.Sum256([]byte("hello world"))
+	want := "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+	if got := got.Trailer.Get("Digest"); got != want {
+		t.Errorf("res.Trailer.Get(%q): got %q, want %q", "Digest", got, want)
+	}
+}
+
+func TestChecksumModifierMismatch(t *testing.T) {
+	sum := sha256.Sum256([]byte("hello world"))
+
+	for _, tc := range []struct {
+		body    string
+		wantErr bool
+	}{
+		{"hello world", false},
+		{"hello there", true},
+	} {
+		res := proxyutil.NewResponse(200, strings.NewReader(tc.body), nil)
+
+		mod := NewChecksumModifier(ChecksumOptions{Expected: sum[:]})
+		if err := mod.ModifyResponse(res); err != nil {
+			t.Fatalf("ModifyResponse(): got %v, want no error", err)
+		}
+
+		_, err := ioutil.ReadAll(res.Body)
+		if got := err != nil; got != tc.wantErr {
+			t.Errorf("%q: ioutil.ReadAll(): got error %v, want error %t", tc.body, err, tc.wantErr)
+		}
+	}
+}
+
+func TestChecksumModifierFromJSON(t *testing.T) {
+	sum := sha256.Sum256(nil)
+
+	msg := []byte(fmt.Sprintf(`{
+		"body.ChecksumModifier": {
+			"scope": ["response"],
+			"expected": "%x",
+			"header": "Digest"
+		}
+	}`, sum))
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	mod, ok := r.ResponseModifier().(*ChecksumModifier)
+	if !ok {
+		t.Fatal("r.ResponseModifier(): got not *ChecksumModifier, want *ChecksumModifier")
+	}
+	if !bytes.Equal(mod.opts.Expected, sum[:]) {
+		t.Errorf("mod.opts.Expected: got %x, want %x", mod.opts.Expected, sum)
+	}
+	if got, want := mod.opts.Header, "Digest"; got != want {
+		t.Errorf("mod.opts.Header: got %q, want %q", got, want)
+	}
+
+	msg = []byte(`{
+		"body.ChecksumModifier": {
+			"scope": ["response"],
+			"expected": "abcd"
+		}
+	}`)
+	if _, err := parse.FromJSON(msg); err == nil {
+		t.Error("parse.FromJSON(): got no error, want error for short digest")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("body.DechunkModifier", dechunkModifierFromJSON)
+}
+
+// DechunkModifier rewrites chunked responses to responses with a
+// Content-Length, for clients that do not handle chunked encoding.
+type DechunkModifier struct {
+	maxSize int64
+}
+
+type dechunkModifierJSON struct {
+	MaxSize int64                `json:"maxSize"`
+	Scope   []parse.ModifierType `json:"scope"`
+}
+
+// NewDechunkModifier returns a modifier that buffers chunked response bodies of
+// up to maxSize bytes and sends them with a Content-Length instead of
+// Transfer-Encoding: chunked. Larger bodies are passed through chunked, as they
+// were received.
+//
+// Each response being dechunked holds up to maxSize bytes in memory, and is
+// delayed until its body has been read in full. Streaming responses, such as
+// text/event-stream, are never buffered, since they are not complete until the
+// connection closes.
+func NewDechunkModifier(maxSize int64) *DechunkModifier {
+	return &DechunkModifier{
+		maxSize: maxSize,
+	}
+}
+
+// dechunkModifierFromJSON builds a body.DechunkModifier from JSON.
+//
+// Example JSON:
+// {
+//   "body.DechunkModifier": {
+//     "scope": ["response"],
+//     "maxSize": 1048576
+//   }
+// }
+func dechunkModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &dechunkModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+	if msg.MaxSize <= 0 {
+		return nil, fmt.Errorf("body.DechunkModifier: maxSize must be positive, got %d", msg.MaxSize)
+	}
+
+	return parse.NewResult(NewDechunkModifier(msg.MaxSize), msg.Scope)
+}
+
+// ModifyResponse buffers a chunked response body and sets its Content-Length
+// if it is no larger than the maximum size.
+func (m *DechunkModifier) ModifyResponse(res *http.Response) error {
+	if !isChunked(res) || isStreaming(res) {
+		return nil
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, m.maxSize+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(buf)) > m.maxSize {
+		log.Debugf("body.DechunkModifier: body exceeds %d bytes, passing through chunked", m.maxSize)
+		res.Body = &prefixedBody{
+			Reader: io.MultiReader(bytes.NewReader(buf), res.Body),
+			Closer: res.Body,
+		}
+		return nil
+	}
+	res.Body.Close()
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	res.ContentLength = int64(len(buf))
+	res.TransferEncoding = nil
+	res.Header.Del("Transfer-Encoding")
+	res.Header.Set("Content-Length", strconv.Itoa(len(buf)))
+
+	return nil
+}
+
+// isChunked returns whether res has a chunked body.
+func isChunked(res *http.Response) bool {
+	if res.Body == nil || res.Body == http.NoBody {
+		return false
+	}
+
+	for _, te := range res.TransferEncoding {
+		if te == "chunked" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isStreaming returns whether res has a content type that is streamed
+// indefinitely.
+func isStreaming(res *http.Response) bool {
+	mt, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return mt == "text/event-stream"
+}
+
+// prefixedBody is a response body whose first bytes have been read ahead.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
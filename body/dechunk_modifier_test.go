@@ -0,0 +1,145 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3/parse"
+)
+
+func chunkedResponse(t *testing.T, contentType string) *http.Response {
+	t.Helper()
+
+	raw := "HTTP/1.1 200 OK\r\n" +
+		"Content-Type: " + contentType + "\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" +
+		"5\r\nhello\r\n" +
+		"6\r\n world\r\n" +
+		"0\r\n\r\n"
+
+	res, err := http.ReadResponse(bufio.NewReader(strings.NewReader(raw)), nil)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+
+	return res
+}
+
+func TestDechunkModifier(t *testing.T) {
+	tt := []struct {
+		name        string
+		maxSize     int64
+		contentType string
+		chunked     bool
+	}{
+		{"under limit", 1024, "text/plain", false},
+		{"at limit", 11, "text/plain", false},
+		{"over limit", 10, "text/plain", true},
+		{"streaming", 1024, "text/event-stream", true},
+	}
+
+	for _, tc := range tt {
+		res := chunkedResponse(t, tc.contentType)
+
+		mod := NewDechunkModifier(tc.maxSize)
+		if err := mod.ModifyResponse(res); err != nil {
+			t.Fatalf("%s: ModifyResponse(): got %v, want no error", tc.name, err)
+		}
+
+		var buf bytes.Buffer
+		if err := res.Write(&buf); err != nil {
+			t.Fatalf("%s: res.Write(): got %v, want no error", tc.name, err)
+		}
+		wire := buf.String()
+
+		if got := strings.Contains(wire, "Transfer-Encoding: chunked"); got != tc.chunked {
+			t.Errorf("%s: chunked: got %t, want %t\n%s", tc.name, got, tc.chunked, wire)
+		}
+		if !tc.chunked && !strings.Contains(wire, "Content-Length: 11\r\n") {
+			t.Errorf("%s: Content-Length: want 11\n%s", tc.name, wire)
+		}
+
+		wres, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+		if err != nil {
+			t.Fatalf("%s: http.ReadResponse(): got %v, want no error", tc.name, err)
+		}
+		got, err := ioutil.ReadAll(wres.Body)
+		if err != nil {
+			t.Fatalf("%s: ioutil.ReadAll(): got %v, want no error", tc.name, err)
+		}
+		if want := "hello world"; string(got) != want {
+			t.Errorf("%s: body: got %q, want %q", tc.name, got, want)
+		}
+	}
+}
+
+func TestDechunkModifierIgnoresFixedLength(t *testing.T) {
+	raw := "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello"
+	res, err := http.ReadResponse(bufio.NewReader(strings.NewReader(raw)), nil)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	body := res.Body
+
+	if err := NewDechunkModifier(1024).ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if res.Body != body {
+		t.Error("res.Body: got replaced, want unchanged")
+	}
+}
+
+func TestDechunkModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"body.DechunkModifier": {
+			"scope": ["response"],
+			"maxSize": 1024
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+	resmod := r.ResponseModifier()
+	if resmod == nil {
+		t.Fatal("resmod: got nil, want not nil")
+	}
+
+	res := chunkedResponse(t, "text/plain")
+	if err := resmod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.ContentLength, int64(11); got != want {
+		t.Errorf("res.ContentLength: got %d, want %d", got, want)
+	}
+
+	msg = []byte(`{
+		"body.DechunkModifier": {
+			"scope": ["response"],
+			"maxSize": 0
+		}
+	}`)
+	if _, err := parse.FromJSON(msg); err == nil {
+		t.Error("parse.FromJSON(): got nil, want error for zero maxSize")
+	}
+}
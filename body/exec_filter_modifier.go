@@ -0,0 +1,204 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+const (
+	// defaultExecFilterTimeout is the default time allowed for a filter process
+	// to run.
+	defaultExecFilterTimeout = 10 * time.Second
+
+	// defaultExecFilterMaxSize is the default limit on the size of the bodies
+	// passed to and read from a filter process.
+	defaultExecFilterMaxSize = 10 << 20
+)
+
+var errExecFilterTooLarge = errors.New("body exceeds size limit")
+
+// ExecFilterModifier rewrites response bodies by piping them through an
+// external command.
+type ExecFilterModifier struct {
+	cmd     []string
+	timeout time.Duration
+	maxSize int64
+}
+
+// NewExecFilterModifier returns a response modifier that runs cmd for each
+// response, streams the response body to its standard input and replaces the
+// body with its standard output; for example, {"/usr/bin/jq", ".items"}.
+// Content-Length is set to the size of the output. Wrap the modifier in a
+// filter to rewrite only some responses.
+//
+// The body is passed as received from the origin, so use
+// header.NewAcceptEncodingModifier("identity") to avoid compressed bodies.
+//
+// cmd[0] must be an absolute path to an executable file; it is run directly,
+// not through a shell, with cmd[1:] as its arguments, an empty environment and
+// the temporary directory as its working directory. The command is run with
+// the privileges of the proxy, so only use trusted commands. For the same
+// reason, this modifier cannot be configured from JSON. Only the process itself
+// is killed on timeout, so the command should not leave children running that
+// hold its standard output open.
+//
+// If the process fails, exits with a non-zero status, runs for longer than the
+// timeout or reads or writes more than the size limit, the response is
+// replaced by an empty 502 Bad Gateway and the error is returned.
+func NewExecFilterModifier(cmd []string) (*ExecFilterModifier, error) {
+	if len(cmd) == 0 {
+		return nil, errors.New("body.ExecFilterModifier: empty command")
+	}
+	if !filepath.IsAbs(cmd[0]) {
+		return nil, fmt.Errorf("body.ExecFilterModifier: command %q is not an absolute path", cmd[0])
+	}
+
+	fi, err := os.Stat(cmd[0])
+	if err != nil {
+		return nil, fmt.Errorf("body.ExecFilterModifier: %v", err)
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+		return nil, fmt.Errorf("body.ExecFilterModifier: command %q is not an executable file", cmd[0])
+	}
+
+	return &ExecFilterModifier{
+		cmd:     append([]string(nil), cmd...),
+		timeout: defaultExecFilterTimeout,
+		maxSize: defaultExecFilterMaxSize,
+	}, nil
+}
+
+// SetTimeout sets the time allowed for the process to run for each response.
+// The default is 10 seconds.
+func (m *ExecFilterModifier) SetTimeout(d time.Duration) {
+	m.timeout = d
+}
+
+// SetMaxSize sets the largest body, in bytes, that is passed to the process,
+// and the largest output that is read from it. The default is 10 MiB.
+func (m *ExecFilterModifier) SetMaxSize(size int64) {
+	m.maxSize = size
+}
+
+// ModifyResponse replaces the response body with the output of the command.
+func (m *ExecFilterModifier) ModifyResponse(res *http.Response) error {
+	var in io.Reader = http.NoBody
+	if res.Body != nil {
+		defer res.Body.Close()
+		in = &limitedReader{r: res.Body, n: m.maxSize}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	out := &limitedBuffer{n: m.maxSize}
+	stderr := &limitedBuffer{n: 1024}
+
+	cmd := exec.CommandContext(ctx, m.cmd[0], m.cmd[1:]...)
+	cmd.Env = []string{}
+	cmd.Dir = os.TempDir()
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+
+	log.Debugf("body.ExecFilterModifier: running %s", m.cmd[0])
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err == nil && out.truncated {
+		err = errExecFilterTooLarge
+	}
+	if err != nil {
+		err = fmt.Errorf("body.ExecFilterModifier: %s: %v: %s", m.cmd[0], err, bytes.TrimSpace(stderr.Bytes()))
+
+		res.StatusCode = http.StatusBadGateway
+		res.Status = http.StatusText(http.StatusBadGateway)
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Type")
+		m.setBody(res, nil)
+
+		return err
+	}
+
+	m.setBody(res, out.Bytes())
+
+	return nil
+}
+
+// setBody replaces the body of res with b.
+func (m *ExecFilterModifier) setBody(res *http.Response, b []byte) {
+	res.Header.Set("Content-Length", strconv.Itoa(len(b)))
+	res.Header.Del("Transfer-Encoding")
+	res.ContentLength = int64(len(b))
+	res.TransferEncoding = nil
+	res.Body = ioutil.NopCloser(bytes.NewReader(b))
+}
+
+// limitedReader reads from r, returning errExecFilterTooLarge once more than n
+// bytes have been read.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return 0, errExecFilterTooLarge
+	}
+
+	return n, err
+}
+
+// limitedBuffer holds at most n bytes written to it. Writes past the limit are
+// discarded, rather than failing, so that the process is not blocked on a full
+// pipe.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	n         int64
+	truncated bool
+}
+
+func (l *limitedBuffer) Write(b []byte) (int, error) {
+	if rem := l.n - int64(l.buf.Len()); int64(len(b)) > rem {
+		l.truncated = true
+		l.buf.Write(b[:rem])
+		return len(b), nil
+	}
+
+	return l.buf.Write(b)
+}
+
+// Bytes returns the bytes held by the buffer.
+func (l *limitedBuffer) Bytes() []byte {
+	return l.buf.Bytes()
+}
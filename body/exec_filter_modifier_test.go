@@ -0,0 +1,129 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func execFilterModifier(t *testing.T, cmd ...string) *ExecFilterModifier {
+	t.Helper()
+
+	if _, err := os.Stat(cmd[0]); err != nil {
+		t.Skipf("%s not available: %v", cmd[0], err)
+	}
+	mod, err := NewExecFilterModifier(cmd)
+	if err != nil {
+		t.Fatalf("NewExecFilterModifier(%q): got %v, want no error", cmd, err)
+	}
+
+	return mod
+}
+
+func TestExecFilterModifier(t *testing.T) {
+	mod := execFilterModifier(t, "/usr/bin/tr", "a-z", "A-Z")
+
+	res := proxyutil.NewResponse(200, strings.NewReader("hello world"), nil)
+	res.TransferEncoding = []string{"chunked"}
+
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if want := "HELLO WORLD"; string(got) != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+	if got, want := res.ContentLength, int64(11); got != want {
+		t.Errorf("res.ContentLength: got %d, want %d", got, want)
+	}
+	if got, want := res.Header.Get("Content-Length"), "11"; got != want {
+		t.Errorf("res.Header.Get(%q): got %q, want %q", "Content-Length", got, want)
+	}
+	if res.TransferEncoding != nil {
+		t.Errorf("res.TransferEncoding: got %v, want nil", res.TransferEncoding)
+	}
+}
+
+func TestExecFilterModifierFailures(t *testing.T) {
+	tt := []struct {
+		name string
+		cmd  []string
+		body string
+		init func(*ExecFilterModifier)
+	}{
+		{"exit status", []string{"/bin/sh", "-c", "cat; exit 3"}, "hello", nil},
+		{"timeout", []string{"/bin/sleep", "5"}, "hello", func(m *ExecFilterModifier) {
+			m.SetTimeout(50 * time.Millisecond)
+		}},
+		{"large input", []string{"/bin/cat"}, "hello world", func(m *ExecFilterModifier) {
+			m.SetMaxSize(4)
+		}},
+		{"large output", []string{"/usr/bin/head", "-c", "4096", "/dev/zero"}, "hello", func(m *ExecFilterModifier) {
+			m.SetMaxSize(1024)
+		}},
+	}
+
+	for _, tc := range tt {
+		mod := execFilterModifier(t, tc.cmd...)
+		if tc.init != nil {
+			tc.init(mod)
+		}
+
+		res := proxyutil.NewResponse(200, strings.NewReader(tc.body), nil)
+		res.Header.Set("Content-Type", "text/plain")
+
+		start := time.Now()
+		if err := mod.ModifyResponse(res); err == nil {
+			t.Errorf("%s: ModifyResponse(): got no error, want error", tc.name)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: ModifyResponse(): returned after %v, want sooner", tc.name, elapsed)
+		}
+
+		if got, want := res.StatusCode, http.StatusBadGateway; got != want {
+			t.Errorf("%s: res.StatusCode: got %d, want %d", tc.name, got, want)
+		}
+		if got, _ := ioutil.ReadAll(res.Body); len(got) != 0 {
+			t.Errorf("%s: res.Body: got %q, want empty", tc.name, got)
+		}
+		if got := res.Header.Get("Content-Type"); got != "" {
+			t.Errorf("%s: res.Header.Get(%q): got %q, want empty", tc.name, "Content-Type", got)
+		}
+	}
+}
+
+func TestNewExecFilterModifierInvalid(t *testing.T) {
+	for _, cmd := range [][]string{
+		nil,
+		{"cat"},
+		{"/nonexistent/filter"},
+		{os.TempDir()},
+	} {
+		if _, err := NewExecFilterModifier(cmd); err == nil {
+			t.Errorf("NewExecFilterModifier(%q): got no error, want error", cmd)
+		}
+	}
+}
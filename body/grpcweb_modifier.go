@@ -0,0 +1,285 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/google/martian/v3/log"
+)
+
+const (
+	// grpcWebCompressed is the flag of compressed gRPC-Web messages.
+	grpcWebCompressed = 0x01
+	// grpcWebTrailer is the flag of the gRPC-Web trailer frame.
+	grpcWebTrailer = 0x80
+	// grpcWebHeaderSize is the size of the header of a gRPC-Web frame: one
+	// byte of flags and four bytes of length.
+	grpcWebHeaderSize = 5
+)
+
+// GRPCWebMessage is a message decoded from a gRPC-Web body.
+type GRPCWebMessage struct {
+	// FromClient is set for messages of request bodies.
+	FromClient bool
+	// Compressed is set if the message is compressed with the encoding in the
+	// grpc-encoding header. Compressed messages are not decompressed.
+	Compressed bool
+	// Size is the length of the message from its frame header.
+	Size int64
+	// Data holds the message, or its first bytes if it is truncated.
+	Data []byte
+	// Truncated is set if the message is larger than the maximum size.
+	Truncated bool
+	// Trailer holds the fields of the trailer frame sent at the end of
+	// response bodies, and is nil for other messages.
+	Trailer http.Header
+}
+
+// GRPCWebModifier decodes the messages of gRPC-Web request and response
+// bodies, as they are read, for inspection.
+type GRPCWebModifier struct {
+	observe func(req *http.Request, msg *GRPCWebMessage)
+	maxSize int
+}
+
+// NewGRPCWebModifier returns a modifier that calls observe with each message
+// of the gRPC-Web bodies of requests and responses, including the trailer
+// frame of responses, as the body is read by the proxy. Both the binary
+// (application/grpc-web) and the base64 (application/grpc-web-text) wire
+// formats are decoded; bodies are forwarded unchanged.
+//
+// At most maxSize bytes of each message are held in memory; larger messages
+// are reported truncated. observe is called from the goroutine copying the
+// body and must not block. A body that ends inside a frame, or a malformed
+// base64 body, is logged and its remaining data is not reported.
+func NewGRPCWebModifier(observe func(req *http.Request, msg *GRPCWebMessage), maxSize int) *GRPCWebModifier {
+	return &GRPCWebModifier{
+		observe: observe,
+		maxSize: maxSize,
+	}
+}
+
+// ModifyRequest decodes the messages of gRPC-Web request bodies.
+func (m *GRPCWebModifier) ModifyRequest(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	req.Body = m.wrap(req.Body, req.Header, req, true)
+
+	return nil
+}
+
+// ModifyResponse decodes the messages of gRPC-Web response bodies.
+func (m *GRPCWebModifier) ModifyResponse(res *http.Response) error {
+	if res.Body == nil || res.Body == http.NoBody {
+		return nil
+	}
+
+	res.Body = m.wrap(res.Body, res.Header, res.Request, false)
+
+	return nil
+}
+
+// wrap returns body wrapped to decode its messages, if the Content-Type in h
+// is gRPC-Web, or body otherwise.
+func (m *GRPCWebModifier) wrap(body io.ReadCloser, h http.Header, req *http.Request, fromClient bool) io.ReadCloser {
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return body
+	}
+
+	p := &grpcWebParser{
+		fromClient: fromClient,
+		maxSize:    m.maxSize,
+		observe: func(msg *GRPCWebMessage) {
+			m.observe(req, msg)
+		},
+	}
+
+	switch {
+	case mt == "application/grpc-web-text" || strings.HasPrefix(mt, "application/grpc-web-text+"):
+		return &grpcWebBody{ReadCloser: body, w: &base64Decoder{w: p}, p: p}
+	case mt == "application/grpc-web" || strings.HasPrefix(mt, "application/grpc-web+"):
+		return &grpcWebBody{ReadCloser: body, w: p, p: p}
+	}
+
+	return body
+}
+
+// grpcWebBody passes the data read from a body to be decoded.
+type grpcWebBody struct {
+	io.ReadCloser
+	w    io.Writer
+	p    *grpcWebParser
+	done bool
+}
+
+func (b *grpcWebBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.done {
+		return n, err
+	}
+
+	if n > 0 {
+		if _, werr := b.w.Write(p[:n]); werr != nil {
+			log.Errorf("body.GRPCWebModifier: failed to decode body: %v", werr)
+			b.done = true
+		}
+	}
+	if err == io.EOF {
+		b.done = true
+		b.p.finish()
+	}
+
+	return n, err
+}
+
+// grpcWebParser decodes gRPC-Web frames written to it.
+type grpcWebParser struct {
+	fromClient bool
+	maxSize    int
+	observe    func(*GRPCWebMessage)
+
+	hdr       [grpcWebHeaderSize]byte
+	nhdr      int
+	msg       *GRPCWebMessage
+	remaining int64
+}
+
+func (p *grpcWebParser) Write(b []byte) (int, error) {
+	n := len(b)
+
+	for len(b) > 0 {
+		if p.msg == nil {
+			c := copy(p.hdr[p.nhdr:], b)
+			p.nhdr += c
+			b = b[c:]
+			if p.nhdr < grpcWebHeaderSize {
+				break
+			}
+			p.nhdr = 0
+
+			size := int64(binary.BigEndian.Uint32(p.hdr[1:]))
+			p.msg = &GRPCWebMessage{
+				FromClient: p.fromClient,
+				Compressed: p.hdr[0]&grpcWebCompressed != 0,
+				Size:       size,
+			}
+			if p.hdr[0]&grpcWebTrailer != 0 {
+				p.msg.Trailer = http.Header{}
+			}
+			p.remaining = size
+			if p.remaining == 0 {
+				p.report()
+			}
+			continue
+		}
+
+		c := int64(len(b))
+		if c > p.remaining {
+			c = p.remaining
+		}
+		data := b[:c]
+		if room := p.maxSize - len(p.msg.Data); int64(len(data)) > int64(room) {
+			if room < 0 {
+				room = 0
+			}
+			data = data[:room]
+			p.msg.Truncated = true
+		}
+		p.msg.Data = append(p.msg.Data, data...)
+
+		p.remaining -= c
+		b = b[c:]
+		if p.remaining == 0 {
+			p.report()
+		}
+	}
+
+	return n, nil
+}
+
+// report passes the completed message to the observer.
+func (p *grpcWebParser) report() {
+	msg := p.msg
+	p.msg = nil
+
+	if msg.Trailer != nil && !msg.Truncated {
+		tr := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(msg.Data), strings.NewReader("\r\n"))))
+		h, err := tr.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			log.Errorf("body.GRPCWebModifier: failed to decode trailer frame: %v", err)
+		}
+		for k, vs := range h {
+			msg.Trailer[k] = vs
+		}
+	}
+
+	p.observe(msg)
+}
+
+// finish is called at the end of the body.
+func (p *grpcWebParser) finish() {
+	if p.msg != nil || p.nhdr > 0 {
+		log.Errorf("body.GRPCWebModifier: body ended inside a frame")
+	}
+}
+
+// base64Decoder decodes the base64 data written to it, which may be the
+// concatenation of several padded base64 strings, and writes the result to w.
+type base64Decoder struct {
+	w   io.Writer
+	buf [4]byte
+	n   int
+}
+
+func (d *base64Decoder) Write(b []byte) (int, error) {
+	n := len(b)
+
+	var out [3]byte
+	for _, c := range b {
+		switch c {
+		case '\r', '\n':
+			continue
+		}
+
+		d.buf[d.n] = c
+		d.n++
+		if d.n < len(d.buf) {
+			continue
+		}
+		d.n = 0
+
+		m, err := base64.StdEncoding.Decode(out[:], d.buf[:])
+		if err != nil {
+			return 0, err
+		}
+		if _, err := d.w.Write(out[:m]); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
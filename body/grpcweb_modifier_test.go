@@ -0,0 +1,184 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/martian/v3/proxyutil"
+)
+
+// grpcWebFrame returns a gRPC-Web frame with flags and data.
+func grpcWebFrame(flags byte, data string) []byte {
+	b := make([]byte, grpcWebHeaderSize, grpcWebHeaderSize+len(data))
+	b[0] = flags
+	binary.BigEndian.PutUint32(b[1:], uint32(len(data)))
+
+	return append(b, data...)
+}
+
+// grpcWebResponse returns a response with body and Content-Type ct, whose body
+// is read one byte at a time.
+func grpcWebResponse(t *testing.T, ct string, body []byte) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest("POST", "http://example.com/helloworld.Greeter/SayHello", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	res := proxyutil.NewResponse(200, iotest.OneByteReader(bytes.NewReader(body)), req)
+	res.Header.Set("Content-Type", ct)
+
+	return res
+}
+
+func TestGRPCWebModifier(t *testing.T) {
+	var body []byte
+	body = append(body, grpcWebFrame(0, "hello")...)
+	body = append(body, grpcWebFrame(grpcWebCompressed, "")...)
+	body = append(body, grpcWebFrame(grpcWebTrailer, "grpc-status:0\r\ngrpc-message:OK\r\n")...)
+
+	var msgs []*GRPCWebMessage
+	mod := NewGRPCWebModifier(func(req *http.Request, msg *GRPCWebMessage) {
+		if req == nil {
+			t.Error("observe: got nil request, want request")
+		}
+		msgs = append(msgs, msg)
+	}, 1024)
+
+	res := grpcWebResponse(t, "application/grpc-web+proto", body)
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("res.Body: got %q, want unmodified %q", got, body)
+	}
+
+	if got, want := len(msgs), 3; got != want {
+		t.Fatalf("len(msgs): got %d, want %d", got, want)
+	}
+	if got, want := string(msgs[0].Data), "hello"; got != want {
+		t.Errorf("msgs[0].Data: got %q, want %q", got, want)
+	}
+	if msgs[0].FromClient || msgs[0].Compressed || msgs[0].Trailer != nil {
+		t.Errorf("msgs[0]: got %+v, want uncompressed message from origin", msgs[0])
+	}
+	if !msgs[1].Compressed || msgs[1].Size != 0 {
+		t.Errorf("msgs[1]: got %+v, want empty compressed message", msgs[1])
+	}
+	if got, want := msgs[2].Trailer.Get("Grpc-Status"), "0"; got != want {
+		t.Errorf("msgs[2].Trailer.Get(%q): got %q, want %q", "Grpc-Status", got, want)
+	}
+	if got, want := msgs[2].Trailer.Get("Grpc-Message"), "OK"; got != want {
+		t.Errorf("msgs[2].Trailer.Get(%q): got %q, want %q", "Grpc-Message", got, want)
+	}
+}
+
+func TestGRPCWebModifierText(t *testing.T) {
+	// Each frame is encoded separately, so the body is the concatenation of
+	// padded base64 strings.
+	body := base64.StdEncoding.EncodeToString(grpcWebFrame(0, "hello")) +
+		base64.StdEncoding.EncodeToString(grpcWebFrame(grpcWebTrailer, "grpc-status:0\r\n"))
+
+	var msgs []*GRPCWebMessage
+	mod := NewGRPCWebModifier(func(_ *http.Request, msg *GRPCWebMessage) {
+		msgs = append(msgs, msg)
+	}, 1024)
+
+	res := grpcWebResponse(t, "application/grpc-web-text", []byte(body))
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if _, err := ioutil.ReadAll(res.Body); err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("len(msgs): got %d, want %d", got, want)
+	}
+	if got, want := string(msgs[0].Data), "hello"; got != want {
+		t.Errorf("msgs[0].Data: got %q, want %q", got, want)
+	}
+	if got, want := msgs[1].Trailer.Get("Grpc-Status"), "0"; got != want {
+		t.Errorf("msgs[1].Trailer.Get(%q): got %q, want %q", "Grpc-Status", got, want)
+	}
+}
+
+func TestGRPCWebModifierRequest(t *testing.T) {
+	var msgs []*GRPCWebMessage
+	mod := NewGRPCWebModifier(func(_ *http.Request, msg *GRPCWebMessage) {
+		msgs = append(msgs, msg)
+	}, 4)
+
+	// The second frame is cut short.
+	body := append(grpcWebFrame(0, "hello world"), grpcWebFrame(0, "partial")[:8]...)
+	req, err := http.NewRequest("POST", "http://example.com/helloworld.Greeter/SayHello", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc-web")
+
+	if err := mod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if _, err := ioutil.ReadAll(req.Body); err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+
+	if got, want := len(msgs), 1; got != want {
+		t.Fatalf("len(msgs): got %d, want %d", got, want)
+	}
+	if !msgs[0].FromClient {
+		t.Error("msgs[0].FromClient: got false, want true")
+	}
+	if !msgs[0].Truncated {
+		t.Error("msgs[0].Truncated: got false, want true")
+	}
+	if got, want := string(msgs[0].Data), "hell"; got != want {
+		t.Errorf("msgs[0].Data: got %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Size, int64(11); got != want {
+		t.Errorf("msgs[0].Size: got %d, want %d", got, want)
+	}
+}
+
+func TestGRPCWebModifierIgnoresOtherBodies(t *testing.T) {
+	called := false
+	mod := NewGRPCWebModifier(func(*http.Request, *GRPCWebMessage) {
+		called = true
+	}, 1024)
+
+	res := grpcWebResponse(t, "application/grpc", grpcWebFrame(0, "hello"))
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	ioutil.ReadAll(res.Body)
+
+	if called {
+		t.Error("observe: got called for application/grpc body, want not called")
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+
+	"github.com/google/martian/v3/log"
+)
+
+// Part is a part of a multipart/form-data body: a form field or a file.
+type Part struct {
+	Header textproto.MIMEHeader
+	Body   []byte
+}
+
+// NewFieldPart returns a part for the form field name with value.
+func NewFieldPart(name, value string) *Part {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+		"name": name,
+	}))
+
+	return &Part{
+		Header: h,
+		Body:   []byte(value),
+	}
+}
+
+// NewFilePart returns a part for a file named filename uploaded with the form
+// field name.
+func NewFilePart(name, filename, contentType string, body []byte) *Part {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+		"name":     name,
+		"filename": filename,
+	}))
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+
+	return &Part{
+		Header: h,
+		Body:   body,
+	}
+}
+
+// FormName returns the name of the form field of the part, or the empty string
+// if it has none.
+func (p *Part) FormName() string {
+	_, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+
+	return params["name"]
+}
+
+// FileName returns the file name of the part, or the empty string if it is not
+// a file.
+func (p *Part) FileName() string {
+	_, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+
+	return params["filename"]
+}
+
+// MultipartModifier decodes multipart/form-data request bodies, passes their
+// parts to a function for modification, and encodes the result.
+type MultipartModifier struct {
+	modify  func(req *http.Request, parts []*Part) ([]*Part, error)
+	maxSize int64
+}
+
+// NewMultipartModifier returns a request modifier that calls modify with the
+// parts of each multipart/form-data request body, in order. The parts returned
+// by modify, which may add, remove, reorder or change parts, are encoded as the
+// new body with a new boundary, and the Content-Type and Content-Length of the
+// request are updated to match.
+//
+// Bodies are held in memory to be decoded; bodies larger than maxSize bytes
+// are sent unmodified without calling modify. If modify returns an error, the
+// request is sent unmodified and the error is returned.
+func NewMultipartModifier(modify func(req *http.Request, parts []*Part) ([]*Part, error), maxSize int64) *MultipartModifier {
+	return &MultipartModifier{
+		modify:  modify,
+		maxSize: maxSize,
+	}
+}
+
+// ModifyRequest decodes, modifies and encodes multipart/form-data bodies.
+func (m *MultipartModifier) ModifyRequest(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	mt, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/form-data" || params["boundary"] == "" {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, m.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > m.maxSize {
+		log.Debugf("body.MultipartModifier: body of %s exceeds %d bytes, not modifying", req.URL, m.maxSize)
+		req.Body = &prefixedBody{
+			Reader: io.MultiReader(bytes.NewReader(body), req.Body),
+			Closer: req.Body,
+		}
+		return nil
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	parts, err := readParts(body, params["boundary"])
+	if err != nil {
+		return fmt.Errorf("body.MultipartModifier: failed to decode body: %v", err)
+	}
+
+	parts, err = m.modify(req, parts)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	for _, p := range parts {
+		pw, err := mw.CreatePart(p.Header)
+		if err != nil {
+			return err
+		}
+		if _, err := pw.Write(p.Body); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Content-Length", strconv.Itoa(buf.Len()))
+	req.Header.Del("Transfer-Encoding")
+	req.ContentLength = int64(buf.Len())
+	req.TransferEncoding = nil
+	req.Body = ioutil.NopCloser(buf)
+
+	return nil
+}
+
+// readParts decodes the parts of the multipart body b.
+func readParts(b []byte, boundary string) ([]*Part, error) {
+	mr := multipart.NewReader(bytes.NewReader(b), boundary)
+
+	var parts []*Part
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pb, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, &Part{
+			Header: p.Header,
+			Body:   pb,
+		})
+	}
+}
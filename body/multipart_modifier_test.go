@@ -0,0 +1,190 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package body
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func multipartRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("user", "alice")
+	mw.WriteField("token", "secret")
+	fw, err := mw.CreateFormFile("upload", "a.txt")
+	if err != nil {
+		t.Fatalf("mw.CreateFormFile(): got %v, want no error", err)
+	}
+	fw.Write([]byte("file contents"))
+	mw.Close()
+
+	req, err := http.NewRequest("POST", "http://example.com", buf)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+
+	return req
+}
+
+func TestMultipartModifier(t *testing.T) {
+	req := multipartRequest(t)
+	oldct := req.Header.Get("Content-Type")
+
+	mod := NewMultipartModifier(func(_ *http.Request, parts []*Part) ([]*Part, error) {
+		var out []*Part
+		for _, p := range parts {
+			switch p.FormName() {
+			case "token":
+				continue
+			case "user":
+				p.Body = []byte("bob")
+			case "upload":
+				if got, want := p.FileName(), "a.txt"; got != want {
+					t.Errorf("p.FileName(): got %q, want %q", got, want)
+				}
+			}
+			out = append(out, p)
+		}
+
+		out = append(out, NewFieldPart("added", "yes"))
+		out = append(out, NewFilePart("other", "b.bin", "application/octet-stream", []byte{0, 1, 2}))
+		return out, nil
+	}, 1024)
+
+	if err := mod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	if got := req.Header.Get("Content-Type"); got == oldct {
+		t.Errorf("req.Header.Get(%q): got %q, want new boundary", "Content-Type", got)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := req.ContentLength, int64(len(body)); got != want {
+		t.Errorf("req.ContentLength: got %d, want %d", got, want)
+	}
+	if got, want := req.Header.Get("Content-Length"), strconv.Itoa(len(body)); got != want {
+		t.Errorf("req.Header.Get(%q): got %q, want %q", "Content-Length", got, want)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := req.ParseMultipartForm(1024); err != nil {
+		t.Fatalf("req.ParseMultipartForm(): got %v, want no error", err)
+	}
+
+	if got, want := req.FormValue("user"), "bob"; got != want {
+		t.Errorf("req.FormValue(%q): got %q, want %q", "user", got, want)
+	}
+	if got, want := req.FormValue("added"), "yes"; got != want {
+		t.Errorf("req.FormValue(%q): got %q, want %q", "added", got, want)
+	}
+	if _, ok := req.MultipartForm.Value["token"]; ok {
+		t.Errorf("req.MultipartForm.Value[%q]: got field, want removed", "token")
+	}
+
+	for _, tc := range []struct {
+		name, filename, body string
+	}{
+		{"upload", "a.txt", "file contents"},
+		{"other", "b.bin", "\x00\x01\x02"},
+	} {
+		f, fh, err := req.FormFile(tc.name)
+		if err != nil {
+			t.Fatalf("req.FormFile(%q): got %v, want no error", tc.name, err)
+		}
+		got, _ := ioutil.ReadAll(f)
+		f.Close()
+
+		if fh.Filename != tc.filename {
+			t.Errorf("%s: fh.Filename: got %q, want %q", tc.name, fh.Filename, tc.filename)
+		}
+		if string(got) != tc.body {
+			t.Errorf("%s: file: got %q, want %q", tc.name, got, tc.body)
+		}
+	}
+}
+
+func TestMultipartModifierSkipped(t *testing.T) {
+	called := false
+	modify := func(_ *http.Request, parts []*Part) ([]*Part, error) {
+		called = true
+		return parts, nil
+	}
+
+	// Bodies over the size limit are passed through.
+	req := multipartRequest(t)
+	want, _ := ioutil.ReadAll(req.Body)
+	req.Body = ioutil.NopCloser(bytes.NewReader(want))
+	ct := req.Header.Get("Content-Type")
+
+	if err := NewMultipartModifier(modify, 16).ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if called {
+		t.Error("modify: got called for oversized body, want not called")
+	}
+	if got := req.Header.Get("Content-Type"); got != ct {
+		t.Errorf("req.Header.Get(%q): got %q, want %q", "Content-Type", got, ct)
+	}
+	got, _ := ioutil.ReadAll(req.Body)
+	if !bytes.Equal(got, want) {
+		t.Errorf("req.Body: got %q, want %q", got, want)
+	}
+
+	// Bodies that are not multipart are ignored.
+	req, err := http.NewRequest("POST", "http://example.com", bytes.NewReader([]byte("a=b")))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := NewMultipartModifier(modify, 1024).ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if called {
+		t.Error("modify: got called for form body, want not called")
+	}
+}
+
+func TestMultipartModifierError(t *testing.T) {
+	req := multipartRequest(t)
+	want, _ := ioutil.ReadAll(req.Body)
+	req.Body = ioutil.NopCloser(bytes.NewReader(want))
+
+	mod := NewMultipartModifier(func(*http.Request, []*Part) ([]*Part, error) {
+		return nil, errors.New("modify failed")
+	}, 1024)
+	if err := mod.ModifyRequest(req); err == nil {
+		t.Fatal("ModifyRequest(): got no error, want error")
+	}
+
+	got, _ := ioutil.ReadAll(req.Body)
+	if !bytes.Equal(got, want) {
+		t.Errorf("req.Body: got %q, want unmodified %q", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/google/martian/v3/log"
+)
+
+// BodyFilter transforms a message body as it is streamed, chunk by chunk,
+// without holding the whole body in memory.
+type BodyFilter interface {
+	// FilterBody returns a reader of the transformed body read from r.
+	FilterBody(r io.Reader) io.Reader
+}
+
+// BodyFilterFunc is an adapter to allow the use of ordinary functions as
+// BodyFilters.
+type BodyFilterFunc func(r io.Reader) io.Reader
+
+// FilterBody calls f(r).
+func (f BodyFilterFunc) FilterBody(r io.Reader) io.Reader {
+	return f(r)
+}
+
+// PassthroughBodyFilter is a BodyFilter that returns the body unchanged.
+var PassthroughBodyFilter BodyFilter = BodyFilterFunc(func(r io.Reader) io.Reader {
+	return r
+})
+
+// AddRequestBodyFilter adds a filter that transforms the body of the request
+// as it is sent upstream during the round trip, after all request modifiers
+// have run. Unlike a request modifier that replaces the body, a filter does
+// not buffer the body, so it can transform large uploads. Filters are applied
+// in the order they are added, each reading the output of the previous one.
+//
+// Since the length of the filtered body is not known in advance, a filtered
+// request is sent without Content-Length, using chunked encoding.
+func (ctx *Context) AddRequestBodyFilter(f BodyFilter) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.reqBodyFilters = append(ctx.reqBodyFilters, f)
+}
+
+// filterRequestBody applies the body filters added to ctx to the body of req.
+func filterRequestBody(ctx *Context, req *http.Request) {
+	ctx.mu.RLock()
+	filters := ctx.reqBodyFilters
+	ctx.mu.RUnlock()
+
+	if len(filters) == 0 || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	log.Debugf("martian: filtering body of %s with %d filters", req.URL, len(filters))
+
+	var r io.Reader = req.Body
+	for _, f := range filters {
+		r = f.FilterBody(r)
+	}
+
+	req.Body = &filteredBody{
+		Reader: r,
+		Closer: req.Body,
+	}
+	req.ContentLength = -1
+	req.Header.Del("Content-Length")
+}
+
+// filteredBody is a request body read through filters and closed with the
+// original body.
+type filteredBody struct {
+	io.Reader
+	io.Closer
+}
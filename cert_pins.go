@@ -0,0 +1,169 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// certPinPrefix is the prefix of certificate pins, which are SHA-256 digests
+// of the public key of a certificate.
+const certPinPrefix = "sha256/"
+
+// CertPinError is the error of upstream TLS connections whose certificates do
+// not match the pins of the host, as set with SetUpstreamCertPins.
+type CertPinError struct {
+	// Host is the host of the connection.
+	Host string
+}
+
+func (e *CertPinError) Error() string {
+	return fmt.Sprintf("martian: certificates of %s do not match its pins", e.Host)
+}
+
+// CertPin returns the pin of cert: "sha256/" followed by the base64 encoded
+// SHA-256 digest of its DER encoded SubjectPublicKeyInfo, as used by HPKP and
+// curl's --pinnedpubkey.
+func CertPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return certPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// SetUpstreamCertPins sets the certificate pins of upstream hosts. The
+// proxy refuses TLS connections to a host with pins unless one of the
+// certificates presented by the origin has the public key of one of its pins,
+// as computed by CertPin; the round trip fails with a *CertPinError and the
+// client receives a 502 Bad Gateway. This guards against interception between
+// the proxy and the origin, independently of the MITM of client connections,
+// and applies even when certificate verification is skipped. Hosts are
+// hostnames without a port, matched against the server name of connections;
+// IP addresses are not supported. Connections to hosts without pins are not
+// affected. Pins replace those set by a previous call; a nil map
+// removes all pins.
+//
+// Pins are enforced by the TLS config of the *http.Transport of the round
+// tripper, as described for SetRoundTripper; pins are applied to transports
+// set later with SetRoundTripper. A VerifyConnection callback of that TLS
+// config is kept and runs before the pins are checked. Pins are also enforced
+// on the connections relayed to HTTP/2 origins for SetHTTP2Observer. Tunneled connections, including those
+// of clients offering HTTP/2 with MITMALPNTunnel, are not terminated by the
+// proxy; the client verifies the origin itself.
+func (p *Proxy) SetUpstreamCertPins(pins map[string][]string) error {
+	parsed := make(map[string][][]byte)
+	for host, hpins := range pins {
+		// Connections to IP addresses carry no server name to match pins.
+		if net.ParseIP(host) != nil {
+			return fmt.Errorf("martian: certificate pins for IP address %s are not supported", host)
+		}
+		for _, pin := range hpins {
+			if !strings.HasPrefix(pin, certPinPrefix) {
+				return fmt.Errorf("martian: certificate pin %q for %s is not a %s pin", pin, host, certPinPrefix)
+			}
+			sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, certPinPrefix))
+			if err != nil || len(sum) != sha256.Size {
+				return fmt.Errorf("martian: invalid certificate pin %q for %s", pin, host)
+			}
+
+			host := strings.ToLower(host)
+			parsed[host] = append(parsed[host], sum)
+		}
+	}
+
+	if _, ok := p.httpTransport(); !ok {
+		return errors.New("martian: upstream certificate pins require an *http.Transport round tripper")
+	}
+
+	p.certPins = parsed
+	p.applyCertPins()
+
+	return nil
+}
+
+// applyCertPins sets the TLS config of the round tripper to verify the pins
+// set with SetUpstreamCertPins. A VerifyConnection callback already set on the
+// TLS config runs before the pins are verified.
+func (p *Proxy) applyCertPins() {
+	tr, ok := p.httpTransport()
+	if !ok || p.certPins == nil {
+		return
+	}
+
+	cfg := &tls.Config{}
+	if tr.TLSClientConfig != nil {
+		cfg = tr.TLSClientConfig.Clone()
+	}
+	// Chain the callback the pins replaced rather than the pins' own
+	// callback when the pins are applied again to the same config.
+	prev := cfg.VerifyConnection
+	if tr.TLSClientConfig != nil && tr.TLSClientConfig == p.pinnedTLSConfig {
+		prev = p.pinnedVerify
+	}
+	pins := p.certPins
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if prev != nil {
+			if err := prev(cs); err != nil {
+				return err
+			}
+		}
+		return verifyCertPins(pins, cs.ServerName, cs)
+	}
+	tr.TLSClientConfig = cfg
+	p.pinnedTLSConfig = cfg
+	p.pinnedVerify = prev
+}
+
+// verifyHostPins returns a function for tls.Config.VerifyConnection that
+// verifies the pins of host, or nil if host has no pins. It is used for the
+// TLS connections the proxy makes to origins outside of the round tripper.
+func (p *Proxy) verifyHostPins(host string) func(tls.ConnectionState) error {
+	pins := p.certPins
+	if _, ok := pins[strings.ToLower(host)]; !ok {
+		return nil
+	}
+
+	return func(cs tls.ConnectionState) error {
+		return verifyCertPins(pins, host, cs)
+	}
+}
+
+// verifyCertPins returns a *CertPinError if host has pins and none match the
+// certificates of cs.
+func verifyCertPins(pins map[string][][]byte, host string, cs tls.ConnectionState) error {
+	host = strings.ToLower(host)
+	hpins, ok := pins[host]
+	if !ok {
+		return nil
+	}
+
+	for _, cert := range cs.PeerCertificates {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range hpins {
+			if bytes.Equal(sum[:], pin) {
+				return nil
+			}
+		}
+	}
+
+	return &CertPinError{Host: host}
+}
@@ -0,0 +1,364 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	gocontext "context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/mitm"
+	"golang.org/x/net/http2"
+)
+
+func TestIntegrationUpstreamCertPins(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("origin"))
+	}))
+	defer srv.Close()
+
+	other, _, err := mitm.NewAuthority("other", "Other Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	tt := []struct {
+		name string
+		pins map[string][]string
+		want int
+	}{
+		{
+			name: "matching pin",
+			pins: map[string][]string{"example.com": {CertPin(srv.Certificate())}},
+			want: 200,
+		},
+		{
+			name: "one of several pins",
+			pins: map[string][]string{"example.com": {"sha256/" + strings.Repeat("A", 43) + "=", CertPin(srv.Certificate())}},
+			want: 200,
+		},
+		{
+			name: "mismatching pin",
+			pins: map[string][]string{"example.com": {CertPin(other)}},
+			want: 502,
+		},
+		{
+			name: "pins of another host",
+			pins: map[string][]string{"www.example.com": {CertPin(other)}},
+			want: 200,
+		},
+	}
+
+	for _, tc := range tt {
+		l, err := net.Listen("tcp", "[::]:0")
+		if err != nil {
+			t.Fatalf("%s: net.Listen(): got %v, want no error", tc.name, err)
+		}
+
+		p := NewProxy()
+		defer p.Close()
+
+		p.SetRoundTripper(&http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		})
+		if err := p.SetUpstreamCertPins(tc.pins); err != nil {
+			t.Fatalf("%s: p.SetUpstreamCertPins(): got %v, want no error", tc.name, err)
+		}
+		p.SetDialContext(func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("tcp", srv.Listener.Addr().String())
+		})
+		// Plain proxy requests are sent as HTTP; send them to the origin over TLS.
+		p.SetRequestModifier(RequestModifierFunc(func(req *http.Request) error {
+			req.URL.Scheme = "https"
+			return nil
+		}))
+
+		go p.Serve(l)
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("%s: net.Dial(): got %v, want no error", tc.name, err)
+		}
+		defer conn.Close()
+
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("%s: http.NewRequest(): got %v, want no error", tc.name, err)
+		}
+		if err := req.WriteProxy(conn); err != nil {
+			t.Fatalf("%s: req.WriteProxy(): got %v, want no error", tc.name, err)
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			t.Fatalf("%s: http.ReadResponse(): got %v, want no error", tc.name, err)
+		}
+		res.Body.Close()
+
+		if got := res.StatusCode; got != tc.want {
+			t.Errorf("%s: res.StatusCode: got %d, want %d", tc.name, got, tc.want)
+		}
+		if tc.want == 502 {
+			if got, want := res.Header.Get("Warning"), "do not match its pins"; !strings.Contains(got, want) {
+				t.Errorf("%s: res.Header.Get(%q): got %q, want to contain %q", tc.name, "Warning", got, want)
+			}
+		}
+	}
+}
+
+func TestSetUpstreamCertPinsErrors(t *testing.T) {
+	p := NewProxy()
+	defer p.Close()
+
+	for _, pins := range []map[string][]string{
+		{"example.com": {"AAAA"}},
+		{"example.com": {"sha256/not base64"}},
+		{"example.com": {"sha256/AAAA"}},
+		{"127.0.0.1": {"sha256/" + strings.Repeat("A", 43) + "="}},
+	} {
+		if err := p.SetUpstreamCertPins(pins); err == nil {
+			t.Errorf("p.SetUpstreamCertPins(%v): got nil, want error", pins)
+		}
+	}
+
+	p.SetRoundTripper(martiantest.NewTransport())
+	if err := p.SetUpstreamCertPins(nil); err == nil {
+		t.Error("p.SetUpstreamCertPins() with martiantest.Transport: got nil, want error")
+	}
+}
+
+func TestProxyStatusErrorCertPin(t *testing.T) {
+	if got, want := proxyStatusError(&CertPinError{Host: "example.com"}), "tls_certificate_error"; got != want {
+		t.Errorf("proxyStatusError(): got %q, want %q", got, want)
+	}
+}
+
+func TestIntegrationUpstreamCertPinsHTTP2(t *testing.T) {
+	t.Parallel()
+
+	oca, opriv, err := mitm.NewAuthority("origin", "Origin Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	omc, err := mitm.NewConfig(oca, opriv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	ocfg := omc.TLSForHost("example.com")
+	ocfg.NextProtos = []string{http2.NextProtoTLS}
+
+	ol, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer ol.Close()
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte("origin"))
+		}),
+	}
+	if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+		t.Fatalf("http2.ConfigureServer(): got %v, want no error", err)
+	}
+	go srv.Serve(tls.NewListener(ol, ocfg))
+
+	other, _, err := mitm.NewAuthority("other", "Other Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+
+	tt := []struct {
+		name    string
+		pin     string
+		wantErr bool
+	}{
+		{name: "matching pin", pin: CertPin(oca)},
+		{name: "mismatching pin", pin: CertPin(other), wantErr: true},
+	}
+
+	for _, tc := range tt {
+		l, err := net.Listen("tcp", "[::]:0")
+		if err != nil {
+			t.Fatalf("%s: net.Listen(): got %v, want no error", tc.name, err)
+		}
+
+		p := NewProxy()
+		defer p.Close()
+
+		p.SetTimeout(5 * time.Second)
+		if err := p.SetUpstreamCertPins(map[string][]string{"example.com": {tc.pin}}); err != nil {
+			t.Fatalf("%s: p.SetUpstreamCertPins(): got %v, want no error", tc.name, err)
+		}
+
+		ca, priv, err := mitm.NewAuthority("martian.proxy", "Martian Authority", time.Hour)
+		if err != nil {
+			t.Fatalf("%s: mitm.NewAuthority(): got %v, want no error", tc.name, err)
+		}
+		mc, err := mitm.NewConfig(ca, priv)
+		if err != nil {
+			t.Fatalf("%s: mitm.NewConfig(): got %v, want no error", tc.name, err)
+		}
+		mc.SkipTLSVerify(true)
+		p.SetMITM(mc)
+		p.SetHTTP2Observer(func(*HTTP2Headers) {})
+
+		go p.Serve(l)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+
+		tr := &http2.Transport{
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				conn, err := net.Dial("tcp", l.Addr().String())
+				if err != nil {
+					return nil, err
+				}
+
+				req, err := http.NewRequest("CONNECT", "//"+ol.Addr().String(), nil)
+				if err != nil {
+					return nil, err
+				}
+				if err := req.Write(conn); err != nil {
+					return nil, err
+				}
+				if _, err := http.ReadResponse(bufio.NewReader(conn), req); err != nil {
+					return nil, err
+				}
+
+				tlsconn := tls.Client(conn, &tls.Config{
+					ServerName: "example.com",
+					RootCAs:    roots,
+					NextProtos: []string{http2.NextProtoTLS},
+				})
+				if err := tlsconn.Handshake(); err != nil {
+					return nil, err
+				}
+
+				return tlsconn, nil
+			},
+		}
+		defer tr.CloseIdleConnections()
+
+		req, err := http.NewRequest("GET", "https://example.com/", nil)
+		if err != nil {
+			t.Fatalf("%s: http.NewRequest(): got %v, want no error", tc.name, err)
+		}
+
+		res, err := tr.RoundTrip(req)
+		if tc.wantErr {
+			if err == nil {
+				res.Body.Close()
+				t.Errorf("%s: tr.RoundTrip(): got no error, want relay refused", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: tr.RoundTrip(): got %v, want no error", tc.name, err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: ioutil.ReadAll(): got %v, want no error", tc.name, err)
+		}
+		if got, want := string(body), "origin"; got != want {
+			t.Errorf("%s: res.Body: got %q, want %q", tc.name, got, want)
+		}
+	}
+}
+
+// wrappedTransport is a round tripper that wraps an *http.Transport, like
+// protocol.Transport.
+type wrappedTransport struct {
+	http.RoundTripper
+}
+
+func (t *wrappedTransport) Base() http.RoundTripper {
+	return t.RoundTripper
+}
+
+func TestSetUpstreamCertPinsChainsVerifyConnection(t *testing.T) {
+	other, _, err := mitm.NewAuthority("other", "Other Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+
+	var calls int
+	verr := errors.New("verify connection error")
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				calls++
+				if cs.ServerName == "fail.example.com" {
+					return verr
+				}
+				return nil
+			},
+		},
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	p.SetRoundTripper(&wrappedTransport{tr})
+	pins := map[string][]string{"example.com": {"sha256/" + strings.Repeat("A", 43) + "="}}
+	// Apply the pins more than once; the callback must not be chained to
+	// itself.
+	for i := 0; i < 2; i++ {
+		if err := p.SetUpstreamCertPins(pins); err != nil {
+			t.Fatalf("p.SetUpstreamCertPins(): got %v, want no error", err)
+		}
+	}
+	p.SetRoundTripper(&wrappedTransport{tr})
+
+	verify := tr.TLSClientConfig.VerifyConnection
+
+	if err := verify(tls.ConnectionState{ServerName: "www.example.com"}); err != nil {
+		t.Errorf("VerifyConnection(www.example.com): got %v, want no error", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls: got %d, want 1", calls)
+	}
+
+	if err := verify(tls.ConnectionState{ServerName: "fail.example.com"}); err != verr {
+		t.Errorf("VerifyConnection(fail.example.com): got %v, want %v", err, verr)
+	}
+
+	err = verify(tls.ConnectionState{
+		ServerName:       "example.com",
+		PeerCertificates: []*x509.Certificate{other},
+	})
+	if _, ok := err.(*CertPinError); !ok {
+		t.Errorf("VerifyConnection(example.com): got %v, want *CertPinError", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls: got %d, want 3", calls)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// errReset is returned by handle when the client connection has been prepared
+// to be reset rather than closed gracefully.
+var errReset = errors.New("resetting connection")
+
+// closeWriter is implemented by connections that can be half-closed, such as
+// *net.TCPConn and *tls.Conn.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// SetCloseLinger sets how long the proxy lingers before closing a client
+// connection after writing a final response, such as one to a request with
+// "Connection: close". Closing a TCP connection that still has unread data
+// from the client sends a reset, which can discard the end of the response
+// before the client reads it. With a linger, the proxy closes its side of the
+// connection for writing first, then reads and discards data from the client
+// until the client closes the connection or the linger elapses, and only then
+// closes the connection. Connections that are reset deliberately, or that
+// cannot be half-closed, are closed immediately. A duration of zero, the
+// default, closes connections immediately.
+func (p *Proxy) SetCloseLinger(d time.Duration) {
+	p.closeLinger = d
+}
+
+// lingerClose closes conn for writing, discards data read from it until EOF or
+// until d elapses, and leaves conn to be closed by the caller.
+func lingerClose(conn net.Conn, d time.Duration) {
+	cw, ok := conn.(closeWriter)
+	if !ok {
+		return
+	}
+	if err := cw.CloseWrite(); err != nil {
+		log.Debugf("martian: failed to half-close connection %v: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	conn.SetReadDeadline(time.Now().Add(d))
+	io.Copy(ioutil.Discard, conn)
+}
@@ -130,6 +130,11 @@
 //
 // prompts the user to install the CA certificate used by the proxy if MITM is enabled
 //
+//   GET http://martian.proxy/proxy.pac
+//
+// retrieves a proxy auto-configuration file pointing at the proxy if the
+// pac-proxy flag is set
+//
 //   GET http://martian.proxy/logs
 //
 // retrieves the HAR logs for all requests and responses seen by the proxy if
@@ -186,6 +191,12 @@
 //     90's)
 //   -skip-tls-verify=false
 //     skip TLS server verification; insecure and intended for testing only
+//   -pac-proxy=""
+//     host:port that clients use to reach the proxy; if set, a proxy
+//     auto-configuration file pointing at it is served at /proxy.pac
+//   -pac-exclude=""
+//     comma separated host patterns (e.g. "localhost,*.corp.example.com") that
+//     connect directly rather than through the proxy in the PAC file
 //   -v=0
 //     log level for console logs; defaults to error only.
 package main
@@ -215,19 +226,26 @@ import (
 	"github.com/google/martian/v3/martianhttp"
 	"github.com/google/martian/v3/martianlog"
 	"github.com/google/martian/v3/mitm"
+	"github.com/google/martian/v3/protocol"
 	"github.com/google/martian/v3/servemux"
 	"github.com/google/martian/v3/trafficshape"
 	"github.com/google/martian/v3/verify"
 
+	_ "github.com/google/martian/v3/blocklist"
 	_ "github.com/google/martian/v3/body"
 	_ "github.com/google/martian/v3/cookie"
+	_ "github.com/google/martian/v3/echo"
 	_ "github.com/google/martian/v3/failure"
+	_ "github.com/google/martian/v3/fault"
+	_ "github.com/google/martian/v3/filter"
+	_ "github.com/google/martian/v3/fixture"
 	_ "github.com/google/martian/v3/martianurl"
 	_ "github.com/google/martian/v3/method"
 	_ "github.com/google/martian/v3/pingback"
 	_ "github.com/google/martian/v3/port"
 	_ "github.com/google/martian/v3/priority"
 	_ "github.com/google/martian/v3/querystring"
+	_ "github.com/google/martian/v3/schema"
 	_ "github.com/google/martian/v3/skip"
 	_ "github.com/google/martian/v3/stash"
 	_ "github.com/google/martian/v3/static"
@@ -250,6 +268,8 @@ var (
 	trafficShaping = flag.Bool("traffic-shaping", false, "enable traffic shaping API")
 	skipTLSVerify  = flag.Bool("skip-tls-verify", false, "skip TLS server verification; insecure")
 	dsProxyURL     = flag.String("downstream-proxy-url", "", "URL of downstream proxy")
+	pacProxy       = flag.String("pac-proxy", "", "host:port of the proxy used by clients of the PAC file; serves the PAC file when set")
+	pacExclude     = flag.String("pac-exclude", "", "comma separated host patterns that bypass the proxy in the PAC file")
 )
 
 func main() {
@@ -267,7 +287,7 @@ func main() {
 			InsecureSkipVerify: *skipTLSVerify,
 		},
 	}
-	p.SetRoundTripper(tr)
+	p.SetRoundTripper(protocol.NewTransport(tr))
 
 	if *dsProxyURL != "" {
 		u, err := url.Parse(*dsProxyURL)
@@ -326,6 +346,16 @@ func main() {
 		go p.Serve(tls.NewListener(tl, mc.TLS()))
 	}
 
+	if *pacProxy != "" {
+		var exclusions []string
+		if *pacExclude != "" {
+			exclusions = strings.Split(*pacExclude, ",")
+		}
+
+		ph := martianhttp.NewPACHandler(martianhttp.GeneratePAC(*pacProxy, exclusions))
+		configure("/proxy.pac", ph, mux)
+	}
+
 	stack, fg := httpspec.NewStack("martian")
 
 	// wrap stack in a group so that we can forward API requests to the API port
@@ -0,0 +1,469 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// coalesceHeaders are the request headers that may change the response, and
+// are therefore part of the key used to coalesce requests.
+var coalesceHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Authorization",
+	"Cookie",
+	"If-Modified-Since",
+	"If-None-Match",
+	"Range",
+}
+
+// coalesceIgnoredHeaders are the request headers that neither vary the
+// response nor carry credentials, and are therefore left out of the key.
+// Requests with any header outside of these and coalesceHeaders are not
+// coalesced, since it might carry credentials (such as X-Api-Key or
+// Proxy-Authorization) that would otherwise let one client receive the
+// response fetched for another.
+var coalesceIgnoredHeaders = map[string]bool{
+	"Accept-Charset":            true,
+	"Cache-Control":             true,
+	"Connection":                true,
+	"Dnt":                       true,
+	"Forwarded":                 true,
+	"Keep-Alive":                true,
+	"Pragma":                    true,
+	"Proxy-Connection":          true,
+	"Referer":                   true,
+	"Te":                        true,
+	"Upgrade-Insecure-Requests": true,
+	"User-Agent":                true,
+	"Via":                       true,
+	"X-Forwarded-For":           true,
+	"X-Forwarded-Host":          true,
+	"X-Forwarded-Proto":         true,
+}
+
+// coalesceBufferSize is the default size past which the shared body of a
+// coalesced round trip is no longer kept from its start.
+const coalesceBufferSize = 1 << 20
+
+// SetCoalesceRequests sets whether concurrent identical GET and HEAD requests
+// share a single upstream round trip. Requests are identical when their
+// method, URL and the headers that commonly vary the response (such as Accept,
+// Authorization, Cookie and Range) match. The response body is streamed to all
+// waiting clients as it arrives from the origin.
+//
+// A shared response is returned to every client that joined the round trip,
+// so requests carrying any other header, which might hold credentials such as
+// an API key or Proxy-Authorization, are never coalesced; only headers known
+// to be harmless, such as User-Agent, Referer and Cache-Control, are allowed
+// to differ.
+//
+// Requests join a round trip until the first 1 MiB of its body has been
+// received; past that, the body is only buffered between the slowest and the
+// fastest of its readers, up to 1 MiB, and the origin is read no faster than
+// the slowest reader. The shared round trip is not canceled when the client
+// that started it goes away, so that the other clients still get the
+// response.
+func (p *Proxy) SetCoalesceRequests(coalesce bool) {
+	if coalesce {
+		p.coalescer = newCoalescer()
+		return
+	}
+
+	p.coalescer = nil
+}
+
+// coalescer tracks in-flight round trips that may be shared.
+type coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+
+	// bufferSize is the size past which flights stop accepting new requests
+	// and bound their buffered body.
+	bufferSize int
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		flights:    make(map[string]*flight),
+		bufferSize: coalesceBufferSize,
+	}
+}
+
+// coalesceKey returns the key identifying requests that can share a round
+// trip, or false if req must not be coalesced.
+func coalesceKey(req *http.Request) (string, bool) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return "", false
+	}
+	if req.ContentLength > 0 || len(req.TransferEncoding) > 0 || req.Header.Get("Upgrade") != "" {
+		return "", false
+	}
+	for h := range req.Header {
+		if !coalesceIgnoredHeaders[h] && !isCoalesceHeader(h) {
+			return "", false
+		}
+	}
+
+	var key strings.Builder
+	key.WriteString(req.Method)
+	key.WriteString(" ")
+	key.WriteString(req.URL.String())
+
+	for _, h := range coalesceHeaders {
+		key.WriteString("\n")
+		key.WriteString(h)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(req.Header[h], ", "))
+	}
+
+	return key.String(), true
+}
+
+// isCoalesceHeader returns whether h, in canonical form, is part of the
+// coalescing key.
+func isCoalesceHeader(h string) bool {
+	for _, ch := range coalesceHeaders {
+		if h == ch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// roundTrip performs the round trip for req with rt, or waits for an identical
+// in-flight round trip and shares its response.
+func (c *coalescer) roundTrip(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	key, ok := coalesceKey(req)
+	if !ok {
+		return rt.RoundTrip(req)
+	}
+
+	c.mu.Lock()
+	f, ok := c.flights[key]
+	if ok {
+		f.refs++
+		r := f.newReader()
+		c.mu.Unlock()
+
+		log.HostDebugf(hostname(req.Host), "martian: coalescing request with in-flight round trip: %s", req.URL)
+		select {
+		case <-f.ready:
+		case <-req.Context().Done():
+			r.Close()
+			return nil, req.Context().Err()
+		}
+		return f.response(req, r)
+	}
+
+	f = &flight{
+		c:     c,
+		key:   key,
+		refs:  1,
+		ready: make(chan struct{}),
+	}
+	f.cond = sync.NewCond(&f.mu)
+	r := f.newReader()
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	// The round trip is shared, so it must outlive the request that started
+	// it; only its deadline is kept.
+	ctx, cancel := gocontext.WithCancel(detachedContext{req.Context()})
+	if deadline, ok := req.Context().Deadline(); ok {
+		ctx, cancel = gocontext.WithDeadline(detachedContext{req.Context()}, deadline)
+	}
+	f.cancel = cancel
+
+	f.res, f.err = rt.RoundTrip(req.WithContext(ctx))
+	if f.err != nil {
+		cancel()
+		c.mu.Lock()
+		c.remove(f)
+		c.mu.Unlock()
+		close(f.ready)
+		return nil, f.err
+	}
+
+	go f.pump()
+	close(f.ready)
+
+	return f.response(req, r)
+}
+
+// remove stops new requests from joining f. c.mu must be held.
+func (c *coalescer) remove(f *flight) {
+	if c.flights[f.key] == f {
+		delete(c.flights, f.key)
+	}
+}
+
+// detachedContext carries the values of a context without its cancellation.
+type detachedContext struct {
+	gocontext.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// A flight is a single upstream round trip whose response is shared by all of
+// the coalesced requests.
+type flight struct {
+	c      *coalescer
+	key    string
+	ready  chan struct{}
+	res    *http.Response
+	err    error
+	cancel gocontext.CancelFunc
+
+	// refs is the number of requests sharing the flight whose bodies have not
+	// been closed; it is guarded by c.mu.
+	refs int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// body holds the part of the response body from offset base that has not
+	// been read by all readers yet; it is only trimmed once the flight is
+	// sealed against new requests.
+	body      []byte
+	base      int
+	sealed    bool
+	readers   []*flightReader
+	bodyErr   error
+	done      bool
+	abandoned bool
+}
+
+// newReader returns a reader of the shared body from its start. It must be
+// called before the flight is sealed.
+func (f *flight) newReader() *flightReader {
+	r := &flightReader{f: f}
+
+	f.mu.Lock()
+	f.readers = append(f.readers, r)
+	f.mu.Unlock()
+
+	return r
+}
+
+// pump reads the upstream body into the shared buffer until EOF, an error, or
+// all readers have been closed.
+func (f *flight) pump() {
+	defer f.cancel()
+	defer f.res.Body.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		f.mu.Lock()
+		// Past the buffer size, wait for the slowest reader to catch up.
+		for f.sealed && len(f.body) >= f.c.bufferSize && !f.abandoned {
+			f.cond.Wait()
+		}
+		abandoned := f.abandoned
+		f.mu.Unlock()
+		if abandoned {
+			return
+		}
+
+		n, err := f.res.Body.Read(buf)
+
+		f.mu.Lock()
+		f.body = append(f.body, buf[:n]...)
+		if err != nil {
+			f.done = true
+			if err != io.EOF {
+				f.bodyErr = err
+			}
+		}
+		seal := !f.sealed && f.base+len(f.body) >= f.c.bufferSize
+		f.cond.Broadcast()
+		f.mu.Unlock()
+
+		if err != nil || seal {
+			f.c.mu.Lock()
+			f.c.remove(f)
+			f.c.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+		if seal {
+			log.Debugf("martian: coalesced response exceeds %d bytes, streaming to current readers", f.c.bufferSize)
+
+			f.mu.Lock()
+			f.sealed = true
+			f.trim()
+			f.mu.Unlock()
+		}
+	}
+}
+
+// trim drops the part of the body read by all readers, once the flight is
+// sealed. f.mu must be held.
+func (f *flight) trim() {
+	if !f.sealed {
+		return
+	}
+
+	min := f.base + len(f.body)
+	for _, r := range f.readers {
+		if r.off < min {
+			min = r.off
+		}
+	}
+	f.body = f.body[min-f.base:]
+	f.base = min
+	f.cond.Broadcast()
+}
+
+// release drops a reference to f, stopping the upstream body read once there
+// are no readers left.
+func (f *flight) release() {
+	f.c.mu.Lock()
+	f.refs--
+	refs := f.refs
+	if refs == 0 {
+		f.c.remove(f)
+	}
+	f.c.mu.Unlock()
+
+	if refs == 0 {
+		if f.cancel != nil {
+			f.cancel()
+		}
+
+		f.mu.Lock()
+		f.abandoned = true
+		f.cond.Broadcast()
+		f.mu.Unlock()
+	}
+}
+
+// response returns a copy of the shared response for req that reads the body
+// with r. The trailer is declared with the origin's keys and filled in once r
+// has read the whole body.
+func (f *flight) response(req *http.Request, r *flightReader) (*http.Response, error) {
+	if f.err != nil {
+		r.Close()
+		return nil, f.err
+	}
+
+	res := new(http.Response)
+	*res = *f.res
+	res.Header = cloneHeader(f.res.Header)
+	if f.res.Trailer != nil {
+		res.Trailer = make(http.Header, len(f.res.Trailer))
+		for k := range f.res.Trailer {
+			res.Trailer[k] = nil
+		}
+	}
+	res.Request = req
+	res.Body = r
+	r.trailer = res.Trailer
+
+	return res, nil
+}
+
+// flightReader reads the shared body of a flight.
+type flightReader struct {
+	f       *flight
+	off     int
+	trailer http.Header
+	closed  bool
+}
+
+// Read blocks until data past the reader's offset is available or the body has
+// been fully read.
+func (r *flightReader) Read(p []byte) (int, error) {
+	f := r.f
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for r.off == f.base+len(f.body) && !f.done && !f.abandoned {
+		f.cond.Wait()
+	}
+
+	if r.off < f.base+len(f.body) {
+		n := copy(p, f.body[r.off-f.base:])
+		r.off += n
+		f.trim()
+		return n, nil
+	}
+	if f.bodyErr != nil {
+		return 0, f.bodyErr
+	}
+	if !f.done {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	// The origin's trailer is complete once its body has been read.
+	if r.trailer != nil {
+		for k, vv := range f.res.Trailer {
+			r.trailer[k] = append([]string(nil), vv...)
+		}
+	}
+
+	return 0, io.EOF
+}
+
+// Close releases the reader's reference to the shared body.
+func (r *flightReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	f := r.f
+	f.mu.Lock()
+	for i, fr := range f.readers {
+		if fr == r {
+			f.readers = append(f.readers[:i], f.readers[i+1:]...)
+			break
+		}
+	}
+	f.trim()
+	f.mu.Unlock()
+
+	f.release()
+
+	return nil
+}
+
+func cloneHeader(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+
+	h2 := make(http.Header, len(h))
+	for k, vv := range h {
+		vv2 := make([]string, len(vv))
+		copy(vv2, vv)
+		h2[k] = vv2
+	}
+
+	return h2
+}
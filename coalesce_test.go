@@ -0,0 +1,400 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	gocontext "context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowTransport counts round trips and streams its body through a pipe so
+// that the response is still in flight when other requests arrive.
+type slowTransport struct {
+	calls   int32
+	pw      *io.PipeWriter
+	pr      *io.PipeReader
+	trailer http.Header
+	ctx     gocontext.Context
+}
+
+func (tr *slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&tr.calls, 1)
+	tr.ctx = req.Context()
+
+	return &http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       tr.pr,
+		Trailer:    tr.trailer,
+		Request:    req,
+	}, nil
+}
+
+func TestCoalesceRequests(t *testing.T) {
+	pr, pw := io.Pipe()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+
+	const n = 5
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	errs := make([]error, n)
+
+	for i := 0; i < n; i++ {
+		req, err := http.NewRequest("GET", "http://example.com/file", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+
+		res, err := c.roundTrip(tr, req)
+		if err != nil {
+			t.Fatalf("c.roundTrip(): got %v, want no error", err)
+		}
+		if res.Request != req {
+			t.Errorf("res.Request: got %v, want %v", res.Request, req)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer res.Body.Close()
+
+			b, err := ioutil.ReadAll(res.Body)
+			bodies[i], errs[i] = string(b), err
+		}(i)
+	}
+
+	if got := atomic.LoadInt32(&tr.calls); got != 1 {
+		t.Errorf("tr.calls: got %d, want 1", got)
+	}
+
+	pw.Write([]byte("shared "))
+	time.Sleep(10 * time.Millisecond)
+	pw.Write([]byte("body"))
+	pw.Close()
+
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Errorf("%d: ioutil.ReadAll(): got %v, want no error", i, errs[i])
+		}
+		if got, want := bodies[i], "shared body"; got != want {
+			t.Errorf("%d: body: got %q, want %q", i, got, want)
+		}
+	}
+
+	// Once the flight has completed, new requests go upstream again.
+	pr, pw = io.Pipe()
+	tr.pr, tr.pw = pr, pw
+	go pw.Close()
+
+	req, err := http.NewRequest("GET", "http://example.com/file", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	res, err := c.roundTrip(tr, req)
+	if err != nil {
+		t.Fatalf("c.roundTrip(): got %v, want no error", err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if got := atomic.LoadInt32(&tr.calls); got != 2 {
+		t.Errorf("tr.calls: got %d, want 2", got)
+	}
+}
+
+func TestCoalesceRequestsSkipsUnsafeMethods(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "http://example.com/file", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		if _, err := c.roundTrip(tr, req); err != nil {
+			t.Fatalf("c.roundTrip(): got %v, want no error", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&tr.calls); got != 2 {
+		t.Errorf("tr.calls: got %d, want 2", got)
+	}
+}
+
+func TestCoalesceRequestsVaryingHeaders(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+
+	for _, enc := range []string{"gzip", "identity"} {
+		req, err := http.NewRequest("GET", "http://example.com/file", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		req.Header.Set("Accept-Encoding", enc)
+
+		if _, err := c.roundTrip(tr, req); err != nil {
+			t.Fatalf("c.roundTrip(): got %v, want no error", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&tr.calls); got != 2 {
+		t.Errorf("tr.calls: got %d, want 2", got)
+	}
+}
+
+func TestCoalesceRequestsSkipsUnknownHeaders(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+
+	for _, h := range []string{"X-Api-Key", "Proxy-Authorization", "X-Custom-Auth"} {
+		for _, v := range []string{"alice", "bob"} {
+			req, err := http.NewRequest("GET", "http://example.com/file", nil)
+			if err != nil {
+				t.Fatalf("http.NewRequest(): got %v, want no error", err)
+			}
+			req.Header.Set(h, v)
+			req.Header.Set("User-Agent", v)
+
+			if _, err := c.roundTrip(tr, req); err != nil {
+				t.Fatalf("c.roundTrip(): got %v, want no error", err)
+			}
+		}
+	}
+
+	if got := atomic.LoadInt32(&tr.calls); got != 6 {
+		t.Errorf("tr.calls: got %d, want 6", got)
+	}
+}
+
+func TestCoalesceRequestsIgnoredHeaders(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+
+	for _, ua := range []string{"curl", "wget"} {
+		req, err := http.NewRequest("GET", "http://example.com/file", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		req.Header.Set("User-Agent", ua)
+		req.Header.Set("Authorization", "Bearer token")
+
+		if _, err := c.roundTrip(tr, req); err != nil {
+			t.Fatalf("c.roundTrip(): got %v, want no error", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&tr.calls); got != 1 {
+		t.Errorf("tr.calls: got %d, want 1", got)
+	}
+}
+
+func TestCoalesceRequestsBoundedBuffer(t *testing.T) {
+	pr, pw := io.Pipe()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+	c.bufferSize = 100
+
+	var bodies []io.ReadCloser
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "http://example.com/large", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		res, err := c.roundTrip(tr, req)
+		if err != nil {
+			t.Fatalf("c.roundTrip(): got %v, want no error", err)
+		}
+		bodies = append(bodies, res.Body)
+	}
+
+	want := bytes.Repeat([]byte("0123456789"), 100)
+	go func() {
+		for i := 0; i < len(want); i += 10 {
+			pw.Write(want[i : i+10])
+		}
+		pw.Close()
+	}()
+
+	// While the second reader is idle, the first can only get ahead of it by
+	// the buffer size.
+	var fast int64
+	fastc := make(chan []byte, 1)
+	go func() {
+		var got []byte
+		buf := make([]byte, 10)
+		for {
+			n, err := bodies[0].Read(buf)
+			got = append(got, buf[:n]...)
+			atomic.AddInt64(&fast, int64(n))
+			if err != nil {
+				break
+			}
+		}
+		fastc <- got
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if got, max := atomic.LoadInt64(&fast), int64(c.bufferSize+10); got > max {
+		t.Errorf("bytes read by fast reader: got %d, want at most %d", got, max)
+	}
+
+	// The flight no longer accepts new requests once its body exceeds the
+	// buffer size.
+	req, err := http.NewRequest("GET", "http://example.com/large", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	opr, opw := io.Pipe()
+	other := &slowTransport{pr: opr, pw: opw}
+	go opw.Close()
+	res, err := c.roundTrip(other, req)
+	if err != nil {
+		t.Fatalf("c.roundTrip(): got %v, want no error", err)
+	}
+	res.Body.Close()
+	if got := atomic.LoadInt32(&other.calls); got != 1 {
+		t.Errorf("other.calls: got %d, want 1", got)
+	}
+
+	slow, err := ioutil.ReadAll(bodies[1])
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if !bytes.Equal(slow, want) {
+		t.Errorf("slow body: got %d bytes, want %d identical bytes", len(slow), len(want))
+	}
+	if got := <-fastc; !bytes.Equal(got, want) {
+		t.Errorf("fast body: got %d bytes, want %d identical bytes", len(got), len(want))
+	}
+	for _, body := range bodies {
+		body.Close()
+	}
+}
+
+func TestCoalesceRequestsOutliveFirstClient(t *testing.T) {
+	pr, pw := io.Pipe()
+	tr := &slowTransport{pr: pr, pw: pw}
+
+	c := newCoalescer()
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	req, err := http.NewRequest("GET", "http://example.com/file", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	first, err := c.roundTrip(tr, req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("c.roundTrip(): got %v, want no error", err)
+	}
+
+	req, err = http.NewRequest("GET", "http://example.com/file", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	second, err := c.roundTrip(tr, req)
+	if err != nil {
+		t.Fatalf("c.roundTrip(): got %v, want no error", err)
+	}
+	defer second.Body.Close()
+
+	// The first client goes away.
+	cancel()
+	first.Body.Close()
+
+	if err := tr.ctx.Err(); err != nil {
+		t.Errorf("round trip context: got %v, want no error", err)
+	}
+
+	go func() {
+		pw.Write([]byte("body"))
+		pw.Close()
+	}()
+
+	b, err := ioutil.ReadAll(second.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := string(b), "body"; got != want {
+		t.Errorf("body: got %q, want %q", got, want)
+	}
+}
+
+func TestCoalesceRequestsTrailer(t *testing.T) {
+	pr, pw := io.Pipe()
+	tr := &slowTransport{pr: pr, pw: pw, trailer: http.Header{"X-Checksum": nil}}
+
+	c := newCoalescer()
+
+	var responses []*http.Response
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "http://example.com/file", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		res, err := c.roundTrip(tr, req)
+		if err != nil {
+			t.Fatalf("c.roundTrip(): got %v, want no error", err)
+		}
+		defer res.Body.Close()
+
+		if _, ok := res.Trailer["X-Checksum"]; !ok {
+			t.Errorf("%d: res.Trailer: got %v, want X-Checksum declared", i, res.Trailer)
+		}
+		responses = append(responses, res)
+	}
+
+	go func() {
+		pw.Write([]byte("body"))
+		// Like the transport, set the trailer before the body's EOF.
+		tr.trailer.Set("X-Checksum", "abc")
+		pw.Close()
+	}()
+
+	for i, res := range responses {
+		if _, err := ioutil.ReadAll(res.Body); err != nil {
+			t.Fatalf("%d: ioutil.ReadAll(): got %v, want no error", i, err)
+		}
+		if got, want := res.Trailer.Get("X-Checksum"), "abc"; got != want {
+			t.Errorf("%d: res.Trailer.Get(%q): got %q, want %q", i, "X-Checksum", got, want)
+		}
+	}
+}
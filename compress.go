@@ -0,0 +1,260 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompressOptions configures the compression of responses by the proxy, as
+// set with SetCompressResponses.
+type CompressOptions struct {
+	// MinSize is the minimum size in bytes of bodies to compress; smaller
+	// bodies gain little and are sent as is. Zero compresses bodies of any
+	// size.
+	MinSize int64
+
+	// Level is the gzip compression level, from gzip.BestSpeed to
+	// gzip.BestCompression. Zero means gzip.DefaultCompression.
+	Level int
+}
+
+// SetCompressResponses sets the proxy to gzip the bodies of responses that
+// the origin sent uncompressed to clients that accept gzip in
+// Accept-Encoding. Compressed responses have a Content-Encoding of gzip, a
+// chunked body without a Content-Length and "Accept-Encoding" added to Vary;
+// a strong ETag is made weak, since it no longer identifies the bytes sent,
+// and Accept-Ranges is removed.
+//
+// Responses are not compressed if they already have a Content-Encoding, are
+// smaller than opts.MinSize, have a content type that is compressed already,
+// such as images, video and archives, or that is streamed, such as
+// text/event-stream, or have Cache-Control: no-transform. Responses to HEAD,
+// range and HTTP/1.0 requests, and responses without a body, are never
+// compressed. Compression runs after the response modifiers, which see the
+// body as sent by the origin.
+//
+// A nil opts, the default, disables compression. It returns an error for an
+// invalid compression level.
+func (p *Proxy) SetCompressResponses(opts *CompressOptions) error {
+	if opts != nil && opts.Level != 0 {
+		if _, err := gzip.NewWriterLevel(ioutil.Discard, opts.Level); err != nil {
+			return fmt.Errorf("martian: invalid compression level %d", opts.Level)
+		}
+	}
+
+	p.compress = opts
+
+	return nil
+}
+
+// compressedTypes lists content types, or their top-level type followed by a
+// slash, whose bodies are not worth compressing or must not be delayed.
+var compressedTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"font/woff",
+	"font/woff2",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+	"text/event-stream",
+}
+
+// compressResponse gzips the body of res, the response to req, if it should
+// be compressed for the client.
+func (p *Proxy) compressResponse(req *http.Request, res *http.Response) {
+	opts := p.compress
+	if opts == nil || !shouldCompress(req, res) {
+		return
+	}
+
+	if res.ContentLength >= 0 && res.ContentLength < opts.MinSize {
+		return
+	}
+	if res.ContentLength < 0 && opts.MinSize > 0 {
+		// Read ahead to learn whether a body of unknown length is large
+		// enough.
+		head, err := ioutil.ReadAll(io.LimitReader(res.Body, opts.MinSize))
+		res.Body = &prefixBody{Reader: io.MultiReader(bytes.NewReader(head), res.Body), Closer: res.Body}
+		if err != nil {
+			return
+		}
+		if int64(len(head)) < opts.MinSize {
+			// The whole body has been read; send it with its length.
+			res.ContentLength = int64(len(head))
+			res.TransferEncoding = nil
+			return
+		}
+	}
+
+	level := opts.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	zb := &gzipBody{src: res.Body}
+	zb.zw, _ = gzip.NewWriterLevel(&zb.buf, level)
+	res.Body = zb
+
+	res.ContentLength = -1
+	res.TransferEncoding = []string{"chunked"}
+	res.Header.Del("Content-Length")
+	res.Header.Del("Accept-Ranges")
+	res.Header.Set("Content-Encoding", "gzip")
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		res.Header.Set("ETag", "W/"+etag)
+	}
+	if !headerHasToken(res.Header, "Vary", "Accept-Encoding") && !headerHasToken(res.Header, "Vary", "*") {
+		res.Header.Add("Vary", "Accept-Encoding")
+	}
+}
+
+// shouldCompress returns whether res, the response to req, may be compressed
+// regardless of the size of its body.
+func shouldCompress(req *http.Request, res *http.Response) bool {
+	switch {
+	case res.Body == nil, res.Body == http.NoBody, res.ContentLength == 0:
+		return false
+	case req.Method == "HEAD", req.Header.Get("Range") != "", !req.ProtoAtLeast(1, 1):
+		return false
+	case res.StatusCode/100 == 1, res.StatusCode == 204, res.StatusCode == 206, res.StatusCode == 304:
+		return false
+	case res.Header.Get("Content-Encoding") != "", res.Header.Get("Content-Range") != "":
+		return false
+	case headerHasToken(res.Header, "Cache-Control", "no-transform"):
+		return false
+	case !acceptsGzip(req.Header):
+		return false
+	}
+
+	if ct := res.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		switch {
+		case err != nil:
+			return false
+		case mt == "image/svg+xml":
+			return true
+		}
+		for _, t := range compressedTypes {
+			if mt == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// acceptsGzip returns whether the Accept-Encoding of h allows gzip, either by
+// name or by "*", with a non-zero quality.
+func acceptsGzip(h http.Header) bool {
+	gzipq, anyq := -1.0, -1.0
+	for _, v := range h["Accept-Encoding"] {
+		for _, elem := range strings.Split(v, ",") {
+			params := strings.Split(elem, ";")
+			coding := strings.ToLower(strings.TrimSpace(params[0]))
+
+			q := 1.0
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") || strings.HasPrefix(param, "Q=") {
+					if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = f
+					}
+				}
+			}
+
+			switch coding {
+			case "gzip", "x-gzip":
+				gzipq = q
+			case "*":
+				anyq = q
+			}
+		}
+	}
+
+	if gzipq >= 0 {
+		return gzipq > 0
+	}
+
+	return anyq > 0
+}
+
+// headerHasToken returns whether the comma-separated values of the header
+// field name in h include token, compared case-insensitively.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// prefixBody is a body whose first bytes have been read ahead.
+type prefixBody struct {
+	io.Reader
+	io.Closer
+}
+
+// gzipBody compresses the body it reads from src.
+type gzipBody struct {
+	src io.ReadCloser
+	zw  *gzip.Writer
+	buf bytes.Buffer
+	err error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	for b.buf.Len() == 0 && b.err == nil {
+		n, err := b.src.Read(p)
+		if n > 0 {
+			b.zw.Write(p[:n])
+		}
+		if err == io.EOF {
+			b.zw.Close()
+		}
+		b.err = err
+	}
+
+	if b.buf.Len() > 0 {
+		return b.buf.Read(p)
+	}
+
+	return 0, b.err
+}
+
+func (b *gzipBody) Close() error {
+	return b.src.Close()
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestIntegrationCompressResponses(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	if err := p.SetCompressResponses(&CompressOptions{MinSize: 100}); err != nil {
+		t.Fatalf("p.SetCompressResponses(): got %v, want no error", err)
+	}
+
+	large := strings.Repeat("compressible ", 100)
+	small := "tiny"
+
+	tr := martiantest.NewTransport()
+	tr.Func(func(req *http.Request) (*http.Response, error) {
+		body := large
+		if req.URL.Path == "/small" || req.URL.Path == "/small-unknown-length" {
+			body = small
+		}
+
+		res := proxyutil.NewResponse(200, strings.NewReader(body), req)
+		res.ContentLength = int64(len(body))
+		res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		res.Header.Set("ETag", `"v1"`)
+
+		switch req.URL.Path {
+		case "/unknown-length", "/small-unknown-length":
+			res.ContentLength = -1
+		case "/encoded":
+			res.Header.Set("Content-Encoding", "br")
+		case "/image":
+			res.Header.Set("Content-Type", "image/png")
+		case "/no-transform":
+			res.Header.Set("Cache-Control", "public, no-transform")
+		}
+
+		return res, nil
+	})
+	p.SetRoundTripper(tr)
+
+	go p.Serve(l)
+
+	tt := []struct {
+		path           string
+		acceptEncoding string
+		compressed     bool
+		body           string
+	}{
+		{path: "/", acceptEncoding: "gzip, deflate", compressed: true, body: large},
+		{path: "/unknown-length", acceptEncoding: "gzip", compressed: true, body: large},
+		{path: "/", acceptEncoding: "br;q=1, *;q=0.5", compressed: true, body: large},
+		{path: "/", acceptEncoding: "", body: large},
+		{path: "/", acceptEncoding: "gzip;q=0, *", body: large},
+		{path: "/small", acceptEncoding: "gzip", body: small},
+		{path: "/small-unknown-length", acceptEncoding: "gzip", body: small},
+		{path: "/encoded", acceptEncoding: "gzip", body: large},
+		{path: "/image", acceptEncoding: "gzip", body: large},
+		{path: "/no-transform", acceptEncoding: "gzip", body: large},
+	}
+
+	for i, tc := range tt {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("%d. net.Dial(): got %v, want no error", i, err)
+		}
+		defer conn.Close()
+
+		req, err := http.NewRequest("GET", "http://example.com"+tc.path, nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		if tc.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		}
+		if err := req.WriteProxy(conn); err != nil {
+			t.Fatalf("%d. req.WriteProxy(): got %v, want no error", i, err)
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			t.Fatalf("%d. http.ReadResponse(): got %v, want no error", i, err)
+		}
+		defer res.Body.Close()
+
+		got := res.Header.Get("Content-Encoding") == "gzip"
+		if got != tc.compressed {
+			t.Errorf("%d. %s with Accept-Encoding %q: compressed: got %t, want %t", i, tc.path, tc.acceptEncoding, got, tc.compressed)
+			continue
+		}
+
+		body := res.Body
+		if tc.compressed {
+			if res.ContentLength != -1 {
+				t.Errorf("%d. res.ContentLength: got %d, want -1", i, res.ContentLength)
+			}
+			if got, want := res.Header.Get("Vary"), "Accept-Encoding"; got != want {
+				t.Errorf("%d. res.Header.Get(%q): got %q, want %q", i, "Vary", got, want)
+			}
+			if got, want := res.Header.Get("ETag"), `W/"v1"`; got != want {
+				t.Errorf("%d. res.Header.Get(%q): got %q, want %q", i, "ETag", got, want)
+			}
+
+			zr, err := gzip.NewReader(res.Body)
+			if err != nil {
+				t.Fatalf("%d. gzip.NewReader(): got %v, want no error", i, err)
+			}
+			body = zr
+		} else if tc.path != "/unknown-length" {
+			if got, want := res.ContentLength, int64(len(tc.body)); got != want {
+				t.Errorf("%d. res.ContentLength: got %d, want %d", i, got, want)
+			}
+		}
+
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatalf("%d. ioutil.ReadAll(): got %v, want no error", i, err)
+		}
+		if got := string(b); got != tc.body {
+			t.Errorf("%d. body: got %q, want %q", i, got, tc.body)
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tt := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"identity", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"x-gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0, br", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"gzip;q=0, *", false},
+		{"gzip, *;q=0", true},
+	}
+
+	for i, tc := range tt {
+		h := http.Header{}
+		if tc.value != "" {
+			h.Set("Accept-Encoding", tc.value)
+		}
+
+		if got := acceptsGzip(h); got != tc.want {
+			t.Errorf("%d. acceptsGzip(%q): got %t, want %t", i, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestSetCompressResponsesInvalidLevel(t *testing.T) {
+	p := NewProxy()
+	defer p.Close()
+
+	if err := p.SetCompressResponses(&CompressOptions{Level: 42}); err == nil {
+		t.Error("p.SetCompressResponses(): got nil, want error")
+	}
+	if err := p.SetCompressResponses(&CompressOptions{Level: gzip.BestSpeed}); err != nil {
+		t.Errorf("p.SetCompressResponses(): got %v, want no error", err)
+	}
+}
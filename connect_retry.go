@@ -0,0 +1,60 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// SetConnectRetries sets the number of times a failed attempt to connect to
+// the target of a CONNECT request, or to the downstream proxy, is retried
+// before the CONNECT fails with a 502 Bad Gateway. Retries wait for backoff
+// after the first failure, doubling the wait after each further failure, and
+// stop early once the context of the request is done. Only failures to
+// establish the connection are retried; a downstream proxy that responds with
+// an error status is not retried. By default, failures are not retried.
+func (p *Proxy) SetConnectRetries(n int, backoff time.Duration) {
+	p.connectRetries = n
+	p.connectBackoff = backoff
+}
+
+// connect connects to the host of the CONNECT request req, directly or
+// through the downstream proxy, retrying failures as set with
+// SetConnectRetries. It returns the response to the CONNECT and, if a tunnel
+// was established, the connection.
+func (p *Proxy) connect(req *http.Request) (*http.Response, net.Conn, error) {
+	backoff := p.connectBackoff
+	for attempt := 0; ; attempt++ {
+		res, conn, err := p.connectOnce(req)
+		if err == nil || attempt >= p.connectRetries {
+			return res, conn, err
+		}
+
+		log.Debugf("martian: CONNECT attempt %d to %s failed, retrying in %v: %v", attempt+1, req.URL.Host, backoff, err)
+
+		timer := p.clock.NewTimer(backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, nil, err
+		case <-timer.C():
+		}
+		backoff *= 2
+	}
+}
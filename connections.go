@@ -0,0 +1,217 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// ConnectionInfo describes a client connection being served by the proxy.
+type ConnectionInfo struct {
+	// ID is the ID of the connection's session.
+	ID string
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+	// Host is the host of the most recent request read from the connection,
+	// or empty if no request has been read yet.
+	Host string
+	// BytesRead is the number of bytes read from the client.
+	BytesRead int64
+	// BytesWritten is the number of bytes written to the client.
+	BytesWritten int64
+	// Started is the time the connection was accepted.
+	Started time.Time
+	// Duration is the time since the connection was accepted.
+	Duration time.Duration
+}
+
+// connStats holds the counters of a connection tracked by a connRegistry.
+type connStats struct {
+	// read and written are accessed atomically and are kept first in the
+	// struct for 64-bit alignment.
+	read    int64
+	written int64
+
+	id      string
+	remote  net.Addr
+	started time.Time
+	host    atomic.Value
+
+	conn      net.Conn
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// newConnStats returns the stats of conn, accepted at started.
+func newConnStats(conn net.Conn, started time.Time) *connStats {
+	return &connStats{
+		remote:  conn.RemoteAddr(),
+		started: started,
+		conn:    conn,
+		closed:  make(chan struct{}),
+	}
+}
+
+// close closes the connection on behalf of CloseConnection.
+func (cs *connStats) close() error {
+	var err error
+	cs.closeOnce.Do(func() {
+		close(cs.closed)
+		err = cs.conn.Close()
+	})
+
+	return err
+}
+
+// isClosed returns whether the connection was closed by CloseConnection.
+func (cs *connStats) isClosed() bool {
+	select {
+	case <-cs.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// setHost records the host of the request being served.
+func (cs *connStats) setHost(host string) {
+	cs.host.Store(host)
+}
+
+// lastHost returns the host of the most recent request read from the
+// connection, or the empty string if no request has been read yet.
+func (cs *connStats) lastHost() string {
+	host, _ := cs.host.Load().(string)
+
+	return host
+}
+
+// info returns a snapshot of the connection as of now.
+func (cs *connStats) info(now time.Time) ConnectionInfo {
+	return ConnectionInfo{
+		ID:           cs.id,
+		RemoteAddr:   cs.remote,
+		Host:         cs.lastHost(),
+		BytesRead:    atomic.LoadInt64(&cs.read),
+		BytesWritten: atomic.LoadInt64(&cs.written),
+		Started:      cs.started,
+		Duration:     now.Sub(cs.started),
+	}
+}
+
+// connRegistry tracks the connections being served by the proxy. Counters are
+// updated without holding the registry lock, which is only taken when a
+// connection is added or removed and while taking a snapshot.
+type connRegistry struct {
+	mu    sync.Mutex
+	conns map[*connStats]struct{}
+}
+
+// add registers cs.
+func (r *connRegistry) add(cs *connStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conns == nil {
+		r.conns = make(map[*connStats]struct{})
+	}
+	r.conns[cs] = struct{}{}
+}
+
+// remove unregisters cs.
+func (r *connRegistry) remove(cs *connStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.conns, cs)
+}
+
+// lookup returns the registered connection with id.
+func (r *connRegistry) lookup(id string) (*connStats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for cs := range r.conns {
+		if cs.id == id {
+			return cs, true
+		}
+	}
+
+	return nil, false
+}
+
+// snapshot returns the info of all registered connections as of now.
+func (r *connRegistry) snapshot(now time.Time) []ConnectionInfo {
+	r.mu.Lock()
+	all := make([]*connStats, 0, len(r.conns))
+	for cs := range r.conns {
+		all = append(all, cs)
+	}
+	r.mu.Unlock()
+
+	infos := make([]ConnectionInfo, len(all))
+	for i, cs := range all {
+		infos[i] = cs.info(now)
+	}
+
+	return infos
+}
+
+// Connections returns a snapshot of the client connections being served by the
+// proxy, in no particular order. Bytes are counted as read from and written to
+// the client by the proxy: for connections that are MITMed, the decrypted
+// bytes are counted.
+func (p *Proxy) Connections() []ConnectionInfo {
+	return p.conns.snapshot(p.clock.Now())
+}
+
+// CloseConnection closes the client connection with id, as reported by
+// Connections, interrupting any request or tunnel in progress on it. It returns
+// an error if no such connection is being served.
+func (p *Proxy) CloseConnection(id string) error {
+	cs, ok := p.conns.lookup(id)
+	if !ok {
+		return fmt.Errorf("martian: no connection with ID %q", id)
+	}
+
+	log.Debugf("martian: closing connection %s: %v", id, cs.remote)
+	return cs.close()
+}
+
+// countingConn counts the bytes read from and written to a net.Conn.
+type countingConn struct {
+	net.Conn
+	stats *connStats
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.stats.read, int64(n))
+
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.stats.written, int64(n))
+
+	return n, err
+}
@@ -22,6 +22,9 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"time"
+
+	"github.com/google/martian/v3/mitm"
 )
 
 // Context provides information and storage for a single request/response pair.
@@ -36,6 +39,14 @@ type Context struct {
 	skipRoundTrip bool
 	skipLogging   bool
 	apiRequest    bool
+	resetConn     bool
+	socketMark    uint32
+	hasSocketMark bool
+	queueHost     string
+	queueStart    time.Time
+	queueWait     time.Duration
+
+	reqBodyFilters []BodyFilter
 }
 
 // Session provides information and storage about a connection.
@@ -47,6 +58,27 @@ type Session struct {
 	conn     net.Conn
 	brw      *bufio.ReadWriter
 	vals     map[string]interface{}
+	reads    *readCanceler
+	store    Store
+	mitm     *mitm.Config
+	drainGen uint32
+	stats    *connStats
+
+	// tunneled is set once the connection carries requests MITMed from a
+	// CONNECT tunnel.
+	tunneled bool
+
+	// connectHeader holds the headers of the CONNECT request whose tunnel was
+	// MITMed.
+	connectHeader http.Header
+
+	// destination is the host:port that requests without a host in their URL
+	// are sent to, in place of their Host header.
+	destination string
+
+	// passthrough is set if the connection skips all modifiers; see
+	// Proxy.SetSampleRate.
+	passthrough bool
 }
 
 var (
@@ -123,6 +155,19 @@ func (s *Session) MarkInsecure() {
 	s.secure = false
 }
 
+// RemoteAddr returns the remote network address of the connection, or nil if
+// the session has no connection.
+func (s *Session) RemoteAddr() net.Addr {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.RemoteAddr()
+}
+
 // Hijack takes control of the connection from the proxy. No further action
 // will be taken by the proxy and the connection will be closed following the
 // return of the hijacker.
@@ -175,11 +220,61 @@ func (s *Session) Set(key string, val interface{}) {
 	s.vals[key] = val
 }
 
+// SetDestination sets the host, or host:port, that requests read from the
+// session's connection are sent to when their request-target does not name a
+// host, as with the origin-form requests of transparent proxying. By default
+// the proxy takes the host of such requests from their Host header. The Host
+// header itself is sent unchanged. It is typically called by a SessionModifier
+// that knows the original destination of the connection.
+func (s *Session) SetDestination(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.destination = host
+}
+
+// Destination returns the destination set with SetDestination, or the empty
+// string if none was set.
+func (s *Session) Destination() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.destination
+}
+
+// setConnectHeader records the headers of the CONNECT request of a MITMed
+// tunnel.
+func (s *Session) setConnectHeader(h http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connectHeader = h
+}
+
 // Session returns the session for the context.
 func (ctx *Context) Session() *Session {
 	return ctx.session
 }
 
+// Store returns the store set with Proxy.SetSessionStore, or nil if the proxy
+// has no store. Values in the store outlive the session and are shared across
+// connections.
+func (ctx *Context) Store() Store {
+	return ctx.session.store
+}
+
+// ConnectHeaders returns a copy of the headers of the CONNECT request that
+// opened the tunnel the current request was MITMed from, as seen after the
+// request modifiers ran on the CONNECT, or nil if the request was not MITMed
+// from a CONNECT tunnel. The headers belong to the session, not to the
+// request: every request read from the tunnel sees the same headers.
+func (ctx *Context) ConnectHeaders() http.Header {
+	ctx.session.mu.RLock()
+	defer ctx.session.mu.RUnlock()
+
+	return cloneHeader(ctx.session.connectHeader)
+}
+
 // ID returns the context ID.
 func (ctx *Context) ID() string {
 	return ctx.id
@@ -221,6 +316,25 @@ func (ctx *Context) SkippingRoundTrip() bool {
 	return ctx.skipRoundTrip
 }
 
+// ResetConnection aborts the client connection instead of responding to the
+// current request. The proxy closes a TCP connection with SO_LINGER set to
+// zero, so that the client receives a TCP RST, simulating a network failure;
+// other connections are closed normally.
+func (ctx *Context) ResetConnection() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.resetConn = true
+}
+
+// ResettingConnection returns whether the client connection will be reset.
+func (ctx *Context) ResettingConnection() bool {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+
+	return ctx.resetConn
+}
+
 // SkipLogging skips logging by Martian loggers for the current request.
 func (ctx *Context) SkipLogging() {
 	ctx.mu.Lock()
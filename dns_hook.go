@@ -0,0 +1,115 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"net"
+
+	"github.com/google/martian/v3/log"
+)
+
+// SetDNSHook sets a function that observes and modifies the addresses that
+// the hostnames of upstream connections resolve to, for example to simulate
+// DNS based failover by reordering, removing or adding addresses. hook is
+// called with the hostname and its addresses, resolved with the DoH resolver
+// set with SetDoHResolver, and its cache, if any, or with the system resolver
+// otherwise; the slice may be modified. The dialer set with SetDialContext is
+// called with each returned address in order until a connection succeeds. If
+// hook returns no addresses, resolution fails and no connection is made.
+//
+// Addresses that are IP literals are dialed without calling hook. A nil hook,
+// the default, disables it.
+func (p *Proxy) SetDNSHook(hook func(host string, ips []net.IP) []net.IP) {
+	p.dnsHook = hook
+	p.setDialContext(p.baseDialContext)
+}
+
+// resolvingDialContext returns a dial func that resolves hostnames with the
+// DoH resolver and the DNS hook, if set, before calling dial.
+func (p *Proxy) resolvingDialContext(dial func(gocontext.Context, string, string) (net.Conn, error)) func(gocontext.Context, string, string) (net.Conn, error) {
+	doh, hook := p.doh, p.dnsHook
+	if hook == nil {
+		if doh != nil {
+			return doh.dialContext(dial)
+		}
+		return dial
+	}
+
+	return func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		var ips []net.IP
+		if doh != nil {
+			ips, err = doh.lookup(ctx, host)
+			if err != nil {
+				log.Errorf("martian: DoH lookup for %s failed, falling back to system resolver: %v", host, err)
+			}
+			// The cached addresses must not be modified by hook.
+			ips = append([]net.IP(nil), ips...)
+		}
+		if doh == nil || err != nil {
+			ips, err = lookupIP(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ips = hook(host, ips)
+		if len(ips) == 0 {
+			return nil, &net.DNSError{
+				Err:        "no addresses returned by DNS hook",
+				Name:       host,
+				IsNotFound: true,
+			}
+		}
+
+		return dialIPs(ctx, dial, network, host, port, ips)
+	}
+}
+
+// lookupIP resolves host with the system resolver.
+func lookupIP(ctx gocontext.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+
+	return ips, nil
+}
+
+// dialIPs calls dial with each of the addresses ips of host, in order, until a
+// connection succeeds, and returns the error of the last attempt otherwise.
+func dialIPs(ctx gocontext.Context, dial func(gocontext.Context, string, string) (net.Conn, error), network, host, port string, ips []net.IP) (net.Conn, error) {
+	var err error
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dial(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		log.Debugf("martian: failed to dial %s (%s): %v", host, ip, err)
+	}
+
+	return nil, err
+}
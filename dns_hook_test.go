@@ -0,0 +1,159 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestIntegrationDNSHook(t *testing.T) {
+	t.Parallel()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("origin"))
+	}))
+	defer origin.Close()
+
+	_, port, err := net.SplitHostPort(origin.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("net.SplitHostPort(): got %v, want no error", err)
+	}
+
+	var queries int32
+	doh := newDoHServer(t, "origin.test", [4]byte{127, 0, 0, 1}, &queries)
+	defer doh.Close()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	if err := p.SetDoHResolver(doh.URL); err != nil {
+		t.Fatalf("p.SetDoHResolver(): got %v, want no error", err)
+	}
+
+	var mu sync.Mutex
+	var hosts []string
+	var fail bool
+	p.SetDNSHook(func(host string, ips []net.IP) []net.IP {
+		mu.Lock()
+		defer mu.Unlock()
+
+		hosts = append(hosts, host)
+		if len(ips) != 1 || !ips[0].Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Errorf("hook: got ips %v, want [127.0.0.1]", ips)
+		}
+		if fail {
+			return nil
+		}
+
+		// Nothing listens on the origin port of 127.0.0.2, so the dial fails
+		// over to the resolved address.
+		return append([]net.IP{net.IPv4(127, 0, 0, 2)}, ips...)
+	})
+
+	go p.Serve(l)
+
+	proxyURL, err := url.Parse("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("url.Parse(): got %v, want no error", err)
+	}
+	tr := &http.Transport{
+		Proxy: http.ProxyURL(proxyURL),
+	}
+	defer tr.CloseIdleConnections()
+
+	for _, tc := range []struct {
+		fail bool
+		want int
+	}{
+		{false, 200},
+		{true, 502},
+	} {
+		mu.Lock()
+		fail = tc.fail
+		mu.Unlock()
+
+		req, err := http.NewRequest("GET", "http://origin.test:"+port+"/", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		req.Close = true
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("tr.RoundTrip(): got %v, want no error", err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if got := res.StatusCode; got != tc.want {
+			t.Errorf("fail %t: res.StatusCode: got %d, want %d", tc.fail, got, tc.want)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := len(hosts), 2; got != want {
+		t.Fatalf("hook calls: got %d, want %d", got, want)
+	}
+	for _, host := range hosts {
+		if got, want := host, "origin.test"; got != want {
+			t.Errorf("hook host: got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDNSHookSystemResolver(t *testing.T) {
+	p := NewProxy()
+	p.SetDNSHook(func(host string, ips []net.IP) []net.IP {
+		if len(ips) == 0 {
+			t.Errorf("hook: got no ips for %s, want resolved ips", host)
+		}
+		return []net.IP{net.IPv4(192, 0, 2, 1), net.IPv4(192, 0, 2, 2)}
+	})
+
+	var addrs []string
+	dial := p.resolvingDialContext(func(_ gocontext.Context, _, addr string) (net.Conn, error) {
+		addrs = append(addrs, addr)
+		return nil, errors.New("dial failed")
+	})
+
+	if _, err := dial(gocontext.Background(), "tcp", "localhost:80"); err == nil {
+		t.Fatal("dial(): got no error, want error")
+	}
+	want := []string{"192.0.2.1:80", "192.0.2.2:80"}
+	if len(addrs) != len(want) || addrs[0] != want[0] || addrs[1] != want[1] {
+		t.Errorf("dialed addresses: got %v, want %v", addrs, want)
+	}
+
+	// IP literals are dialed without consulting the hook.
+	addrs = nil
+	dial(gocontext.Background(), "tcp", "127.0.0.1:80")
+	if len(addrs) != 1 || addrs[0] != "127.0.0.1:80" {
+		t.Errorf("dialed addresses: got %v, want [127.0.0.1:80]", addrs)
+	}
+}
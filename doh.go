@@ -0,0 +1,244 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	gocontext "context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/martian/v3/internal/clock"
+	"github.com/google/martian/v3/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohMaxResponseSize is the largest DNS message accepted from a DoH server.
+const dohMaxResponseSize = 64 * 1024
+
+// SetDoHResolver sets the DNS-over-HTTPS (RFC 8484) server used to resolve
+// the hostnames of upstream connections, such as "https://dns.google/dns-query".
+// A and AAAA records are looked up with the server and cached for their TTL,
+// and the dialer set with SetDialContext is called with the resolved
+// addresses. If the DoH server cannot be reached, the dialer is called with
+// the hostname and resolves it with the system resolver. The hostname of the
+// DoH server itself is resolved with the system resolver.
+//
+// An empty server URL disables DoH resolution.
+func (p *Proxy) SetDoHResolver(server string) error {
+	if server == "" {
+		p.doh = nil
+		p.setDialContext(p.baseDialContext)
+		return nil
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("martian: invalid DoH server URL scheme %q", u.Scheme)
+	}
+
+	p.doh = newDoHResolver(u.String(), p.clock)
+	p.setDialContext(p.baseDialContext)
+
+	return nil
+}
+
+// dohResolver resolves hostnames with a DNS-over-HTTPS server.
+type dohResolver struct {
+	server string
+	client *http.Client
+
+	clock clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]dohEntry
+}
+
+type dohEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+func newDoHResolver(server string, clk clock.Clock) *dohResolver {
+	return &dohResolver{
+		server: server,
+		clock:  clk,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   10 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		cache: make(map[string]dohEntry),
+	}
+}
+
+// dialContext returns a dial function that resolves the host of each address
+// with r before calling dial.
+func (r *dohResolver) dialContext(dial func(gocontext.Context, string, string) (net.Conn, error)) func(gocontext.Context, string, string) (net.Conn, error) {
+	return func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		ips, err := r.lookup(ctx, host)
+		if err != nil {
+			log.Errorf("martian: DoH lookup for %s failed, falling back to system resolver: %v", host, err)
+			return dial(ctx, network, addr)
+		}
+
+		return dialIPs(ctx, dial, network, host, port, ips)
+	}
+}
+
+// lookup returns the IPv4 and IPv6 addresses of host, IPv4 first.
+func (r *dohResolver) lookup(ctx gocontext.Context, host string) ([]net.IP, error) {
+	r.mu.Lock()
+	e, ok := r.cache[host]
+	r.mu.Unlock()
+
+	if ok && r.clock.Now().Before(e.expires) {
+		return e.ips, nil
+	}
+
+	var ips []net.IP
+	var minTTL uint32
+	var lastErr error
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		qips, ttl, err := r.query(ctx, host, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(qips) > 0 && (minTTL == 0 || ttl < minTTL) {
+			minTTL = ttl
+		}
+		ips = append(ips, qips...)
+	}
+
+	if len(ips) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, lastErr
+	}
+
+	r.mu.Lock()
+	r.cache[host] = dohEntry{
+		ips:     ips,
+		expires: r.clock.Now().Add(time.Duration(minTTL) * time.Second),
+	}
+	r.mu.Unlock()
+
+	return ips, nil
+}
+
+// query sends a single DoH query for host and returns the addresses in the
+// answer along with their smallest TTL.
+func (r *dohResolver) query(ctx gocontext.Context, host string, qtype dnsmessage.Type) ([]net.IP, uint32, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	q, err := msg.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequest("POST", r.server, bytes.NewReader(q))
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, 0, fmt.Errorf("DoH server responded with status %d", res.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, dohMaxResponseSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ans dnsmessage.Message
+	if err := ans.Unpack(b); err != nil {
+		return nil, 0, err
+	}
+	if ans.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DoH query for %s failed: %v", host, ans.RCode)
+	}
+
+	var ips []net.IP
+	var minTTL uint32
+	for _, rr := range ans.Answers {
+		var ip net.IP
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			ip = net.IP(body.AAAA[:])
+		default:
+			continue
+		}
+
+		if len(ips) == 0 || rr.Header.TTL < minTTL {
+			minTTL = rr.Header.TTL
+		}
+		ips = append(ips, ip)
+	}
+
+	return ips, minTTL, nil
+}
+
+// dnsFQDN returns host as a fully qualified domain name.
+func dnsFQDN(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+
+	return host + "."
+}
@@ -0,0 +1,212 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/internal/clock"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newDoHServer returns a DoH server that answers A queries for host with ip.
+func newDoHServer(t *testing.T, host string, ip [4]byte, queries *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(queries, 1)
+
+		if got, want := req.Header.Get("Content-Type"), "application/dns-message"; got != want {
+			t.Errorf("Content-Type: got %q, want %q", got, want)
+		}
+
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), 400)
+			return
+		}
+
+		var msg dnsmessage.Message
+		if err := msg.Unpack(b); err != nil || len(msg.Questions) != 1 {
+			http.Error(rw, "bad query", 400)
+			return
+		}
+
+		q := msg.Questions[0]
+		msg.Header.Response = true
+		if q.Type == dnsmessage.TypeA && q.Name.String() == host+"." {
+			msg.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{
+					Name:  q.Name,
+					Type:  dnsmessage.TypeA,
+					Class: dnsmessage.ClassINET,
+					TTL:   60,
+				},
+				Body: &dnsmessage.AResource{A: ip},
+			}}
+		}
+
+		ans, err := msg.Pack()
+		if err != nil {
+			http.Error(rw, err.Error(), 500)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/dns-message")
+		rw.Write(ans)
+	}))
+}
+
+func TestIntegrationDoHResolver(t *testing.T) {
+	t.Parallel()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("origin"))
+	}))
+	defer origin.Close()
+
+	_, port, err := net.SplitHostPort(origin.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("net.SplitHostPort(): got %v, want no error", err)
+	}
+
+	var queries int32
+	doh := newDoHServer(t, "origin.test", [4]byte{127, 0, 0, 1}, &queries)
+	defer doh.Close()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	if err := p.SetDoHResolver(doh.URL); err != nil {
+		t.Fatalf("p.SetDoHResolver(): got %v, want no error", err)
+	}
+
+	go p.Serve(l)
+
+	proxyURL, err := url.Parse("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("url.Parse(): got %v, want no error", err)
+	}
+	tr := &http.Transport{
+		Proxy: http.ProxyURL(proxyURL),
+	}
+	defer tr.CloseIdleConnections()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "http://origin.test:"+port+"/", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		req.Close = true
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("tr.RoundTrip(): got %v, want no error", err)
+		}
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if got, want := string(b), "origin"; got != want {
+			t.Errorf("res.Body: got %q, want %q", got, want)
+		}
+	}
+
+	// A and AAAA are each queried once; the second request is served from
+	// the cache.
+	if got, want := atomic.LoadInt32(&queries), int32(2); got != want {
+		t.Errorf("queries: got %d, want %d", got, want)
+	}
+}
+
+func TestDoHResolverCacheExpiry(t *testing.T) {
+	var queries int32
+	doh := newDoHServer(t, "origin.test", [4]byte{127, 0, 0, 1}, &queries)
+	defer doh.Close()
+
+	fake := clock.NewFake(time.Now())
+	r := newDoHResolver(doh.URL, fake)
+
+	lookup := func() {
+		ips, err := r.lookup(gocontext.Background(), "origin.test")
+		if err != nil {
+			t.Fatalf("r.lookup(): got %v, want no error", err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Fatalf("r.lookup(): got %v, want [127.0.0.1]", ips)
+		}
+	}
+
+	// The answer has a TTL of 60 seconds; A and AAAA are queried each time
+	// the cache entry is missing or has expired.
+	lookup()
+	fake.Advance(59 * time.Second)
+	lookup()
+	if got, want := atomic.LoadInt32(&queries), int32(2); got != want {
+		t.Errorf("queries before expiry: got %d, want %d", got, want)
+	}
+
+	fake.Advance(time.Second)
+	lookup()
+	if got, want := atomic.LoadInt32(&queries), int32(4); got != want {
+		t.Errorf("queries after expiry: got %d, want %d", got, want)
+	}
+}
+
+func TestDoHResolverFallback(t *testing.T) {
+	var queries int32
+	doh := newDoHServer(t, "origin.test", [4]byte{127, 0, 0, 1}, &queries)
+	doh.Close()
+
+	r := newDoHResolver(doh.URL, clock.Real)
+
+	var addrs []string
+	dial := r.dialContext(func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		addrs = append(addrs, addr)
+		return nil, errors.New("dial error")
+	})
+
+	dial(gocontext.Background(), "tcp", "origin.test:80")
+	dial(gocontext.Background(), "tcp", "127.0.0.1:80")
+
+	if got, want := len(addrs), 2; got != want {
+		t.Fatalf("len(addrs): got %d, want %d", got, want)
+	}
+	if got, want := addrs[0], "origin.test:80"; got != want {
+		t.Errorf("addrs[0]: got %q, want %q", got, want)
+	}
+	if got, want := addrs[1], "127.0.0.1:80"; got != want {
+		t.Errorf("addrs[1]: got %q, want %q", got, want)
+	}
+}
+
+func TestSetDoHResolverInvalidURL(t *testing.T) {
+	p := NewProxy()
+
+	if err := p.SetDoHResolver("ftp://dns.example.com"); err == nil {
+		t.Error("p.SetDoHResolver(): got nil, want error")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package echo provides a modifier that responds to requests for a host with a
+// dump of the request as it was received by the proxy, without a round trip.
+package echo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+const echoKey = "echo.Dump"
+
+func init() {
+	parse.Register("echo.Modifier", modifierFromJSON)
+}
+
+// Modifier is a martian.RequestResponseModifier that echoes requests for a
+// host back to the client.
+type Modifier struct {
+	host string
+}
+
+type modifierJSON struct {
+	Host  string               `json:"host"`
+	Scope []parse.ModifierType `json:"scope"`
+}
+
+// NewEchoModifier returns a modifier that intercepts requests for host and
+// responds with a 200 OK whose body is the request line, headers and body of
+// the request. Requests for other hosts are not modified.
+func NewEchoModifier(host string) *Modifier {
+	return &Modifier{
+		host: host,
+	}
+}
+
+// modifierFromJSON builds an echo.Modifier from JSON.
+//
+// Example JSON:
+// {
+//   "echo.Modifier": {
+//     "scope": ["request", "response"],
+//     "host": "echo.martian.proxy"
+//   }
+// }
+func modifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &modifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return parse.NewResult(NewEchoModifier(msg.Host), msg.Scope)
+}
+
+// ModifyRequest dumps requests for the host and skips their round trip.
+func (m *Modifier) ModifyRequest(req *http.Request) error {
+	if !m.matches(req) {
+		return nil
+	}
+
+	dump, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("echo.ModifyRequest: echoing request: %s", req.URL)
+
+	ctx := martian.NewContext(req)
+	ctx.Set(echoKey, dump)
+	ctx.SkipRoundTrip()
+
+	return nil
+}
+
+// ModifyResponse replaces the response to an echoed request with the request
+// dump.
+func (m *Modifier) ModifyResponse(res *http.Response) error {
+	ctx := martian.NewContext(res.Request)
+	if ctx == nil {
+		return nil
+	}
+
+	v, ok := ctx.Get(echoKey)
+	if !ok {
+		return nil
+	}
+	dump := v.([]byte)
+
+	res.Body.Close()
+
+	res.StatusCode = http.StatusOK
+	res.Status = "200 OK"
+	res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	res.Header.Del("Content-Encoding")
+	res.ContentLength = int64(len(dump))
+	res.Body = ioutil.NopCloser(bytes.NewReader(dump))
+
+	return nil
+}
+
+// matches returns whether req is for the host of the modifier, ignoring case
+// and port.
+func (m *Modifier) matches(req *http.Request) bool {
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.EqualFold(host, m.host)
+}
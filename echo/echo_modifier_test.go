@@ -0,0 +1,136 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestModifyRequestEchoesMatchingHost(t *testing.T) {
+	m := NewEchoModifier("echo.example.com")
+
+	req, err := http.NewRequest("POST", "http://echo.example.com:8080/anything?q=1", strings.NewReader("request body"))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("X-Testing", "true")
+
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if !ctx.SkippingRoundTrip() {
+		t.Error("ctx.SkippingRoundTrip(): got false, want true")
+	}
+
+	res := proxyutil.NewResponse(502, nil, req)
+	if err := m.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	if got, want := res.StatusCode, 200; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := res.ContentLength, int64(len(b)); got != want {
+		t.Errorf("res.ContentLength: got %d, want %d", got, want)
+	}
+
+	for _, want := range []string{
+		"POST /anything?q=1 HTTP/1.1",
+		"X-Testing: true",
+		"request body",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("res.Body: got %q, want to contain %q", b, want)
+		}
+	}
+
+	// The request body is still readable by later modifiers.
+	rb, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := string(rb), "request body"; got != want {
+		t.Errorf("req.Body: got %q, want %q", got, want)
+	}
+}
+
+func TestModifyRequestIgnoresOtherHosts(t *testing.T) {
+	m := NewEchoModifier("echo.example.com")
+
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if ctx.SkippingRoundTrip() {
+		t.Error("ctx.SkippingRoundTrip(): got true, want false")
+	}
+
+	res := proxyutil.NewResponse(404, nil, req)
+	if err := m.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, 404; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"echo.Modifier": {
+			"scope": ["request", "response"],
+			"host": "echo.example.com"
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	if _, ok := r.RequestModifier().(*Modifier); !ok {
+		t.Fatalf("r.RequestModifier(): got %T, want *echo.Modifier", r.RequestModifier())
+	}
+	if _, ok := r.ResponseModifier().(*Modifier); !ok {
+		t.Fatalf("r.ResponseModifier(): got %T, want *echo.Modifier", r.ResponseModifier())
+	}
+}
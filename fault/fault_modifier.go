@@ -0,0 +1,157 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fault provides a modifier that injects synthetic failures into a
+// sample of requests for chaos testing.
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/filter"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+const faultKey = "fault.Injected"
+
+func init() {
+	parse.Register("fault.Modifier", modifierFromJSON)
+}
+
+// Modifier is a martian.RequestResponseModifier that fails a sample of the
+// requests it sees, either with an error response or by resetting the client
+// connection, without a round trip.
+type Modifier struct {
+	rate    float64
+	status  int
+	matcher filter.RequestCondition
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+
+	injected int64
+}
+
+type modifierJSON struct {
+	Rate       float64              `json:"rate"`
+	StatusCode int                  `json:"statusCode"`
+	Scope      []parse.ModifierType `json:"scope"`
+}
+
+// NewFaultInjectionModifier returns a modifier that fails rate (between 0 and
+// 1) of the requests matched by matcher, or of all requests if matcher is nil.
+// A failed request receives a response with status and an empty body; if
+// status is 0 the client connection is reset instead.
+func NewFaultInjectionModifier(rate float64, status int, matcher filter.RequestCondition) *Modifier {
+	return &Modifier{
+		rate:    rate,
+		status:  status,
+		matcher: matcher,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// modifierFromJSON builds a fault.Modifier from JSON. Use a filter to limit the
+// requests it applies to.
+//
+// Example JSON:
+// {
+//   "fault.Modifier": {
+//     "scope": ["request", "response"],
+//     "rate": 0.1,
+//     "statusCode": 503
+//   }
+// }
+func modifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &modifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+	if msg.Rate < 0 || msg.Rate > 1 {
+		return nil, fmt.Errorf("fault.Modifier: rate %v not between 0 and 1", msg.Rate)
+	}
+
+	return parse.NewResult(NewFaultInjectionModifier(msg.Rate, msg.StatusCode, nil), msg.Scope)
+}
+
+// Injected returns the number of faults injected by the modifier.
+func (m *Modifier) Injected() int64 {
+	return atomic.LoadInt64(&m.injected)
+}
+
+// ModifyRequest samples matching requests and, for those selected, skips the
+// round trip or resets the connection.
+func (m *Modifier) ModifyRequest(req *http.Request) error {
+	if m.matcher != nil && !m.matcher.MatchRequest(req) {
+		return nil
+	}
+	if !m.sample() {
+		return nil
+	}
+
+	atomic.AddInt64(&m.injected, 1)
+	ctx := martian.NewContext(req)
+
+	if m.status == 0 {
+		log.Debugf("fault.ModifyRequest: resetting connection: %s", req.URL)
+		ctx.ResetConnection()
+		return nil
+	}
+
+	log.Debugf("fault.ModifyRequest: injecting %d response: %s", m.status, req.URL)
+	ctx.Set(faultKey, true)
+	ctx.SkipRoundTrip()
+
+	return nil
+}
+
+// ModifyResponse replaces the response to a failed request with the fault
+// status.
+func (m *Modifier) ModifyResponse(res *http.Response) error {
+	ctx := martian.NewContext(res.Request)
+	if ctx == nil {
+		return nil
+	}
+	if _, ok := ctx.Get(faultKey); !ok {
+		return nil
+	}
+
+	res.Body.Close()
+
+	res.StatusCode = m.status
+	res.Status = fmt.Sprintf("%d %s", m.status, http.StatusText(m.status))
+	res.Header = http.Header{}
+	res.ContentLength = 0
+	res.Body = ioutil.NopCloser(strings.NewReader(""))
+
+	return nil
+}
+
+// sample returns whether the current request should fail.
+func (m *Modifier) sample() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rnd.Float64() < m.rate
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fault
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestModifierInjectsStatus(t *testing.T) {
+	m := NewFaultInjectionModifier(1, 503, nil)
+
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if !ctx.SkippingRoundTrip() {
+		t.Error("ctx.SkippingRoundTrip(): got false, want true")
+	}
+
+	res := proxyutil.NewResponse(200, nil, req)
+	if err := m.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, 503; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+	if got, want := m.Injected(), int64(1); got != want {
+		t.Errorf("m.Injected(): got %d, want %d", got, want)
+	}
+}
+
+func TestModifierRate(t *testing.T) {
+	tt := []struct {
+		rate    float64
+		min     int64
+		max     int64
+		matches bool
+	}{
+		{rate: 0, min: 0, max: 0, matches: true},
+		{rate: 1, min: 1000, max: 1000, matches: true},
+		{rate: 0.5, min: 400, max: 600, matches: true},
+		{rate: 1, min: 0, max: 0, matches: false},
+	}
+
+	for i, tc := range tt {
+		tm := martiantest.NewMatcher()
+		tm.RequestEvaluatesTo(tc.matches)
+		m := NewFaultInjectionModifier(tc.rate, 500, tm)
+
+		for j := 0; j < 1000; j++ {
+			req, err := http.NewRequest("GET", "http://example.com/", nil)
+			if err != nil {
+				t.Fatalf("http.NewRequest(): got %v, want no error", err)
+			}
+			_, remove, err := martian.TestContext(req, nil, nil)
+			if err != nil {
+				t.Fatalf("martian.TestContext(): got %v, want no error", err)
+			}
+
+			if err := m.ModifyRequest(req); err != nil {
+				t.Fatalf("ModifyRequest(): got %v, want no error", err)
+			}
+			remove()
+		}
+
+		if got := m.Injected(); got < tc.min || got > tc.max {
+			t.Errorf("%d. m.Injected(): got %d, want between %d and %d", i, got, tc.min, tc.max)
+		}
+	}
+}
+
+func TestModifierResetsConnection(t *testing.T) {
+	m := NewFaultInjectionModifier(1, 0, nil)
+
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if !ctx.ResettingConnection() {
+		t.Error("ctx.ResettingConnection(): got false, want true")
+	}
+	if got, want := m.Injected(), int64(1); got != want {
+		t.Errorf("m.Injected(): got %d, want %d", got, want)
+	}
+}
+
+func TestModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"fault.Modifier": {
+			"scope": ["request", "response"],
+			"rate": 0.25,
+			"statusCode": 503
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	m, ok := r.RequestModifier().(*Modifier)
+	if !ok {
+		t.Fatalf("r.RequestModifier(): got %T, want *fault.Modifier", r.RequestModifier())
+	}
+	if got, want := m.rate, 0.25; got != want {
+		t.Errorf("m.rate: got %v, want %v", got, want)
+	}
+	if got, want := m.status, 503; got != want {
+		t.Errorf("m.status: got %d, want %d", got, want)
+	}
+
+	if _, err := parse.FromJSON([]byte(`{"fault.Modifier": {"scope": ["request"], "rate": 2}}`)); err == nil {
+		t.Error("parse.FromJSON(): got nil, want error for invalid rate")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("fault.TTFBDelayModifier", ttfbDelayModifierFromJSON)
+}
+
+// TTFBDelayModifier is a martian.ResponseModifier that delays the first byte of
+// responses, simulating a slow backend.
+type TTFBDelayModifier struct {
+	delay time.Duration
+}
+
+type ttfbDelayModifierJSON struct {
+	Delay string               `json:"delay"`
+	Scope []parse.ModifierType `json:"scope"`
+}
+
+// NewTTFBDelayModifier returns a response modifier that waits for d before
+// returning. Since the proxy writes the response as soon as the response
+// modifiers return, this delays the status line and headers by d, while the
+// body is then sent as fast as the origin provides it. Add it after any other
+// response modifiers, and wrap it in a filter to delay only some routes.
+//
+// The wait ends early if the context of the request is done.
+func NewTTFBDelayModifier(d time.Duration) *TTFBDelayModifier {
+	return &TTFBDelayModifier{
+		delay: d,
+	}
+}
+
+// ttfbDelayModifierFromJSON builds a fault.TTFBDelayModifier from JSON. The
+// delay is a duration string as accepted by time.ParseDuration.
+//
+// Example JSON:
+// {
+//   "fault.TTFBDelayModifier": {
+//     "scope": ["response"],
+//     "delay": "500ms"
+//   }
+// }
+func ttfbDelayModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &ttfbDelayModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	d, err := time.ParseDuration(msg.Delay)
+	if err != nil {
+		return nil, fmt.Errorf("fault.TTFBDelayModifier: invalid delay: %v", err)
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("fault.TTFBDelayModifier: negative delay %v", d)
+	}
+
+	return parse.NewResult(NewTTFBDelayModifier(d), msg.Scope)
+}
+
+// ModifyResponse waits for the delay before the response is written.
+func (m *TTFBDelayModifier) ModifyResponse(res *http.Response) error {
+	if m.delay <= 0 {
+		return nil
+	}
+
+	var done <-chan struct{}
+	if res.Request != nil {
+		done = res.Request.Context().Done()
+	}
+
+	log.Debugf("fault.TTFBDelayModifier: delaying response by %v", m.delay)
+
+	t := time.NewTimer(m.delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-done:
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fault
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestTTFBDelayModifier(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	res := proxyutil.NewResponse(200, nil, req)
+
+	mod := NewTTFBDelayModifier(50 * time.Millisecond)
+
+	start := time.Now()
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := time.Since(start), 50*time.Millisecond; got < want {
+		t.Errorf("ModifyResponse(): returned after %v, want at least %v", got, want)
+	}
+}
+
+func TestTTFBDelayModifierCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req = req.WithContext(ctx)
+	res := proxyutil.NewResponse(200, nil, req)
+
+	mod := NewTTFBDelayModifier(time.Minute)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, max := time.Since(start), 10*time.Second; got > max {
+		t.Errorf("ModifyResponse(): returned after %v, want canceled well before %v", got, max)
+	}
+}
+
+func TestTTFBDelayModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"fault.TTFBDelayModifier": {
+			"scope": ["response"],
+			"delay": "250ms"
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	mod, ok := r.ResponseModifier().(*TTFBDelayModifier)
+	if !ok {
+		t.Fatal("r.ResponseModifier(): got not *TTFBDelayModifier, want *TTFBDelayModifier")
+	}
+	if got, want := mod.delay, 250*time.Millisecond; got != want {
+		t.Errorf("mod.delay: got %v, want %v", got, want)
+	}
+
+	for _, delay := range []string{"slow", "-1s"} {
+		msg := []byte(`{
+			"fault.TTFBDelayModifier": {
+				"scope": ["response"],
+				"delay": "` + delay + `"
+			}
+		}`)
+		if _, err := parse.FromJSON(msg); err == nil {
+			t.Errorf("parse.FromJSON(%q): got no error, want error", delay)
+		}
+	}
+}
@@ -21,9 +21,11 @@
 package fifo
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/google/martian/v3"
 	"github.com/google/martian/v3/log"
@@ -41,6 +43,7 @@ type Group struct {
 	resmods []martian.ResponseModifier
 
 	aggregateErrors bool
+	hook            martian.ModifierHook
 }
 
 type groupJSON struct {
@@ -68,6 +71,15 @@ func (g *Group) SetAggregateErrors(aggerr bool) {
 	g.aggregateErrors = aggerr
 }
 
+// SetModifierHook sets a hook that is called with the time taken by each
+// modifier in the group, to find slow modifiers. Modifiers are identified by
+// martian.ModifierName. A nested group is timed as a whole; set a hook on it to
+// time its own modifiers. By default no hook is set and modifiers are not
+// timed.
+func (g *Group) SetModifierHook(hook martian.ModifierHook) {
+	g.hook = hook
+}
+
 // AddRequestModifier adds a RequestModifier to the group's list of request modifiers.
 func (g *Group) AddRequestModifier(reqmod martian.RequestModifier) {
 	g.reqmu.Lock()
@@ -89,6 +101,12 @@ func (g *Group) AddResponseModifier(resmod martian.ResponseModifier) {
 // aggregateErrors is set to true, the errors returned by each modifier in the group are
 // aggregated.
 func (g *Group) ModifyRequest(req *http.Request) error {
+	return g.ModifyRequestContext(req.Context(), req)
+}
+
+// ModifyRequestContext modifies the request as ModifyRequest does, passing ctx
+// to the modifiers in the group that implement martian.ContextAwareModifier.
+func (g *Group) ModifyRequestContext(ctx context.Context, req *http.Request) error {
 	log.Debugf("fifo.ModifyRequest: %s", req.URL)
 	g.reqmu.RLock()
 	defer g.reqmu.RUnlock()
@@ -96,7 +114,7 @@ func (g *Group) ModifyRequest(req *http.Request) error {
 	merr := martian.NewMultiError()
 
 	for _, reqmod := range g.reqmods {
-		if err := reqmod.ModifyRequest(req); err != nil {
+		if err := g.modifyRequest(ctx, reqmod, req); err != nil {
 			if g.aggregateErrors {
 				merr.Add(err)
 				continue
@@ -129,7 +147,7 @@ func (g *Group) ModifyResponse(res *http.Response) error {
 	merr := martian.NewMultiError()
 
 	for _, resmod := range g.resmods {
-		if err := resmod.ModifyResponse(res); err != nil {
+		if err := g.modifyResponse(resmod, res); err != nil {
 			if g.aggregateErrors {
 				merr.Add(err)
 				continue
@@ -146,6 +164,32 @@ func (g *Group) ModifyResponse(res *http.Response) error {
 	return merr
 }
 
+// modifyRequest runs reqmod, reporting its duration to the hook if one is set.
+func (g *Group) modifyRequest(ctx context.Context, reqmod martian.RequestModifier, req *http.Request) error {
+	if g.hook == nil {
+		return martian.ModifyRequestContext(ctx, reqmod, req)
+	}
+
+	start := time.Now()
+	err := martian.ModifyRequestContext(ctx, reqmod, req)
+	g.hook(martian.ModifierName(reqmod), false, time.Since(start), err)
+
+	return err
+}
+
+// modifyResponse runs resmod, reporting its duration to the hook if one is set.
+func (g *Group) modifyResponse(resmod martian.ResponseModifier, res *http.Response) error {
+	if g.hook == nil {
+		return resmod.ModifyResponse(res)
+	}
+
+	start := time.Now()
+	err := resmod.ModifyResponse(res)
+	g.hook(martian.ModifierName(resmod), true, time.Since(start), err)
+
+	return err
+}
+
 // VerifyRequests returns a MultiError containing all the
 // verification errors returned by request verifiers.
 func (g *Group) VerifyRequests() error {
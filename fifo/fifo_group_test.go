@@ -15,11 +15,13 @@
 package fifo
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/martian/v3"
 	"github.com/google/martian/v3/martiantest"
@@ -111,6 +113,53 @@ func TestModifyRequest(t *testing.T) {
 	}
 }
 
+type contextModifier struct {
+	ctx context.Context
+}
+
+func (m *contextModifier) ModifyRequest(req *http.Request) error {
+	return errors.New("ModifyRequest called on context aware modifier")
+}
+
+func (m *contextModifier) ModifyRequestContext(ctx context.Context, req *http.Request) error {
+	m.ctx = ctx
+	return nil
+}
+
+func TestModifyRequestContext(t *testing.T) {
+	fg := NewGroup()
+	cm := &contextModifier{}
+	fg.AddRequestModifier(cm)
+
+	tm := martiantest.NewModifier()
+	fg.AddRequestModifier(tm)
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	if err := fg.ModifyRequestContext(ctx, req); err != nil {
+		t.Fatalf("fg.ModifyRequestContext(): got %v, want no error", err)
+	}
+	if cm.ctx != ctx {
+		t.Errorf("cm.ctx: got %v, want %v", cm.ctx, ctx)
+	}
+	if !tm.RequestModified() {
+		t.Error("tm.RequestModified(): got false, want true")
+	}
+
+	if err := fg.ModifyRequest(req); err != nil {
+		t.Fatalf("fg.ModifyRequest(): got %v, want no error", err)
+	}
+	if got, want := cm.ctx, req.Context(); got != want {
+		t.Errorf("cm.ctx: got %v, want %v", got, want)
+	}
+}
+
 func TestModifyRequestHaltsOnError(t *testing.T) {
 	fg := NewGroup()
 
@@ -245,6 +294,51 @@ func TestModifyResponseAggregatesErrors(t *testing.T) {
 	}
 }
 
+func TestModifierHook(t *testing.T) {
+	fg := NewGroup()
+
+	type call struct {
+		name     string
+		response bool
+		err      error
+	}
+	var calls []call
+	fg.SetModifierHook(func(name string, response bool, elapsed time.Duration, err error) {
+		if elapsed < 0 {
+			t.Errorf("%s: elapsed: got %v, want >= 0", name, elapsed)
+		}
+		calls = append(calls, call{name, response, err})
+	})
+
+	modErr := errors.New("modifier error")
+	tm := martiantest.NewModifier()
+	tm.ResponseError(modErr)
+	fg.AddRequestModifier(tm)
+	fg.AddRequestModifier(martian.Noop("noop"))
+	fg.AddResponseModifier(tm)
+
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := fg.ModifyRequest(req); err != nil {
+		t.Fatalf("fg.ModifyRequest(): got %v, want no error", err)
+	}
+	res := proxyutil.NewResponse(200, nil, req)
+	if err := fg.ModifyResponse(res); err != modErr {
+		t.Fatalf("fg.ModifyResponse(): got %v, want %v", err, modErr)
+	}
+
+	want := []call{
+		{"*martiantest.Modifier", false, nil},
+		{"noop", false, nil},
+		{"*martiantest.Modifier", true, modErr},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls: got %v, want %v", calls, want)
+	}
+}
+
 func TestVerifyRequests(t *testing.T) {
 	fg := NewGroup()
 
@@ -0,0 +1,182 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("filter.ClientIPFilter", clientIPFilterFromJSON)
+}
+
+// ClientIPMatcher is a conditional evaluator of the IP address of the client
+// that sent a request.
+type ClientIPMatcher struct {
+	nets []*net.IPNet
+}
+
+// NewClientIPMatcher returns a matcher of client addresses in any of cidrs.
+// Each entry is an IPv4 or IPv6 network in CIDR notation, such as "10.0.0.0/8"
+// or "fd00::/8", or a single address.
+func NewClientIPMatcher(cidrs []string) (*ClientIPMatcher, error) {
+	m := &ClientIPMatcher{}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("filter.ClientIPMatcher: invalid address %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			m.nets = append(m.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("filter.ClientIPMatcher: %v", err)
+		}
+		m.nets = append(m.nets, n)
+	}
+
+	return m, nil
+}
+
+// MatchRequest returns whether the client address of req, taken from its
+// RemoteAddr, is in one of the networks. The proxy sets RemoteAddr to the
+// remote address of the client connection, so listeners that rewrite it, for
+// example for the PROXY protocol, are honored. IPv4 addresses mapped to IPv6
+// match IPv4 networks.
+func (m *ClientIPMatcher) MatchRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range m.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchResponse returns whether the client address of the request of res is in
+// one of the networks.
+func (m *ClientIPMatcher) MatchResponse(res *http.Response) bool {
+	if res.Request == nil {
+		return false
+	}
+
+	return m.MatchRequest(res.Request)
+}
+
+// ClientIPFilter runs its modifiers based on the IP address of the client.
+type ClientIPFilter struct {
+	*Filter
+}
+
+type clientIPFilterJSON struct {
+	CIDRs        []string             `json:"cidrs"`
+	Modifier     json.RawMessage      `json:"modifier"`
+	ElseModifier json.RawMessage      `json:"else"`
+	Scope        []parse.ModifierType `json:"scope"`
+}
+
+// NewClientIPFilter returns a filter that runs mod on requests from clients
+// whose address is in one of cidrs, as matched by a ClientIPMatcher. If mod is
+// also a martian.ResponseModifier, it runs on the responses to those requests
+// too. Modifiers for other clients can be set with RequestWhenFalse and
+// ResponseWhenFalse.
+func NewClientIPFilter(cidrs []string, mod martian.RequestModifier) (*ClientIPFilter, error) {
+	m, err := NewClientIPMatcher(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	f := New()
+	f.SetRequestCondition(m)
+	f.SetResponseCondition(m)
+	f.RequestWhenTrue(mod)
+	if resmod, ok := mod.(martian.ResponseModifier); ok {
+		f.ResponseWhenTrue(resmod)
+	}
+
+	return &ClientIPFilter{f}, nil
+}
+
+// clientIPFilterFromJSON builds a filter.ClientIPFilter from JSON.
+//
+// Example JSON:
+// {
+//   "filter.ClientIPFilter": {
+//     "scope": ["request", "response"],
+//     "cidrs": ["10.0.0.0/8", "fd00::/8"],
+//     "modifier": { ... },
+//     "else": { ... }
+//   }
+// }
+func clientIPFilterFromJSON(b []byte) (*parse.Result, error) {
+	msg := &clientIPFilterJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	filter, err := NewClientIPFilter(msg.CIDRs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := parse.FromJSON(msg.Modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.RequestWhenTrue(m.RequestModifier())
+	filter.ResponseWhenTrue(m.ResponseModifier())
+
+	if len(msg.ElseModifier) > 0 {
+		em, err := parse.FromJSON(msg.ElseModifier)
+		if err != nil {
+			return nil, err
+		}
+
+		if em != nil {
+			filter.RequestWhenFalse(em.RequestModifier())
+			filter.ResponseWhenFalse(em.ResponseModifier())
+		}
+	}
+
+	return parse.NewResult(filter, msg.Scope)
+}
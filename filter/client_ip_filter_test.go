@@ -0,0 +1,103 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestClientIPFilter(t *testing.T) {
+	cidrs := []string{"10.0.0.0/8", "192.168.1.7", "2001:db8::/32", "fe80::1"}
+
+	tt := []struct {
+		remoteAddr string
+		want       bool
+	}{
+		{"10.1.2.3:1234", true},
+		{"11.1.2.3:1234", false},
+		{"192.168.1.7:80", true},
+		{"192.168.1.8:80", false},
+		{"[2001:db8::1]:443", true},
+		{"[2001:db9::1]:443", false},
+		{"[fe80::1%eth0]:443", true},
+		{"[::ffff:10.0.0.1]:443", true},
+		{"10.0.0.1", true},
+		{"", false},
+		{"not an address", false},
+	}
+
+	for i, tc := range tt {
+		tm := martiantest.NewModifier()
+		f, err := NewClientIPFilter(cidrs, tm)
+		if err != nil {
+			t.Fatalf("NewClientIPFilter(): got %v, want no error", err)
+		}
+
+		tmelse := martiantest.NewModifier()
+		f.RequestWhenFalse(tmelse)
+		f.ResponseWhenFalse(tmelse)
+
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		req.RemoteAddr = tc.remoteAddr
+
+		if err := f.ModifyRequest(req); err != nil {
+			t.Fatalf("%d. ModifyRequest(): got %v, want no error", i, err)
+		}
+		if got := tm.RequestModified(); got != tc.want {
+			t.Errorf("%d. %q: tm.RequestModified(): got %t, want %t", i, tc.remoteAddr, got, tc.want)
+		}
+		if got := tmelse.RequestModified(); got == tc.want {
+			t.Errorf("%d. %q: tmelse.RequestModified(): got %t, want %t", i, tc.remoteAddr, got, !tc.want)
+		}
+
+		res := proxyutil.NewResponse(200, nil, req)
+		if err := f.ModifyResponse(res); err != nil {
+			t.Fatalf("%d. ModifyResponse(): got %v, want no error", i, err)
+		}
+		if got := tm.ResponseModified(); got != tc.want {
+			t.Errorf("%d. %q: tm.ResponseModified(): got %t, want %t", i, tc.remoteAddr, got, tc.want)
+		}
+	}
+}
+
+func TestNewClientIPFilterInvalid(t *testing.T) {
+	for _, cidr := range []string{"10.0.0.0/33", "example.com", ""} {
+		if _, err := NewClientIPFilter([]string{cidr}, nil); err == nil {
+			t.Errorf("NewClientIPFilter(%q): got no error, want error", cidr)
+		}
+	}
+}
+
+func TestClientIPFilterFromJSONInvalid(t *testing.T) {
+	msg := []byte(`{
+		"filter.ClientIPFilter": {
+			"scope": ["request"],
+			"cidrs": ["10.0.0.0/33"],
+			"modifier": {}
+		}
+	}`)
+
+	if _, err := parse.FromJSON(msg); err == nil {
+		t.Error("parse.FromJSON(): got no error, want error for invalid CIDR")
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fixture provides a modifier that serves canned responses for matched
+// requests without a round trip, acting as a mock server embedded in the proxy.
+package fixture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/filter"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/martianurl"
+	"github.com/google/martian/v3/parse"
+)
+
+const fixtureKey = "fixture.Fixture"
+
+// fileExt is the extension of fixture files loaded from a directory.
+const fileExt = ".http"
+
+func init() {
+	parse.Register("fixture.Modifier", modifierFromJSON)
+}
+
+// Fixture is a canned response.
+type Fixture struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+type entry struct {
+	cond    filter.RequestCondition
+	fixture *Fixture
+}
+
+// Modifier is a martian.RequestResponseModifier that serves fixtures for
+// matched requests. Requests that match no fixture are not modified.
+type Modifier struct {
+	mu      sync.RWMutex
+	entries []entry
+}
+
+type fixtureJSON struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+type modifierJSON struct {
+	Fixtures []fixtureJSON        `json:"fixtures"`
+	Dir      string               `json:"dir"`
+	Scope    []parse.ModifierType `json:"scope"`
+}
+
+// NewFixtureModifier returns a modifier that serves the fixture of the first
+// condition in fixtures that matches a request. Map iteration order is
+// unspecified, so conditions in fixtures should not overlap; use Add to give
+// fixtures a precedence.
+func NewFixtureModifier(fixtures map[filter.RequestCondition]Fixture) *Modifier {
+	m := &Modifier{}
+	for cond, f := range fixtures {
+		m.Add(cond, f)
+	}
+
+	return m
+}
+
+// Add adds a fixture served for requests matched by cond. Fixtures are
+// checked in the order they are added.
+func (m *Modifier) Add(cond filter.RequestCondition, f Fixture) {
+	if f.StatusCode == 0 {
+		f.StatusCode = http.StatusOK
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = append(m.entries, entry{cond: cond, fixture: &f})
+}
+
+// LoadDir adds a fixture for each file in dir with the extension ".http". Each
+// file holds an HTTP response, status line, headers and body, as written by
+// http.Response.Write or curl -i. The path of the file relative to dir gives
+// the host and path of the requests it is served for: the file
+// example.com/api/users.http is served for requests to example.com/api/users,
+// and example.com/index.http for requests to example.com/.
+func (m *Modifier) LoadDir(dir string) error {
+	return filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(p) != fileExt {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, fileExt))
+
+		i := strings.IndexByte(rel, '/')
+		if i < 0 {
+			return fmt.Errorf("fixture: %s is not in a host directory", p)
+		}
+		host, upath := rel[:i], rel[i:]
+		if path.Base(upath) == "index" {
+			upath = strings.TrimSuffix(upath, "index")
+		}
+
+		f, err := readFixture(p)
+		if err != nil {
+			return err
+		}
+
+		log.Debugf("fixture.LoadDir: loaded fixture for %s%s", host, upath)
+		m.Add(martianurl.NewMatcher(&url.URL{Host: host, Path: upath}), *f)
+
+		return nil
+	})
+}
+
+// readFixture reads the HTTP response in the file at p.
+func readFixture(p string) (*Fixture, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fixture: failed to parse %s: %v", p, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fixture: failed to read body of %s: %v", p, err)
+	}
+
+	// The body is served with its own length.
+	res.Header.Del("Content-Length")
+	res.Header.Del("Transfer-Encoding")
+
+	return &Fixture{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}, nil
+}
+
+// modifierFromJSON builds a fixture.Modifier from JSON. Fixtures may be given
+// inline, loaded from a directory, or both. An inline fixture is served for
+// requests matching all non-empty parts of its URL.
+//
+// Example JSON:
+// {
+//   "fixture.Modifier": {
+//     "scope": ["request", "response"],
+//     "fixtures": [
+//       {
+//         "url": "http://api.example.com/users",
+//         "statusCode": 200,
+//         "header": {
+//           "Content-Type": ["application/json"]
+//         },
+//         "body": "[]"
+//       }
+//     ],
+//     "dir": "/etc/martian/fixtures"
+//   }
+// }
+func modifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &modifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	mod := &Modifier{}
+	for _, fj := range msg.Fixtures {
+		u, err := url.Parse(fj.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		mod.Add(martianurl.NewMatcher(u), Fixture{
+			StatusCode: fj.StatusCode,
+			Header:     fj.Header,
+			Body:       []byte(fj.Body),
+		})
+	}
+	if msg.Dir != "" {
+		if err := mod.LoadDir(msg.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return parse.NewResult(mod, msg.Scope)
+}
+
+// ModifyRequest skips the round trip of requests with a fixture.
+func (m *Modifier) ModifyRequest(req *http.Request) error {
+	if req.Method == "CONNECT" {
+		return nil
+	}
+
+	f := m.match(req)
+	if f == nil {
+		return nil
+	}
+
+	log.Debugf("fixture.ModifyRequest: serving fixture for %s", req.URL)
+
+	ctx := martian.NewContext(req)
+	ctx.Set(fixtureKey, f)
+	ctx.SkipRoundTrip()
+
+	return nil
+}
+
+// ModifyResponse replaces the response to a request with a fixture with the
+// fixture.
+func (m *Modifier) ModifyResponse(res *http.Response) error {
+	ctx := martian.NewContext(res.Request)
+	if ctx == nil {
+		return nil
+	}
+
+	v, ok := ctx.Get(fixtureKey)
+	if !ok {
+		return nil
+	}
+	f := v.(*Fixture)
+
+	if res.Body != nil {
+		res.Body.Close()
+	}
+
+	res.StatusCode = f.StatusCode
+	res.Status = fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode))
+	res.Header = http.Header{}
+	for k, vs := range f.Header {
+		res.Header[k] = append([]string(nil), vs...)
+	}
+	res.Header.Set("Content-Length", strconv.Itoa(len(f.Body)))
+	res.ContentLength = int64(len(f.Body))
+	res.TransferEncoding = nil
+	res.Body = ioutil.NopCloser(bytes.NewReader(f.Body))
+
+	return nil
+}
+
+// match returns the fixture for req, or nil if there is none.
+func (m *Modifier) match(req *http.Request) *Fixture {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.entries {
+		if e.cond.MatchRequest(req) {
+			return e.fixture
+		}
+	}
+
+	return nil
+}
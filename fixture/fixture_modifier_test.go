@@ -0,0 +1,185 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/filter"
+	"github.com/google/martian/v3/martianurl"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+// serve runs req through mod and returns the response, which is the origin
+// response if req has no fixture.
+func serve(t *testing.T, mod *Modifier, rawurl string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := mod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	res := proxyutil.NewResponse(299, nil, req)
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	return res
+}
+
+func TestFixtureModifier(t *testing.T) {
+	mod := NewFixtureModifier(map[filter.RequestCondition]Fixture{
+		martianurl.NewMatcher(&url.URL{Host: "api.example.com", Path: "/users"}): {
+			StatusCode: 201,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       []byte(`[{"name":"martian"}]`),
+		},
+	})
+
+	res := serve(t, mod, "http://api.example.com/users")
+	if got, want := res.StatusCode, 201; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+	if got, want := res.Header.Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("res.Header.Get(%q): got %q, want %q", "Content-Type", got, want)
+	}
+	if got, want := res.ContentLength, int64(20); got != want {
+		t.Errorf("res.ContentLength: got %d, want %d", got, want)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := string(body), `[{"name":"martian"}]`; got != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+
+	// Serving a fixture does not modify it.
+	res = serve(t, mod, "http://api.example.com/users")
+	res.Header.Set("Content-Type", "text/plain")
+	res = serve(t, mod, "http://api.example.com/users")
+	if got, want := res.Header.Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("res.Header.Get(%q): got %q, want %q", "Content-Type", got, want)
+	}
+
+	res = serve(t, mod, "http://api.example.com/groups")
+	if got, want := res.StatusCode, 299; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestFixtureModifierPrecedence(t *testing.T) {
+	mod := &Modifier{}
+	mod.Add(martianurl.NewMatcher(&url.URL{Path: "/special"}), Fixture{StatusCode: 202})
+	mod.Add(martianurl.NewMatcher(&url.URL{Host: "example.com"}), Fixture{})
+
+	if got, want := serve(t, mod, "http://example.com/special").StatusCode, 202; got != want {
+		t.Errorf("/special: res.StatusCode: got %d, want %d", got, want)
+	}
+	if got, want := serve(t, mod, "http://example.com/other").StatusCode, 200; got != want {
+		t.Errorf("/other: res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestFixtureModifierFromJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixture")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"example.com/index.http":     "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<html></html>",
+		"example.com/api/users.http": "HTTP/1.1 404 Not Found\r\nContent-Length: 2\r\n\r\n{}",
+		"example.com/README":         "not a fixture",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("os.MkdirAll(): got %v, want no error", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile(): got %v, want no error", err)
+		}
+	}
+
+	msg := []byte(fmt.Sprintf(`{
+		"fixture.Modifier": {
+			"scope": ["request", "response"],
+			"fixtures": [
+				{
+					"url": "http://inline.example.com/",
+					"statusCode": 418,
+					"body": "teapot"
+				}
+			],
+			"dir": %q
+		}
+	}`, dir))
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	mod, ok := r.RequestModifier().(*Modifier)
+	if !ok {
+		t.Fatal("r.RequestModifier(): got not *Modifier, want *Modifier")
+	}
+
+	tt := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"http://inline.example.com/", 418, "teapot"},
+		{"http://example.com/", 200, "<html></html>"},
+		{"https://example.com/api/users", 404, "{}"},
+		{"http://example.com/README", 299, ""},
+	}
+
+	for _, tc := range tt {
+		res := serve(t, mod, tc.url)
+		if got, want := res.StatusCode, tc.status; got != want {
+			t.Errorf("%s: res.StatusCode: got %d, want %d", tc.url, got, want)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+		}
+		if got, want := string(body), tc.body; got != want {
+			t.Errorf("%s: res.Body: got %q, want %q", tc.url, got, want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ForwardedMode selects the headers the proxy adds to upstream requests to
+// identify the client.
+type ForwardedMode int
+
+const (
+	// ForwardedNone adds no forwarding headers.
+	ForwardedNone ForwardedMode = iota
+	// ForwardedXFF appends the client IP to X-Forwarded-For and sets
+	// X-Forwarded-Proto and X-Forwarded-Host when absent.
+	ForwardedXFF
+	// ForwardedRFC7239 appends a forwarded-element to the Forwarded header.
+	//
+	// https://tools.ietf.org/html/rfc7239
+	ForwardedRFC7239
+)
+
+// SetForwardedHeaders sets the forwarding headers added to requests before
+// they are passed to the request modifier. The client address is taken from
+// the remote address of the connection, so listeners that rewrite RemoteAddr
+// (e.g. for the PROXY protocol) are honored.
+func (p *Proxy) SetForwardedHeaders(mode ForwardedMode) {
+	p.forwardedMode = mode
+}
+
+// SetStripForwardedFor sets whether forwarding headers sent by the client are
+// sanitized before the proxy adds its own. When enabled, only the entries
+// appended by the trusted hops configured with SetTrustedForwardedHops are kept
+// in X-Forwarded-For and Forwarded; with no trusted hops those headers, along
+// with X-Forwarded-Proto and X-Forwarded-Host, are removed entirely.
+func (p *Proxy) SetStripForwardedFor(strip bool) {
+	p.stripForwarded = strip
+}
+
+// SetTrustedForwardedHops sets the number of proxies in front of this proxy
+// whose forwarding entries are trusted. The last hops entries of the inbound
+// chain are kept when stripping is enabled.
+func (p *Proxy) SetTrustedForwardedHops(hops int) {
+	if hops < 0 {
+		hops = 0
+	}
+
+	p.trustedHops = hops
+}
+
+// stripForwardedHeaders trims the inbound forwarding headers of req to the last
+// hops entries.
+func stripForwardedHeaders(req *http.Request, hops int) {
+	for _, name := range []string{"X-Forwarded-For", "Forwarded"} {
+		elems := splitForwarded(req.Header[name])
+		if len(elems) > hops {
+			elems = elems[len(elems)-hops:]
+		}
+
+		if len(elems) == 0 {
+			req.Header.Del(name)
+			continue
+		}
+		req.Header.Set(name, strings.Join(elems, ", "))
+	}
+
+	if hops == 0 {
+		req.Header.Del("X-Forwarded-Proto")
+		req.Header.Del("X-Forwarded-Host")
+	}
+}
+
+// forwardedProto returns the lower-cased protocol in the X-Forwarded-Proto
+// header of req as set by the nearest hop, or "" if there is none.
+func forwardedProto(req *http.Request) string {
+	elems := splitForwarded(req.Header["X-Forwarded-Proto"])
+	if len(elems) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(elems[len(elems)-1])
+}
+
+// splitForwarded splits comma separated header values into their elements,
+// ignoring commas within quoted-strings.
+func splitForwarded(values []string) []string {
+	var elems []string
+	for _, v := range values {
+		var quoted bool
+		start := 0
+		for i := 0; i <= len(v); i++ {
+			if i < len(v) {
+				switch v[i] {
+				case '"':
+					quoted = !quoted
+					continue
+				case ',':
+					if quoted {
+						continue
+					}
+				default:
+					continue
+				}
+			}
+
+			if elem := strings.TrimSpace(v[start:i]); elem != "" {
+				elems = append(elems, elem)
+			}
+			start = i + 1
+		}
+	}
+
+	return elems
+}
+
+// addForwardedHeaders adds the forwarding headers for mode to req.
+func addForwardedHeaders(req *http.Request, mode ForwardedMode) {
+	if mode == ForwardedNone {
+		return
+	}
+
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	switch mode {
+	case ForwardedXFF:
+		xff := ip
+		if prior := req.Header["X-Forwarded-For"]; len(prior) > 0 {
+			xff = strings.Join(prior, ", ") + ", " + ip
+		}
+		req.Header.Set("X-Forwarded-For", xff)
+
+		if req.Header.Get("X-Forwarded-Proto") == "" {
+			req.Header.Set("X-Forwarded-Proto", req.URL.Scheme)
+		}
+		if req.Header.Get("X-Forwarded-Host") == "" {
+			req.Header.Set("X-Forwarded-Host", req.Host)
+		}
+	case ForwardedRFC7239:
+		elem := fmt.Sprintf("for=%s;host=%s;proto=%s", forwardedNode(ip), forwardedValue(req.Host), req.URL.Scheme)
+		if prior := req.Header["Forwarded"]; len(prior) > 0 {
+			elem = strings.Join(prior, ", ") + ", " + elem
+		}
+		req.Header.Set("Forwarded", elem)
+	}
+}
+
+// forwardedNode formats ip as a node for the Forwarded header; IPv6 addresses
+// are bracketed and quoted.
+func forwardedNode(ip string) string {
+	if strings.Contains(ip, ":") {
+		return fmt.Sprintf("%q", "["+ip+"]")
+	}
+
+	return forwardedValue(ip)
+}
+
+// forwardedValue returns v as a token, or as a quoted-string if it contains
+// characters that are not allowed in a token.
+func forwardedValue(v string) string {
+	for _, r := range v {
+		if !isTokenRune(r) {
+			return fmt.Sprintf("%q", v)
+		}
+	}
+
+	return v
+}
+
+func isTokenRune(r rune) bool {
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+		return true
+	}
+
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAddForwardedHeaders(t *testing.T) {
+	tt := []struct {
+		mode       ForwardedMode
+		remoteAddr string
+		prior      http.Header
+		want       http.Header
+	}{
+		{
+			mode:       ForwardedNone,
+			remoteAddr: "10.0.0.1:1234",
+			want:       http.Header{},
+		},
+		{
+			mode:       ForwardedXFF,
+			remoteAddr: "10.0.0.1:1234",
+			want: http.Header{
+				"X-Forwarded-For":   []string{"10.0.0.1"},
+				"X-Forwarded-Proto": []string{"http"},
+				"X-Forwarded-Host":  []string{"example.com"},
+			},
+		},
+		{
+			mode:       ForwardedXFF,
+			remoteAddr: "10.0.0.1:1234",
+			prior: http.Header{
+				"X-Forwarded-For":   []string{"192.0.2.1, 192.0.2.2", "192.0.2.3"},
+				"X-Forwarded-Proto": []string{"https"},
+			},
+			want: http.Header{
+				"X-Forwarded-For":   []string{"192.0.2.1, 192.0.2.2, 192.0.2.3, 10.0.0.1"},
+				"X-Forwarded-Proto": []string{"https"},
+				"X-Forwarded-Host":  []string{"example.com"},
+			},
+		},
+		{
+			mode:       ForwardedRFC7239,
+			remoteAddr: "10.0.0.1:1234",
+			want: http.Header{
+				"Forwarded": []string{"for=10.0.0.1;host=example.com;proto=http"},
+			},
+		},
+		{
+			mode:       ForwardedRFC7239,
+			remoteAddr: "[2001:db8::1]:1234",
+			prior: http.Header{
+				"Forwarded": []string{"for=192.0.2.1"},
+			},
+			want: http.Header{
+				"Forwarded": []string{`for=192.0.2.1, for="[2001:db8::1]";host=example.com;proto=http`},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		req.RemoteAddr = tc.remoteAddr
+		for k, v := range tc.prior {
+			req.Header[k] = v
+		}
+
+		addForwardedHeaders(req, tc.mode)
+
+		if got, want := len(req.Header), len(tc.want); got != want {
+			t.Errorf("%d. len(req.Header): got %d, want %d: %v", i, got, want, req.Header)
+		}
+		for k := range tc.want {
+			if got, want := req.Header.Get(k), tc.want.Get(k); got != want {
+				t.Errorf("%d. req.Header.Get(%q): got %q, want %q", i, k, got, want)
+			}
+		}
+	}
+}
+
+func TestStripForwardedHeaders(t *testing.T) {
+	tt := []struct {
+		hops  int
+		prior http.Header
+		want  http.Header
+	}{
+		{
+			hops: 0,
+			prior: http.Header{
+				"X-Forwarded-For":   []string{"6.6.6.6, 192.0.2.1"},
+				"X-Forwarded-Proto": []string{"https"},
+				"X-Forwarded-Host":  []string{"forged.example.com"},
+				"Forwarded":         []string{"for=6.6.6.6"},
+			},
+			want: http.Header{},
+		},
+		{
+			hops: 1,
+			prior: http.Header{
+				"X-Forwarded-For": []string{"6.6.6.6, 7.7.7.7", "192.0.2.1"},
+				"Forwarded":       []string{`for=6.6.6.6, for="[2001:db8::1]";host="a,b"`},
+			},
+			want: http.Header{
+				"X-Forwarded-For": []string{"192.0.2.1"},
+				"Forwarded":       []string{`for="[2001:db8::1]";host="a,b"`},
+			},
+		},
+		{
+			hops: 3,
+			prior: http.Header{
+				"X-Forwarded-For": []string{"192.0.2.1"},
+			},
+			want: http.Header{
+				"X-Forwarded-For": []string{"192.0.2.1"},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		for k, v := range tc.prior {
+			req.Header[k] = v
+		}
+
+		stripForwardedHeaders(req, tc.hops)
+
+		if got, want := len(req.Header), len(tc.want); got != want {
+			t.Errorf("%d. len(req.Header): got %d, want %d: %v", i, got, want, req.Header)
+		}
+		for k := range tc.want {
+			if got, want := req.Header.Get(k), tc.want.Get(k); got != want {
+				t.Errorf("%d. req.Header.Get(%q): got %q, want %q", i, k, got, want)
+			}
+		}
+	}
+}
+
+func TestStripThenAddForwardedHeaders(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "6.6.6.6")
+
+	stripForwardedHeaders(req, 0)
+	addForwardedHeaders(req, ForwardedXFF)
+
+	if got, want := req.Header.Get("X-Forwarded-For"), "10.0.0.1"; got != want {
+		t.Errorf("req.Header.Get(%q): got %q, want %q", "X-Forwarded-For", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// ListenFDsEnv is the environment variable that tells a process how many
+// listening sockets it inherited from its parent; see InheritedListeners.
+const ListenFDsEnv = "MARTIAN_LISTEN_FDS"
+
+// firstInheritedFD is the first file descriptor handed to a child process,
+// after standard input, output and error.
+const firstInheritedFD = 3
+
+// ListenerFiles returns a duplicate of the file descriptor of each listener
+// being served, in the order returned by Addrs, so that they can be handed to
+// a new process for a restart without dropping connections. Each listener
+// must be a *net.TCPListener, a *net.UnixListener or another listener with a
+// File method. The caller must close the files. File descriptors cannot be
+// duplicated on Windows.
+//
+// The handoff proceeds as follows:
+//
+//  1. The old process passes the files to the new process as
+//     exec.Cmd.ExtraFiles, which makes them file descriptors 3 onwards, and
+//     sets ListenFDsEnv to their count in the environment of the new process.
+//  2. The new process calls InheritedListeners and serves the listeners.
+//     Both processes now accept connections from the same sockets.
+//  3. Once the new process is ready, by whatever means the caller uses to
+//     signal it, the old process cancels the contexts passed to ServeContext.
+//     It stops accepting connections, and the connections it has already
+//     accepted are closed after their current request. Connections reports
+//     when none are left, after which the old process can exit.
+func (p *Proxy) ListenerFiles() ([]*os.File, error) {
+	p.listenermu.Lock()
+	defer p.listenermu.Unlock()
+
+	var files []*os.File
+	for _, l := range p.listeners {
+		fl, ok := l.(interface {
+			File() (*os.File, error)
+		})
+		if !ok {
+			closeFiles(files)
+			return nil, fmt.Errorf("martian: listener %s of type %T has no file descriptor", l.Addr(), l)
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, fmt.Errorf("martian: failed to duplicate listener %s: %v", l.Addr(), err)
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// InheritedListeners returns the listeners handed to the process by its parent
+// with ListenerFiles, in the same order, or nil if ListenFDsEnv is not set. It
+// unsets ListenFDsEnv so that the listeners are not inherited again by
+// processes started later, and must be called once.
+func InheritedListeners() ([]net.Listener, error) {
+	v, ok := os.LookupEnv(ListenFDsEnv)
+	if !ok {
+		return nil, nil
+	}
+	os.Unsetenv(ListenFDsEnv)
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("martian: invalid %s %q", ListenFDsEnv, v)
+	}
+
+	files := make([]*os.File, n)
+	for i := range files {
+		fd := firstInheritedFD + i
+		files[i] = os.NewFile(uintptr(fd), "martian-listener-"+strconv.Itoa(fd))
+	}
+
+	return listenersFromFiles(files)
+}
+
+// listenersFromFiles returns a listener for each of files, and closes the
+// files, since each listener holds a duplicate.
+func listenersFromFiles(files []*os.File) ([]net.Listener, error) {
+	defer closeFiles(files)
+
+	var ls []net.Listener
+	for _, f := range files {
+		if f == nil {
+			closeListeners(ls)
+			return nil, errors.New("martian: invalid inherited file descriptor")
+		}
+
+		l, err := net.FileListener(f)
+		if err != nil {
+			closeListeners(ls)
+			return nil, fmt.Errorf("martian: inherited file %s is not a listener: %v", f.Name(), err)
+		}
+		ls = append(ls, l)
+	}
+
+	return ls, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+func closeListeners(ls []net.Listener) {
+	for _, l := range ls {
+		l.Close()
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/martiantest"
+)
+
+func TestListenerHandoff(t *testing.T) {
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	old := NewProxy()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- old.ServeContext(ctx, l, nil)
+	}()
+
+	for old.Addr() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	files, err := old.ListenerFiles()
+	if err != nil {
+		t.Fatalf("ListenerFiles(): got %v, want no error", err)
+	}
+	if got, want := len(files), 1; got != want {
+		t.Fatalf("len(ListenerFiles()): got %d, want %d", got, want)
+	}
+
+	ls, err := listenersFromFiles(files)
+	if err != nil {
+		t.Fatalf("listenersFromFiles(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	tr := martiantest.NewTransport()
+	tr.Respond(204)
+	p.SetRoundTripper(tr)
+	p.SetTimeout(2 * time.Second)
+	go p.Serve(ls[0])
+	defer ls[0].Close()
+
+	// Once the old proxy stops, the new one keeps serving the socket.
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("ServeContext(): got %v, want no error", err)
+	}
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.WriteProxy(conn); err != nil {
+		t.Fatalf("req.WriteProxy(): got %v, want no error", err)
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	res.Body.Close()
+	if got, want := res.StatusCode, 204; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestInheritedListeners(t *testing.T) {
+	os.Unsetenv(ListenFDsEnv)
+	ls, err := InheritedListeners()
+	if err != nil {
+		t.Fatalf("InheritedListeners(): got %v, want no error", err)
+	}
+	if ls != nil {
+		t.Errorf("InheritedListeners(): got %v, want nil without %s", ls, ListenFDsEnv)
+	}
+
+	os.Setenv(ListenFDsEnv, "bogus")
+	if _, err := InheritedListeners(); err == nil {
+		t.Errorf("InheritedListeners(): got no error, want error for invalid %s", ListenFDsEnv)
+	}
+	if _, ok := os.LookupEnv(ListenFDsEnv); ok {
+		t.Errorf("os.LookupEnv(%q): got set, want unset", ListenFDsEnv)
+	}
+}
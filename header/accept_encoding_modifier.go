@@ -0,0 +1,90 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("header.AcceptEncodingModifier", acceptEncodingModifierFromJSON)
+}
+
+// AcceptEncodingModifier is a request modifier that overrides the
+// Accept-Encoding header of requests.
+type AcceptEncodingModifier struct {
+	value string
+}
+
+type acceptEncodingModifierJSON struct {
+	Value string               `json:"value"`
+	Scope []parse.ModifierType `json:"scope"`
+}
+
+// NewAcceptEncodingModifier returns a request modifier that replaces the
+// Accept-Encoding header of each request with value, such as "identity" to ask
+// for uncompressed responses or "gzip" to allow only gzip. The codings in value
+// are lowercased and separated by ", ". An empty value is treated as
+// "identity"; the header is never removed, since without it the default
+// round tripper asks for gzip itself and decompresses the response.
+//
+// The header is a preference, and origins are free to ignore it, so responses
+// may still arrive with a Content-Encoding that was not asked for. To inspect
+// such bodies, decode them, for example with log.Logger's decode option.
+func NewAcceptEncodingModifier(value string) *AcceptEncodingModifier {
+	var codings []string
+	for _, c := range strings.Split(value, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			codings = append(codings, c)
+		}
+	}
+	if len(codings) == 0 {
+		codings = []string{"identity"}
+	}
+
+	return &AcceptEncodingModifier{
+		value: strings.Join(codings, ", "),
+	}
+}
+
+// ModifyRequest sets the Accept-Encoding header of req.
+func (m *AcceptEncodingModifier) ModifyRequest(req *http.Request) error {
+	req.Header.Set("Accept-Encoding", m.value)
+
+	return nil
+}
+
+// acceptEncodingModifierFromJSON builds a header.AcceptEncodingModifier from
+// JSON.
+//
+// Example JSON:
+// {
+//   "header.AcceptEncodingModifier": {
+//     "scope": ["request"],
+//     "value": "identity"
+//   }
+// }
+func acceptEncodingModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &acceptEncodingModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return parse.NewResult(NewAcceptEncodingModifier(msg.Value), msg.Scope)
+}
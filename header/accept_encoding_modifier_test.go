@@ -0,0 +1,87 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/martian/v3/parse"
+)
+
+func TestAcceptEncodingModifier(t *testing.T) {
+	tt := []struct {
+		value  string
+		header string
+		want   string
+	}{
+		{"identity", "gzip, deflate, br", "identity"},
+		{"GZIP", "", "gzip"},
+		{" gzip ,, Deflate", "br", "gzip, deflate"},
+		{"", "gzip", "identity"},
+	}
+
+	for i, tc := range tt {
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		if tc.header != "" {
+			req.Header.Set("Accept-Encoding", tc.header)
+		}
+
+		if err := NewAcceptEncodingModifier(tc.value).ModifyRequest(req); err != nil {
+			t.Fatalf("%d. ModifyRequest(): got %v, want no error", i, err)
+		}
+		if got := req.Header["Accept-Encoding"]; len(got) != 1 || got[0] != tc.want {
+			t.Errorf("%d. req.Header[%q]: got %q, want [%q]", i, "Accept-Encoding", got, tc.want)
+		}
+	}
+}
+
+func TestAcceptEncodingModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"header.AcceptEncodingModifier": {
+			"scope": ["request"],
+			"value": "identity"
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	reqmod := r.RequestModifier()
+	if reqmod == nil {
+		t.Fatal("r.RequestModifier(): got nil, want not nil")
+	}
+	if r.ResponseModifier() != nil {
+		t.Error("r.ResponseModifier(): got not nil, want nil")
+	}
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	if err := reqmod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if got, want := req.Header.Get("Accept-Encoding"), "identity"; got != want {
+		t.Errorf("req.Header.Get(%q): got %q, want %q", "Accept-Encoding", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/filter"
+	"github.com/google/martian/v3/parse"
+)
+
+// ContentTypeMatcher is a conditional evaluator of the Content-Type header of
+// requests and responses.
+type ContentTypeMatcher struct {
+	mediaType string
+	params    map[string]string
+}
+
+// NewContentTypeMatcher builds a new Content-Type matcher. pattern is a media
+// type such as "text/html", or a wildcard such as "text/*" or "*/*". Parameters
+// in the Content-Type header, such as charset, are ignored unless pattern
+// includes them, in which case their values must match.
+func NewContentTypeMatcher(pattern string) *ContentTypeMatcher {
+	mt, params := parseMediaType(pattern)
+	if mt == "*" {
+		mt = "*/*"
+	}
+
+	return &ContentTypeMatcher{
+		mediaType: mt,
+		params:    params,
+	}
+}
+
+// MatchRequest returns whether the Content-Type of req matches the pattern.
+func (m *ContentTypeMatcher) MatchRequest(req *http.Request) bool {
+	return m.match(req.Header.Get("Content-Type"))
+}
+
+// MatchResponse returns whether the Content-Type of res matches the pattern.
+func (m *ContentTypeMatcher) MatchResponse(res *http.Response) bool {
+	return m.match(res.Header.Get("Content-Type"))
+}
+
+func (m *ContentTypeMatcher) match(ct string) bool {
+	if ct == "" {
+		return false
+	}
+
+	mt, params := parseMediaType(ct)
+
+	switch {
+	case m.mediaType == "*/*":
+	case strings.HasSuffix(m.mediaType, "/*"):
+		if !strings.HasPrefix(mt, strings.TrimSuffix(m.mediaType, "*")) {
+			return false
+		}
+	case mt != m.mediaType:
+		return false
+	}
+
+	for name, value := range m.params {
+		if !strings.EqualFold(params[name], value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseMediaType returns the lowercase media type and the parameters of v.
+// Malformed parameters are ignored.
+func parseMediaType(v string) (string, map[string]string) {
+	mt, params, err := mime.ParseMediaType(v)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.SplitN(v, ";", 2)[0]))
+		params = nil
+	}
+
+	return mt, params
+}
+
+// ContentTypeFilter runs its modifiers based on the Content-Type of requests
+// and responses.
+type ContentTypeFilter struct {
+	*filter.Filter
+}
+
+type contentTypeFilterJSON struct {
+	ContentType  string               `json:"contentType"`
+	Modifier     json.RawMessage      `json:"modifier"`
+	ElseModifier json.RawMessage      `json:"else"`
+	Scope        []parse.ModifierType `json:"scope"`
+}
+
+func init() {
+	parse.Register("header.ContentTypeFilter", contentTypeFilterFromJSON)
+}
+
+// NewContentTypeFilter builds a new Content-Type filter that runs resmod on
+// responses whose Content-Type matches pattern. See NewContentTypeMatcher for
+// the pattern syntax.
+func NewContentTypeFilter(pattern string, resmod martian.ResponseModifier) *ContentTypeFilter {
+	m := NewContentTypeMatcher(pattern)
+	f := filter.New()
+	f.SetRequestCondition(m)
+	f.SetResponseCondition(m)
+	f.ResponseWhenTrue(resmod)
+
+	return &ContentTypeFilter{f}
+}
+
+// contentTypeFilterFromJSON builds a header.ContentTypeFilter from JSON.
+//
+// Example JSON:
+// {
+//   "header.ContentTypeFilter": {
+//     "scope": ["response"],
+//     "contentType": "text/*",
+//     "modifier": { ... },
+//     "else": { ... }
+//   }
+// }
+func contentTypeFilterFromJSON(b []byte) (*parse.Result, error) {
+	msg := &contentTypeFilterJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	filter := NewContentTypeFilter(msg.ContentType, nil)
+
+	m, err := parse.FromJSON(msg.Modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.RequestWhenTrue(m.RequestModifier())
+	filter.ResponseWhenTrue(m.ResponseModifier())
+
+	if len(msg.ElseModifier) > 0 {
+		em, err := parse.FromJSON(msg.ElseModifier)
+		if err != nil {
+			return nil, err
+		}
+
+		if em != nil {
+			filter.RequestWhenFalse(em.RequestModifier())
+			filter.ResponseWhenFalse(em.ResponseModifier())
+		}
+	}
+
+	return parse.NewResult(filter, msg.Scope)
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestContentTypeFilter(t *testing.T) {
+	tt := []struct {
+		pattern     string
+		contentType string
+		want        bool
+	}{
+		{"text/html", "text/html", true},
+		{"text/html", "text/html; charset=utf-8", true},
+		{"text/html", "Text/HTML;charset=UTF-8", true},
+		{"text/html", "text/plain", false},
+		{"text/html", "", false},
+		{"text/*", "text/css", true},
+		{"text/*", "application/json", false},
+		{"*/*", "image/png", true},
+		{"*", "image/png", true},
+		{"text/html; charset=utf-8", "text/html; charset=UTF-8", true},
+		{"text/html; charset=utf-8", "text/html; charset=iso-8859-1", false},
+		{"text/html; charset=utf-8", "text/html", false},
+		{"application/json", "application/json; charset", true},
+	}
+
+	for i, tc := range tt {
+		tm := martiantest.NewModifier()
+		f := NewContentTypeFilter(tc.pattern, tm)
+
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("%d. http.NewRequest(): got %v, want no error", i, err)
+		}
+		res := proxyutil.NewResponse(200, nil, req)
+		if tc.contentType != "" {
+			res.Header.Set("Content-Type", tc.contentType)
+		}
+
+		if err := f.ModifyResponse(res); err != nil {
+			t.Fatalf("%d. ModifyResponse(): got %v, want no error", i, err)
+		}
+		if got := tm.ResponseModified(); got != tc.want {
+			t.Errorf("%d. %q matching %q: got %t, want %t", i, tc.pattern, tc.contentType, got, tc.want)
+		}
+	}
+}
+
+func TestContentTypeFilterFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"header.ContentTypeFilter": {
+			"scope": ["response"],
+			"contentType": "text/*",
+			"modifier": {
+				"header.Modifier" : {
+					"scope": ["response"],
+					"name": "Martian-Testing",
+					"value": "true"
+				}
+			},
+			"else": {
+				"header.Modifier" : {
+					"scope": ["response"],
+					"name": "Martian-Testing",
+					"value": "false"
+				}
+			}
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+	resmod := r.ResponseModifier()
+	if resmod == nil {
+		t.Fatal("resmod: got nil, want not nil")
+	}
+
+	tt := []struct {
+		contentType string
+		want        string
+	}{
+		{"text/html; charset=utf-8", "true"},
+		{"application/json", "false"},
+	}
+
+	for i, tc := range tt {
+		res := proxyutil.NewResponse(200, nil, nil)
+		res.Header.Set("Content-Type", tc.contentType)
+
+		if err := resmod.ModifyResponse(res); err != nil {
+			t.Fatalf("%d. ModifyResponse(): got %v, want no error", i, err)
+		}
+		if got := res.Header.Get("Martian-Testing"); got != tc.want {
+			t.Errorf("%d. res.Header.Get(%q): got %q, want %q", i, "Martian-Testing", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("header.DuplicateModifier", duplicateModifierFromJSON)
+}
+
+// DuplicatePolicy selects what DuplicateModifier does with a single-valued
+// header that appears more than once with different values.
+type DuplicatePolicy int
+
+const (
+	// KeepFirst keeps the first value of the header.
+	KeepFirst DuplicatePolicy = iota
+	// KeepLast keeps the last value of the header.
+	KeepLast
+	// RejectDuplicates leaves the headers unchanged and returns an error.
+	RejectDuplicates
+)
+
+// singleValuedHeaders are the response headers that DuplicateModifier
+// collapses by default. Each allows a single value, so duplicates cannot be
+// combined into a list the way Cache-Control or Vary can; Set-Cookie is
+// multi-valued but cannot be combined either, and is never collapsed.
+var singleValuedHeaders = []string{
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Origin",
+	"Access-Control-Max-Age",
+	"Age",
+	"Content-Disposition",
+	"Content-Length",
+	"Content-Location",
+	"Content-Range",
+	"Content-Type",
+	"Date",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Location",
+	"Referrer-Policy",
+	"Retry-After",
+	"Server",
+	"Strict-Transport-Security",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+}
+
+// DuplicateModifier is a response modifier that collapses duplicate
+// single-valued headers.
+type DuplicateModifier struct {
+	policy  DuplicatePolicy
+	headers []string
+}
+
+type duplicateModifierJSON struct {
+	Policy  string               `json:"policy"`
+	Headers []string             `json:"headers"`
+	Scope   []parse.ModifierType `json:"scope"`
+}
+
+// NewDuplicateModifier returns a response modifier that collapses each
+// single-valued header that appears more than once, such as two Content-Type
+// headers, into one value chosen by policy. Duplicates with identical values
+// are collapsed under any policy. Multi-valued headers, like Set-Cookie, are
+// left alone. Add it after the other response modifiers so that it sees the
+// headers as they are written.
+func NewDuplicateModifier(policy DuplicatePolicy) *DuplicateModifier {
+	return &DuplicateModifier{
+		policy:  policy,
+		headers: singleValuedHeaders,
+	}
+}
+
+// SetHeaders sets the names of the headers that are collapsed, in place of the
+// default list of single-valued headers.
+func (m *DuplicateModifier) SetHeaders(names []string) {
+	m.headers = make([]string, len(names))
+	for i, name := range names {
+		m.headers[i] = http.CanonicalHeaderKey(name)
+	}
+}
+
+// ModifyResponse collapses the duplicate headers of res.
+func (m *DuplicateModifier) ModifyResponse(res *http.Response) error {
+	if m.policy == RejectDuplicates {
+		for _, name := range m.headers {
+			if vs := res.Header[name]; len(vs) > 1 && !allEqual(vs) {
+				return fmt.Errorf("header.DuplicateModifier: response has %d different %s headers: %q", len(vs), name, vs)
+			}
+		}
+	}
+
+	for _, name := range m.headers {
+		vs := res.Header[name]
+		if len(vs) < 2 {
+			continue
+		}
+
+		v := vs[0]
+		if m.policy == KeepLast {
+			v = vs[len(vs)-1]
+		}
+		res.Header[name] = []string{v}
+	}
+
+	return nil
+}
+
+// allEqual returns whether all of vs are the same.
+func allEqual(vs []string) bool {
+	for _, v := range vs[1:] {
+		if v != vs[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// duplicateModifierFromJSON builds a header.DuplicateModifier from JSON. The
+// policy is "first", the default, "last" or "error".
+//
+// Example JSON:
+// {
+//   "header.DuplicateModifier": {
+//     "scope": ["response"],
+//     "policy": "last",
+//     "headers": ["Content-Type", "Location"]
+//   }
+// }
+func duplicateModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &duplicateModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	var policy DuplicatePolicy
+	switch msg.Policy {
+	case "", "first":
+		policy = KeepFirst
+	case "last":
+		policy = KeepLast
+	case "error":
+		policy = RejectDuplicates
+	default:
+		return nil, fmt.Errorf("header.DuplicateModifier: unknown policy %q, want first, last or error", msg.Policy)
+	}
+
+	mod := NewDuplicateModifier(policy)
+	if len(msg.Headers) > 0 {
+		mod.SetHeaders(msg.Headers)
+	}
+
+	return parse.NewResult(mod, msg.Scope)
+}
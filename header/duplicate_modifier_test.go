@@ -0,0 +1,122 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func duplicateResponse() *http.Response {
+	res := proxyutil.NewResponse(200, nil, nil)
+	res.Header["Content-Type"] = []string{"text/html", "application/json"}
+	res.Header["Location"] = []string{"/a", "/a"}
+	res.Header["Set-Cookie"] = []string{"a=1", "b=2"}
+	res.Header["Vary"] = []string{"Accept", "Origin"}
+
+	return res
+}
+
+func TestDuplicateModifier(t *testing.T) {
+	tt := []struct {
+		policy          DuplicatePolicy
+		wantContentType []string
+	}{
+		{KeepFirst, []string{"text/html"}},
+		{KeepLast, []string{"application/json"}},
+	}
+
+	for _, tc := range tt {
+		res := duplicateResponse()
+
+		if err := NewDuplicateModifier(tc.policy).ModifyResponse(res); err != nil {
+			t.Fatalf("%d: ModifyResponse(): got %v, want no error", tc.policy, err)
+		}
+
+		for _, h := range []struct {
+			name string
+			want []string
+		}{
+			{"Content-Type", tc.wantContentType},
+			{"Location", []string{"/a"}},
+			{"Set-Cookie", []string{"a=1", "b=2"}},
+			{"Vary", []string{"Accept", "Origin"}},
+		} {
+			if got := res.Header[h.name]; !reflect.DeepEqual(got, h.want) {
+				t.Errorf("%d: res.Header[%q]: got %q, want %q", tc.policy, h.name, got, h.want)
+			}
+		}
+	}
+}
+
+func TestDuplicateModifierReject(t *testing.T) {
+	res := duplicateResponse()
+
+	if err := NewDuplicateModifier(RejectDuplicates).ModifyResponse(res); err == nil {
+		t.Fatal("ModifyResponse(): got no error, want error for different Content-Type headers")
+	}
+	if got, want := len(res.Header["Content-Type"]), 2; got != want {
+		t.Errorf("len(res.Header[%q]): got %d, want %d", "Content-Type", got, want)
+	}
+
+	// Identical duplicates are not an error.
+	res.Header.Set("Content-Type", "text/html")
+	if err := NewDuplicateModifier(RejectDuplicates).ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.Header["Location"], []string{"/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("res.Header[%q]: got %q, want %q", "Location", got, want)
+	}
+}
+
+func TestDuplicateModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"header.DuplicateModifier": {
+			"scope": ["response"],
+			"policy": "last",
+			"headers": ["vary"]
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	res := duplicateResponse()
+	if err := r.ResponseModifier().ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.Header["Vary"], []string{"Origin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("res.Header[%q]: got %q, want %q", "Vary", got, want)
+	}
+	if got, want := len(res.Header["Content-Type"]), 2; got != want {
+		t.Errorf("len(res.Header[%q]): got %d, want %d", "Content-Type", got, want)
+	}
+
+	msg = []byte(`{
+		"header.DuplicateModifier": {
+			"scope": ["response"],
+			"policy": "random"
+		}
+	}`)
+	if _, err := parse.FromJSON(msg); err == nil {
+		t.Error("parse.FromJSON(): got no error, want error for unknown policy")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("header.TrailerModifier", trailerModifierFromJSON)
+}
+
+// TrailerModifier sets a trailer field on responses.
+type TrailerModifier struct {
+	name, value string
+}
+
+type trailerModifierJSON struct {
+	Name  string               `json:"name"`
+	Value string               `json:"value"`
+	Scope []parse.ModifierType `json:"scope"`
+}
+
+// NewTrailerModifier returns a response modifier that sends the trailer field
+// name with value after the body of responses.
+//
+// Trailers can only be sent after a chunked body, so responses with a body are
+// re-framed with chunked transfer encoding, dropping their Content-Length, and
+// the trailer is declared in the Trailer header. Responses without a body, such
+// as those to HEAD requests, and responses to HTTP/1.0 requests, which do not
+// support chunked encoding, are left unmodified. The value is set once the
+// body has been read, replacing any trailer of the same name from the origin.
+func NewTrailerModifier(name, value string) *TrailerModifier {
+	return &TrailerModifier{
+		name:  http.CanonicalHeaderKey(name),
+		value: value,
+	}
+}
+
+// ModifyResponse declares the trailer on res and sets it once the body has
+// been read. It returns an error for fields that are not allowed in trailers.
+func (m *TrailerModifier) ModifyResponse(res *http.Response) error {
+	switch m.name {
+	case "", "Content-Length", "Transfer-Encoding", "Trailer":
+		return fmt.Errorf("header.TrailerModifier: field %q is not allowed in trailers", m.name)
+	}
+
+	if !hasBody(res) {
+		return nil
+	}
+	if res.Request != nil && !res.Request.ProtoAtLeast(1, 1) {
+		return nil
+	}
+
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	res.TransferEncoding = []string{"chunked"}
+	if res.Trailer == nil {
+		res.Trailer = http.Header{}
+	}
+	res.Trailer[m.name] = nil
+
+	res.Body = &trailerBody{
+		ReadCloser: res.Body,
+		res:        res,
+		name:       m.name,
+		value:      m.value,
+	}
+
+	return nil
+}
+
+// hasBody returns whether res may have a body.
+func hasBody(res *http.Response) bool {
+	switch {
+	case res.Body == nil, res.Body == http.NoBody:
+		return false
+	case res.StatusCode/100 == 1, res.StatusCode == 204, res.StatusCode == 304:
+		return false
+	case res.Request != nil && res.Request.Method == "HEAD":
+		return false
+	}
+
+	return true
+}
+
+// trailerBody sets a trailer of its response once it has been read.
+type trailerBody struct {
+	io.ReadCloser
+	res         *http.Response
+	name, value string
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.res.Trailer.Set(b.name, b.value)
+	}
+
+	return n, err
+}
+
+// trailerModifierFromJSON builds a header.TrailerModifier from JSON.
+//
+// Example JSON:
+// {
+//   "header.TrailerModifier": {
+//     "scope": ["response"],
+//     "name": "Grpc-Status",
+//     "value": "0"
+//   }
+// }
+func trailerModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &trailerModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return parse.NewResult(NewTrailerModifier(msg.Name, msg.Value), msg.Scope)
+}
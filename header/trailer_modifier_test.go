@@ -0,0 +1,139 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestTrailerModifier(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	res := proxyutil.NewResponse(200, strings.NewReader("hello world"), req)
+	res.ContentLength = 11
+	res.Header.Set("Content-Length", "11")
+
+	mod := NewTrailerModifier("grpc-status", "0")
+	if err := mod.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := res.Write(buf); err != nil {
+		t.Fatalf("res.Write(): got %v, want no error", err)
+	}
+
+	got, err := http.ReadResponse(bufio.NewReader(buf), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	if got, want := got.TransferEncoding, []string{"chunked"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("res.TransferEncoding: got %v, want %v", got, want)
+	}
+	if _, ok := got.Trailer["Grpc-Status"]; !ok {
+		t.Errorf("res.Trailer: got %v, want %q declared", got.Trailer, "Grpc-Status")
+	}
+	if v := got.Trailer.Get("Grpc-Status"); v != "" {
+		t.Errorf("res.Trailer.Get(%q): got %q before body was read, want empty", "Grpc-Status", v)
+	}
+
+	body, err := ioutil.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if want := "hello world"; string(body) != want {
+		t.Errorf("res.Body: got %q, want %q", body, want)
+	}
+	if got, want := got.Trailer.Get("Grpc-Status"), "0"; got != want {
+		t.Errorf("res.Trailer.Get(%q): got %q, want %q", "Grpc-Status", got, want)
+	}
+}
+
+func TestTrailerModifierSkipped(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		major  int
+		minor  int
+		status int
+	}{
+		{"HEAD", 1, 1, 200},
+		{"GET", 1, 0, 200},
+		{"GET", 1, 1, 204},
+	} {
+		req, err := http.NewRequest(tc.method, "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		req.ProtoMajor, req.ProtoMinor = tc.major, tc.minor
+
+		res := proxyutil.NewResponse(tc.status, strings.NewReader("body"), req)
+		res.ContentLength = 4
+
+		if err := NewTrailerModifier("Grpc-Status", "0").ModifyResponse(res); err != nil {
+			t.Fatalf("ModifyResponse(): got %v, want no error", err)
+		}
+		if res.Trailer != nil {
+			t.Errorf("%s HTTP/%d.%d %d: res.Trailer: got %v, want nil", tc.method, tc.major, tc.minor, tc.status, res.Trailer)
+		}
+		if got, want := res.ContentLength, int64(4); got != want {
+			t.Errorf("%s HTTP/%d.%d %d: res.ContentLength: got %d, want %d", tc.method, tc.major, tc.minor, tc.status, got, want)
+		}
+	}
+}
+
+func TestTrailerModifierInvalidName(t *testing.T) {
+	res := proxyutil.NewResponse(200, strings.NewReader("body"), nil)
+
+	if err := NewTrailerModifier("Content-Length", "4").ModifyResponse(res); err == nil {
+		t.Error("ModifyResponse(): got no error, want error")
+	}
+}
+
+func TestTrailerModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"header.TrailerModifier": {
+			"scope": ["response"],
+			"name": "Grpc-Status",
+			"value": "0"
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	mod, ok := r.ResponseModifier().(*TrailerModifier)
+	if !ok {
+		t.Fatal("r.ResponseModifier(): got not *TrailerModifier, want *TrailerModifier")
+	}
+	if got, want := mod.name, "Grpc-Status"; got != want {
+		t.Errorf("mod.name: got %q, want %q", got, want)
+	}
+	if got, want := mod.value, "0"; got != want {
+		t.Errorf("mod.value: got %q, want %q", got, want)
+	}
+}
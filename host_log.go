@@ -0,0 +1,46 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"net"
+	"strings"
+
+	"github.com/google/martian/v3/log"
+)
+
+// SetHostLogLevel sets the log level, such as log.Debug or log.Silent, of the
+// messages logged while handling requests for host, overriding the global
+// level set with log.SetLevel. It raises verbosity for a host being
+// investigated without flooding the logs with messages about other hosts.
+// host is matched against the Host of requests, without the port; messages
+// logged on a connection before its request is read use the host of the
+// previous request, such as the CONNECT request of a MITMed connection.
+//
+// Like the global level, host levels are process-wide and apply to all
+// proxies; they can be removed with log.ClearHostLevel.
+func SetHostLogLevel(host string, level int) {
+	log.SetHostLevel(host, level)
+}
+
+// hostname returns host without its port, if any, or the brackets of an IPv6
+// address.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"bytes"
+	stdlog "log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/martiantest"
+)
+
+func TestHostname(t *testing.T) {
+	tt := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"example.com:8080", "example.com"},
+		{"[::1]:443", "::1"},
+		{"[::1]", "::1"},
+		{"192.0.2.1:80", "192.0.2.1"},
+	}
+
+	for _, tc := range tt {
+		if got := hostname(tc.host); got != tc.want {
+			t.Errorf("hostname(%q): got %q, want %q", tc.host, got, tc.want)
+		}
+	}
+}
+
+func TestSetHostLogLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	stdlog.SetOutput(buf)
+	defer stdlog.SetOutput(os.Stderr)
+
+	SetHostLogLevel("debug.example.com", log.Debug)
+	defer log.ClearHostLevel("debug.example.com")
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	tr := martiantest.NewTransport()
+	p.SetRoundTripper(tr)
+	p.SetTimeout(2 * time.Second)
+
+	go p.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	for _, host := range []string{"debug.example.com", "other.example.com"} {
+		req, err := http.NewRequest("GET", "http://"+host+":8080", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		if err := req.WriteProxy(conn); err != nil {
+			t.Fatalf("req.WriteProxy(): got %v, want no error", err)
+		}
+		res, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+		}
+		res.Body.Close()
+	}
+	conn.Close()
+	// Let the proxy log the end of the connection.
+	time.Sleep(100 * time.Millisecond)
+	stdlog.SetOutput(os.Stderr)
+
+	// Waiting for a request is logged for the host of the previous request on
+	// the connection: only the wait that follows the request to
+	// debug.example.com is logged.
+	got := buf.String()
+	if n := strings.Count(got, "DEBUG: martian: waiting for request"); n != 1 {
+		t.Errorf("log: got %q, want a single message about waiting for a request", got)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/martian/v3/log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP2Headers describes a header block observed on a MITMed HTTP/2
+// connection.
+type HTTP2Headers struct {
+	// Host is the host of the CONNECT request for the connection.
+	Host string
+	// StreamID is the ID of the stream the headers were sent on.
+	StreamID uint32
+	// FromClient is set for headers sent by the client, that is request
+	// headers and request trailers, and unset for headers sent by the origin.
+	FromClient bool
+	// EndStream is set if the headers end the stream, as trailers do.
+	EndStream bool
+	// Pseudo holds the pseudo-header fields by name, such as ":method" and
+	// ":path" for requests and ":status" for responses. For gRPC, ":path" holds
+	// the method name.
+	Pseudo map[string]string
+	// Header holds the regular header fields.
+	Header http.Header
+}
+
+// SetHTTP2Observer sets a function that is called with each header block of
+// the HTTP/2 connections relayed through MITMed tunnels, such as those of gRPC
+// clients.
+//
+// While an observer is set, clients that offer HTTP/2 in the MITM TLS
+// handshake are offered it in return if the origin negotiates HTTP/2 with the
+// proxy; otherwise, they are MITM'd over HTTP/1.1. When a client selects
+// HTTP/2, the proxy relays frames between it and the origin unmodified:
+// request and response modifiers are not run for the streams of the
+// connection, which is copied as a CONNECT tunnel and subject to the same
+// limits. The observer is
+// called from the goroutines relaying each direction of the connection and
+// must not block.
+func (p *Proxy) SetHTTP2Observer(observer func(*HTTP2Headers)) {
+	p.h2observer = observer
+}
+
+// dialHTTP2 connects to the origin of the CONNECT request req over TLS,
+// offering HTTP/2 with ALPN. It returns nil if the connection fails or the
+// origin does not select HTTP/2, in which case the client is not offered
+// HTTP/2 either.
+func (p *Proxy) dialHTTP2(req *http.Request, serverName string, skipVerify bool) *tls.Conn {
+	host := hostname(req.Host)
+
+	res, oconn, err := p.connect(req)
+	if err != nil {
+		log.HostErrorf(host, "martian: failed to connect to HTTP/2 origin %s: %v", req.Host, err)
+		return nil
+	}
+	if oconn == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		log.HostErrorf(host, "martian: failed to connect to HTTP/2 origin %s: %s", req.Host, res.Status)
+		return nil
+	}
+
+	if serverName == "" {
+		serverName = req.URL.Hostname()
+	}
+	tconn := tls.Client(oconn, &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         []string{http2.NextProtoTLS, "http/1.1"},
+		InsecureSkipVerify: skipVerify,
+		VerifyConnection:   p.verifyHostPins(serverName),
+	})
+	if err := tconn.Handshake(); err != nil {
+		log.HostErrorf(host, "martian: failed TLS handshake with HTTP/2 origin %s: %v", req.Host, err)
+		oconn.Close()
+		return nil
+	}
+
+	if proto := tconn.ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+		log.HostDebugf(host, "martian: origin %s negotiated %q, falling back to HTTP/1.1 MITM", req.Host, proto)
+		tconn.Close()
+		return nil
+	}
+
+	return tconn
+}
+
+// relayHTTP2 relays the HTTP/2 connection of the client over conn, whose TLS
+// handshake has completed, to the origin of the CONNECT request req over
+// tconn, reporting header blocks to the HTTP/2 observer. The connections are
+// copied as a CONNECT tunnel, so the tunnel limits of the proxy apply.
+func (p *Proxy) relayHTTP2(session *Session, req *http.Request, conn, tconn *tls.Conn) error {
+	host := hostname(req.Host)
+
+	defer conn.Close()
+	defer tconn.Close()
+
+	// The connection is long-lived; the request timeout no longer applies.
+	conn.SetDeadline(time.Time{})
+
+	cpr, cpw := io.Pipe()
+	go p.observeHTTP2(cpr, req.Host, true)
+	defer cpw.Close()
+
+	opr, opw := io.Pipe()
+	go p.observeHTTP2(opr, req.Host, false)
+	defer opw.Close()
+
+	log.HostDebugf(host, "martian: relaying HTTP/2 connection to %s", req.Host)
+	p.copyTunnel(session, host, req,
+		&observedConn{Conn: conn, w: cpw, peer: tconn},
+		&observedConn{Conn: tconn, w: opw, peer: conn})
+	log.HostDebugf(host, "martian: closed HTTP/2 connection to %s", req.Host)
+
+	return errClose
+}
+
+// observedConn writes the data read from its connection to w, to be observed,
+// and closes peer once reading fails, since neither side of a relayed HTTP/2
+// connection outlives the other.
+type observedConn struct {
+	net.Conn
+	w    *io.PipeWriter
+	peer net.Conn
+}
+
+func (c *observedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.w.Write(b[:n])
+	}
+	if err != nil {
+		c.w.Close()
+		c.peer.Close()
+	}
+
+	return n, err
+}
+
+// observeHTTP2 reads HTTP/2 frames sent by the client or the origin from r and
+// reports their header blocks to the HTTP/2 observer. If the frames cannot be
+// parsed, the rest of r is discarded so that the relay is not blocked.
+func (p *Proxy) observeHTTP2(r io.Reader, host string, client bool) {
+	defer io.Copy(ioutil.Discard, r)
+
+	if client {
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(r, preface); err != nil {
+			return
+		}
+		if !bytes.Equal(preface, []byte(http2.ClientPreface)) {
+			log.HostErrorf(hostname(host), "martian: invalid HTTP/2 client preface from %s connection", host)
+			return
+		}
+	}
+
+	dec := hpack.NewDecoder(4096, nil)
+	// The peer may have allowed the sender a larger dynamic table than the
+	// default; only the sender's table size updates matter to the observer.
+	dec.SetAllowedMaxDynamicTableSize(1 << 24)
+
+	fr := http2.NewFramer(nil, r)
+	fr.ReadMetaHeaders = dec
+	fr.SetMaxReadFrameSize(1<<24 - 1)
+
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			if err != io.EOF && err != io.ErrClosedPipe {
+				log.HostDebugf(hostname(host), "martian: stopped observing HTTP/2 connection to %s: %v", host, err)
+			}
+			return
+		}
+
+		mh, ok := f.(*http2.MetaHeadersFrame)
+		if !ok {
+			continue
+		}
+
+		h := &HTTP2Headers{
+			Host:       host,
+			StreamID:   mh.StreamID,
+			FromClient: client,
+			EndStream:  mh.StreamEnded(),
+			Pseudo:     make(map[string]string),
+			Header:     make(http.Header),
+		}
+		for _, hf := range mh.Fields {
+			if hf.IsPseudo() {
+				h.Pseudo[hf.Name] = hf.Value
+				continue
+			}
+			h.Header.Add(hf.Name, hf.Value)
+		}
+
+		p.h2observer(h)
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/mitm"
+	"golang.org/x/net/http2"
+)
+
+func TestIntegrationHTTP2Observer(t *testing.T) {
+	t.Parallel()
+
+	// The origin serves HTTP/2 over TLS with a certificate from its own
+	// authority; the proxy does not verify it.
+	oca, opriv, err := mitm.NewAuthority("origin", "Origin Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	omc, err := mitm.NewConfig(oca, opriv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	ocfg := omc.TLSForHost("example.com")
+	ocfg.NextProtos = []string{http2.NextProtoTLS}
+
+	ol, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer ol.Close()
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "application/grpc")
+			fmt.Fprintf(rw, "%s %s", req.Proto, req.URL.Path)
+		}),
+	}
+	if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+		t.Fatalf("http2.ConfigureServer(): got %v, want no error", err)
+	}
+	go srv.Serve(tls.NewListener(ol, ocfg))
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	p.SetTimeout(5 * time.Second)
+
+	ca, priv, err := mitm.NewAuthority("martian.proxy", "Martian Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	mc, err := mitm.NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	mc.SkipTLSVerify(true)
+	p.SetMITM(mc)
+
+	hc := make(chan *HTTP2Headers, 10)
+	p.SetHTTP2Observer(func(h *HTTP2Headers) {
+		hc <- h
+	})
+
+	go p.Serve(l)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	tr := &http2.Transport{
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				return nil, err
+			}
+
+			req, err := http.NewRequest("CONNECT", "//"+ol.Addr().String(), nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := req.Write(conn); err != nil {
+				return nil, err
+			}
+			res, err := http.ReadResponse(bufio.NewReader(conn), req)
+			if err != nil {
+				return nil, err
+			}
+			if res.StatusCode != 200 {
+				return nil, fmt.Errorf("CONNECT: got status %d, want 200", res.StatusCode)
+			}
+
+			tlsconn := tls.Client(conn, &tls.Config{
+				ServerName: "example.com",
+				RootCAs:    roots,
+				NextProtos: []string{http2.NextProtoTLS},
+			})
+			if err := tlsconn.Handshake(); err != nil {
+				return nil, err
+			}
+
+			return tlsconn, nil
+		},
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequest("POST", "https://example.com/helloworld.Greeter/SayHello", strings.NewReader("message"))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("tr.RoundTrip(): got %v, want no error", err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+
+	if got, want := string(body), "HTTP/2.0 /helloworld.Greeter/SayHello"; got != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+
+	var reqh, resh *HTTP2Headers
+	for reqh == nil || resh == nil {
+		select {
+		case h := <-hc:
+			if h.FromClient {
+				reqh = h
+			} else {
+				resh = h
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("HTTP/2 observer: timed out waiting for headers")
+		}
+	}
+
+	if got, want := reqh.Pseudo[":path"], "/helloworld.Greeter/SayHello"; got != want {
+		t.Errorf("request :path: got %q, want %q", got, want)
+	}
+	if got, want := reqh.Pseudo[":method"], "POST"; got != want {
+		t.Errorf("request :method: got %q, want %q", got, want)
+	}
+	if got, want := reqh.Header.Get("Content-Type"), "application/grpc"; got != want {
+		t.Errorf("request Content-Type: got %q, want %q", got, want)
+	}
+	if got, want := reqh.Host, ol.Addr().String(); got != want {
+		t.Errorf("request Host: got %q, want %q", got, want)
+	}
+	if got, want := resh.Pseudo[":status"], "200"; got != want {
+		t.Errorf("response :status: got %q, want %q", got, want)
+	}
+	if resh.StreamID != reqh.StreamID {
+		t.Errorf("response StreamID: got %d, want %d", resh.StreamID, reqh.StreamID)
+	}
+}
+
+// startHTTP2Proxy starts an origin serving TLS with the protocols in protos,
+// and a MITM proxy with an HTTP/2 observer in front of it. It returns the
+// proxy, its listener, the origin listener and the roots that verify the MITM
+// certificates.
+func startHTTP2Proxy(t *testing.T, protos []string) (*Proxy, net.Listener, net.Listener, *x509.CertPool) {
+	t.Helper()
+
+	oca, opriv, err := mitm.NewAuthority("origin", "Origin Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	omc, err := mitm.NewConfig(oca, opriv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	ocfg := omc.TLSForHost("example.com")
+	ocfg.NextProtos = protos
+
+	ol, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(rw, "%s %s", req.Proto, req.URL.Path)
+		}),
+	}
+	if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+		t.Fatalf("http2.ConfigureServer(): got %v, want no error", err)
+	}
+	go srv.Serve(tls.NewListener(ol, ocfg))
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	p.SetTimeout(5 * time.Second)
+
+	ca, priv, err := mitm.NewAuthority("martian.proxy", "Martian Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	mc, err := mitm.NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+	mc.SkipTLSVerify(true)
+	p.SetMITM(mc)
+	p.SetHTTP2Observer(func(*HTTP2Headers) {})
+
+	go p.Serve(l)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	return p, l, ol, roots
+}
+
+// dialHTTP2Tunnel opens a CONNECT tunnel to addr through the proxy listening
+// on l and completes a TLS handshake over it, offering HTTP/2 and HTTP/1.1.
+func dialHTTP2Tunnel(t *testing.T, l net.Listener, addr string, roots *x509.CertPool) *tls.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+
+	req, err := http.NewRequest("CONNECT", "//"+addr, nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("req.Write(): got %v, want no error", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, 200; got != want {
+		t.Fatalf("res.StatusCode: got %d, want %d", got, want)
+	}
+
+	tlsconn := tls.Client(conn, &tls.Config{
+		ServerName: "example.com",
+		RootCAs:    roots,
+		NextProtos: []string{http2.NextProtoTLS, "http/1.1"},
+	})
+	if err := tlsconn.Handshake(); err != nil {
+		t.Fatalf("tlsconn.Handshake(): got %v, want no error", err)
+	}
+
+	return tlsconn
+}
+
+func TestIntegrationHTTP2ObserverHTTP1Origin(t *testing.T) {
+	t.Parallel()
+
+	p, l, ol, roots := startHTTP2Proxy(t, []string{"http/1.1"})
+	defer p.Close()
+	defer ol.Close()
+
+	p.SetRoundTripper(&http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	})
+
+	tlsconn := dialHTTP2Tunnel(t, l, ol.Addr().String(), roots)
+	defer tlsconn.Close()
+
+	// The origin does not speak HTTP/2, so neither does the proxy.
+	if got, want := tlsconn.ConnectionState().NegotiatedProtocol, "http/1.1"; got != want {
+		t.Fatalf("NegotiatedProtocol: got %q, want %q", got, want)
+	}
+
+	req, err := http.NewRequest("GET", "https://"+ol.Addr().String()+"/path", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.Write(tlsconn); err != nil {
+		t.Fatalf("req.Write(): got %v, want no error", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(tlsconn), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+
+	if got, want := string(body), "HTTP/1.1 /path"; got != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+}
+
+func TestIntegrationHTTP2ObserverMaxTunnelDuration(t *testing.T) {
+	t.Parallel()
+
+	p, l, ol, roots := startHTTP2Proxy(t, []string{http2.NextProtoTLS})
+	defer p.Close()
+	defer ol.Close()
+
+	p.SetMaxTunnelDuration(100 * time.Millisecond)
+
+	tlsconn := dialHTTP2Tunnel(t, l, ol.Addr().String(), roots)
+	defer tlsconn.Close()
+
+	if got, want := tlsconn.ConnectionState().NegotiatedProtocol, http2.NextProtoTLS; got != want {
+		t.Fatalf("NegotiatedProtocol: got %q, want %q", got, want)
+	}
+
+	// The relay is closed once the tunnel exceeds its maximum duration, even
+	// though the origin keeps the HTTP/2 connection open.
+	tlsconn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.Copy(ioutil.Discard, tlsconn); err != nil {
+		t.Fatalf("io.Copy(): got %v, want connection closed by the proxy", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTimer(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	timer := f.NewTimer(time.Minute)
+	stopped := f.NewTimer(time.Minute)
+	if !stopped.Stop() {
+		t.Error("stopped.Stop(): got false, want true")
+	}
+
+	f.Advance(59 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("timer.C(): fired before deadline")
+	default:
+	}
+
+	f.Advance(time.Second)
+	select {
+	case got := <-timer.C():
+		if want := start.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("timer.C(): got %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("timer.C(): did not fire at deadline")
+	}
+
+	select {
+	case <-stopped.C():
+		t.Error("stopped.C(): fired after Stop")
+	default:
+	}
+
+	if timer.Stop() {
+		t.Error("timer.Stop(): got true after timer fired, want false")
+	}
+	if got, want := f.Now(), start.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("f.Now(): got %v, want %v", got, want)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(time.Now())
+
+	ticker := f.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 0; i < 3; i++ {
+		f.Advance(time.Second)
+		select {
+		case <-ticker.C():
+		default:
+			t.Fatalf("%d: ticker.C(): did not tick", i)
+		}
+	}
+
+	// Ticks that are not received are dropped.
+	f.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("ticker.C(): got second tick, want dropped")
+	default:
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		f.Sleep(time.Hour)
+		close(done)
+	}()
+
+	for f.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("f.Sleep(): returned before clock advanced")
+	default:
+	}
+
+	f.Advance(time.Hour)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("f.Sleep(): did not return after clock advanced")
+	}
+}
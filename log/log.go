@@ -18,6 +18,7 @@ package log
 import (
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 )
 
@@ -96,3 +97,66 @@ func Errorf(format string, args ...interface{}) {
 
 	log.Println(msg)
 }
+
+// hostLevels holds the log levels set for hosts with SetHostLevel.
+var hostLevels map[string]int
+
+// SetHostLevel sets the log level of the messages logged about host with
+// HostInfof, HostDebugf and HostErrorf, overriding the global level; for
+// example, to log debug messages only for a host being investigated. host is
+// a hostname or IP address without a port, and is matched case-insensitively.
+func SetHostLevel(host string, l int) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if hostLevels == nil {
+		hostLevels = make(map[string]int)
+	}
+	hostLevels[strings.ToLower(host)] = l
+}
+
+// ClearHostLevel removes the log level set for host with SetHostLevel, so that
+// messages about host are logged at the global level.
+func ClearHostLevel(host string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	delete(hostLevels, strings.ToLower(host))
+}
+
+// HostInfof logs an info message about host.
+func HostInfof(host, format string, args ...interface{}) {
+	logHost(host, Info, "INFO", format, args...)
+}
+
+// HostDebugf logs a debug message about host.
+func HostDebugf(host, format string, args ...interface{}) {
+	logHost(host, Debug, "DEBUG", format, args...)
+}
+
+// HostErrorf logs an error message about host.
+func HostErrorf(host, format string, args ...interface{}) {
+	logHost(host, Error, "ERROR", format, args...)
+}
+
+// logHost logs a message at level l about host, if the level of host allows
+// it.
+func logHost(host string, l int, prefix, format string, args ...interface{}) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	hl, ok := hostLevels[strings.ToLower(host)]
+	if !ok {
+		hl = level
+	}
+	if hl < l {
+		return
+	}
+
+	msg := fmt.Sprintf("%s: %s", prefix, format)
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+
+	log.Println(msg)
+}
@@ -48,3 +48,42 @@ func TestLog(t *testing.T) {
 		t.Errorf("Errorf(): got %q, want to contain %q", got, want)
 	}
 }
+
+func TestHostLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	stdlog.SetOutput(buf)
+	defer stdlog.SetOutput(os.Stdout)
+
+	defer func(l int) { level = l }(level)
+	level = Error
+
+	SetHostLevel("Debug.example.com", Debug)
+	defer ClearHostLevel("debug.example.com")
+	SetHostLevel("silent.example.com", Silent)
+	defer ClearHostLevel("silent.example.com")
+
+	HostDebugf("debug.example.com", "log: %s test", "debug")
+	if got, want := buf.String(), "DEBUG: log: debug test\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("HostDebugf(): got %q, want to contain %q", got, want)
+	}
+
+	buf.Reset()
+	HostDebugf("other.example.com", "log: %s test", "debug")
+	HostErrorf("silent.example.com", "log: %s test", "error")
+	if got := buf.String(); got != "" {
+		t.Errorf("HostDebugf(), HostErrorf(): got %q, want nothing logged", got)
+	}
+
+	HostErrorf("other.example.com", "log: %s test", "error")
+	if got, want := buf.String(), "ERROR: log: error test\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("HostErrorf(): got %q, want to contain %q", got, want)
+	}
+
+	ClearHostLevel("debug.example.com")
+	buf.Reset()
+	HostInfof("debug.example.com", "log: %s test", "info")
+	if got := buf.String(); got != "" {
+		t.Errorf("HostInfof() after ClearHostLevel(): got %q, want nothing logged", got)
+	}
+}
@@ -16,7 +16,12 @@
 // request and response modifiers.
 package martian
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
 
 // RequestModifier is an interface that defines a request modifier that can be
 // used by a proxy.
@@ -39,6 +44,54 @@ type RequestResponseModifier interface {
 	ResponseModifier
 }
 
+// ContextAwareModifier is an optional interface implemented by request
+// modifiers that perform long-running work and need to observe cancellation.
+// When a request modifier implements it, the proxy calls ModifyRequestContext
+// in place of ModifyRequest, passing the context given to Proxy.ServeContext
+// (or Proxy.HandleConn), which is done when the proxy is shutting down.
+//
+// Modifiers that only implement RequestModifier can observe the same
+// cancellation by selecting on req.Context().Done(); the context of a request
+// read by the proxy is derived from the server context.
+type ContextAwareModifier interface {
+	// ModifyRequestContext modifies the request, aborting if ctx is done.
+	ModifyRequestContext(ctx context.Context, req *http.Request) error
+}
+
+// ModifyRequestContext modifies req with reqmod, calling ModifyRequestContext
+// with ctx if reqmod is a ContextAwareModifier and ModifyRequest otherwise.
+// Modifiers that contain other modifiers, such as groups, use it to pass ctx
+// on to their children.
+func ModifyRequestContext(ctx context.Context, reqmod RequestModifier, req *http.Request) error {
+	if cmod, ok := reqmod.(ContextAwareModifier); ok {
+		return cmod.ModifyRequestContext(ctx, req)
+	}
+
+	return reqmod.ModifyRequest(req)
+}
+
+// Named is an optional interface implemented by modifiers that report a name
+// to identify them when their invocations are instrumented.
+type Named interface {
+	// ModifierName returns the name of the modifier.
+	ModifierName() string
+}
+
+// ModifierName returns the name of mod if it implements Named, or its type
+// otherwise.
+func ModifierName(mod interface{}) string {
+	if n, ok := mod.(Named); ok {
+		return n.ModifierName()
+	}
+
+	return fmt.Sprintf("%T", mod)
+}
+
+// ModifierHook is called after each instrumented modifier invocation with the
+// name of the modifier (see ModifierName), whether it modified a response
+// rather than a request, the time it took and the error it returned.
+type ModifierHook func(name string, response bool, elapsed time.Duration, err error)
+
 // RequestModifierFunc is an adapter for using a function with the given
 // signature as a RequestModifier.
 type RequestModifierFunc func(req *http.Request) error
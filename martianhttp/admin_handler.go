@@ -0,0 +1,224 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martianhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+)
+
+type adminHandler struct {
+	proxy *martian.Proxy
+}
+
+type connectionJSON struct {
+	ID           string `json:"id"`
+	RemoteAddr   string `json:"remoteAddr"`
+	Host         string `json:"host"`
+	BytesRead    int64  `json:"bytesRead"`
+	BytesWritten int64  `json:"bytesWritten"`
+	Started      string `json:"started"`
+	Duration     string `json:"duration"`
+}
+
+type metricsJSON struct {
+	Connections        int             `json:"connections"`
+	BytesRead          int64           `json:"bytesRead"`
+	BytesWritten       int64           `json:"bytesWritten"`
+	Paused             bool            `json:"paused"`
+	Listeners          []string        `json:"listeners"`
+	UpstreamRateLimits []rateLimitJSON `json:"upstreamRateLimits"`
+	QueueWaits         []queueWaitJSON `json:"queueWaits"`
+}
+
+type rateLimitJSON struct {
+	Host        string  `json:"host"`
+	RPS         float64 `json:"rps"`
+	Waiting     int     `json:"waiting"`
+	Utilization float64 `json:"utilization"`
+}
+
+type queueWaitJSON struct {
+	Host     string `json:"host"`
+	Requests int64  `json:"requests"`
+	Total    string `json:"total"`
+	Max      string `json:"max"`
+}
+
+// NewAdminHandler returns an http.Handler with JSON endpoints to inspect and
+// control p:
+//
+//	GET  /connections            lists the client connections being served
+//	POST /connections/{id}/close closes a client connection
+//	POST /drain                  asks clients to reconnect (DrainConnections)
+//	POST /pause                  stops handling new connections
+//	POST /resume                 resumes handling new connections
+//	GET  /metrics                reports totals for the active connections
+//	                             the state of upstream rate limits and the
+//	                             time requests waited for origin connections
+//
+// Paths are relative to where the handler is mounted, so use
+// http.StripPrefix to serve it under a prefix such as "/admin/". Byte counts
+// in /metrics cover the connections open at the time of the request; queue
+// waits, as reported by martian.Proxy.QueueWaits, cover all requests since the
+// proxy was created.
+//
+// The handler is not part of the proxy's traffic path and is only served
+// where the caller mounts it. It performs no authentication, and any client
+// that can reach it can close connections and stop the proxy from serving;
+// serve it on a separate, private listener, or wrap it in a handler that
+// authenticates requests.
+func NewAdminHandler(p *martian.Proxy) http.Handler {
+	return &adminHandler{
+		proxy: p,
+	}
+}
+
+// ServeHTTP routes req to the endpoint for its path.
+func (h *adminHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	path := "/" + strings.Trim(req.URL.Path, "/")
+
+	switch {
+	case path == "/connections":
+		if !allowMethod(rw, req, "GET") {
+			return
+		}
+		h.serveConnections(rw)
+	case strings.HasPrefix(path, "/connections/") && strings.HasSuffix(path, "/close"):
+		if !allowMethod(rw, req, "POST") {
+			return
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/connections/"), "/close")
+		if err := h.proxy.CloseConnection(id); err != nil {
+			http.Error(rw, err.Error(), 404)
+			return
+		}
+		rw.WriteHeader(204)
+	case path == "/drain":
+		if !allowMethod(rw, req, "POST") {
+			return
+		}
+		log.Infof("martianhttp: draining connections")
+		h.proxy.DrainConnections()
+		rw.WriteHeader(204)
+	case path == "/pause":
+		if !allowMethod(rw, req, "POST") {
+			return
+		}
+		log.Infof("martianhttp: pausing proxy")
+		h.proxy.Pause()
+		rw.WriteHeader(204)
+	case path == "/resume":
+		if !allowMethod(rw, req, "POST") {
+			return
+		}
+		log.Infof("martianhttp: resuming proxy")
+		h.proxy.Resume()
+		rw.WriteHeader(204)
+	case path == "/metrics":
+		if !allowMethod(rw, req, "GET") {
+			return
+		}
+		h.serveMetrics(rw)
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+func (h *adminHandler) serveConnections(rw http.ResponseWriter) {
+	conns := []connectionJSON{}
+	for _, ci := range h.proxy.Connections() {
+		var remote string
+		if ci.RemoteAddr != nil {
+			remote = ci.RemoteAddr.String()
+		}
+
+		conns = append(conns, connectionJSON{
+			ID:           ci.ID,
+			RemoteAddr:   remote,
+			Host:         ci.Host,
+			BytesRead:    ci.BytesRead,
+			BytesWritten: ci.BytesWritten,
+			Started:      ci.Started.Format(time.RFC3339),
+			Duration:     ci.Duration.String(),
+		})
+	}
+
+	writeJSON(rw, conns)
+}
+
+func (h *adminHandler) serveMetrics(rw http.ResponseWriter) {
+	m := metricsJSON{
+		Paused:             h.proxy.Paused(),
+		Listeners:          []string{},
+		UpstreamRateLimits: []rateLimitJSON{},
+		QueueWaits:         []queueWaitJSON{},
+	}
+	for _, ci := range h.proxy.Connections() {
+		m.Connections++
+		m.BytesRead += ci.BytesRead
+		m.BytesWritten += ci.BytesWritten
+	}
+	for _, addr := range h.proxy.Addrs() {
+		m.Listeners = append(m.Listeners, addr.String())
+	}
+	for _, rl := range h.proxy.UpstreamRateLimits() {
+		m.UpstreamRateLimits = append(m.UpstreamRateLimits, rateLimitJSON{
+			Host:        rl.Host,
+			RPS:         rl.RPS,
+			Waiting:     rl.Waiting,
+			Utilization: rl.Utilization,
+		})
+	}
+	for _, qw := range h.proxy.QueueWaits() {
+		m.QueueWaits = append(m.QueueWaits, queueWaitJSON{
+			Host:     qw.Host,
+			Requests: qw.Requests,
+			Total:    qw.Total.String(),
+			Max:      qw.Max.String(),
+		})
+	}
+
+	writeJSON(rw, m)
+}
+
+// allowMethod returns whether req uses method, and otherwise responds with 405
+// Method Not Allowed.
+func allowMethod(rw http.ResponseWriter, req *http.Request, method string) bool {
+	if req.Method == method {
+		return true
+	}
+
+	rw.Header().Set("Allow", method)
+	rw.WriteHeader(405)
+	return false
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(rw, err.Error(), 500)
+		log.Errorf("martianhttp: error encoding JSON: %v", err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(b)
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martianhttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/martiantest"
+)
+
+func serveAdmin(t *testing.T, h http.Handler, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+
+	return rw
+}
+
+func TestAdminHandler(t *testing.T) {
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := martian.NewProxy()
+	tr := martiantest.NewTransport()
+	tr.Respond(204)
+	p.SetRoundTripper(tr)
+	p.SetTimeout(2 * time.Second)
+	p.SetUpstreamRateLimit("example.com", 10)
+	go p.Serve(l)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.WriteProxy(conn); err != nil {
+		t.Fatalf("req.WriteProxy(): got %v, want no error", err)
+	}
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	res.Body.Close()
+
+	h := NewAdminHandler(p)
+
+	rw := serveAdmin(t, h, "GET", "/connections")
+	if got, want := rw.Code, 200; got != want {
+		t.Fatalf("GET /connections: rw.Code: got %d, want %d", got, want)
+	}
+	var conns []connectionJSON
+	if err := json.Unmarshal(rw.Body.Bytes(), &conns); err != nil {
+		t.Fatalf("json.Unmarshal(): got %v, want no error", err)
+	}
+	if got, want := len(conns), 1; got != want {
+		t.Fatalf("len(conns): got %d, want %d", got, want)
+	}
+	if got, want := conns[0].Host, "example.com"; got != want {
+		t.Errorf("conns[0].Host: got %q, want %q", got, want)
+	}
+
+	rw = serveAdmin(t, h, "GET", "/metrics")
+	var m metricsJSON
+	if err := json.Unmarshal(rw.Body.Bytes(), &m); err != nil {
+		t.Fatalf("json.Unmarshal(): got %v, want no error", err)
+	}
+	if got, want := m.Connections, 1; got != want {
+		t.Errorf("m.Connections: got %d, want %d", got, want)
+	}
+	if m.BytesRead == 0 || m.BytesWritten == 0 {
+		t.Errorf("m.BytesRead, m.BytesWritten: got %d, %d, want non-zero", m.BytesRead, m.BytesWritten)
+	}
+	if got, want := len(m.Listeners), 1; got != want {
+		t.Errorf("len(m.Listeners): got %d, want %d", got, want)
+	}
+	if got, want := len(m.UpstreamRateLimits), 1; got != want {
+		t.Fatalf("len(m.UpstreamRateLimits): got %d, want %d", got, want)
+	}
+	if got, want := m.UpstreamRateLimits[0].Host, "example.com"; got != want {
+		t.Errorf("m.UpstreamRateLimits[0].Host: got %q, want %q", got, want)
+	}
+	if got, want := m.UpstreamRateLimits[0].RPS, 10.0; got != want {
+		t.Errorf("m.UpstreamRateLimits[0].RPS: got %v, want %v", got, want)
+	}
+
+	for _, path := range []string{"/pause", "/resume", "/drain"} {
+		if got, want := serveAdmin(t, h, "POST", path).Code, 204; got != want {
+			t.Errorf("POST %s: rw.Code: got %d, want %d", path, got, want)
+		}
+		if path == "/pause" && !p.Paused() {
+			t.Error("p.Paused(): got false after POST /pause, want true")
+		}
+	}
+	if p.Paused() {
+		t.Error("p.Paused(): got true after POST /resume, want false")
+	}
+
+	if got, want := serveAdmin(t, h, "POST", "/connections/"+conns[0].ID+"/close").Code, 204; got != want {
+		t.Fatalf("POST /connections/{id}/close: rw.Code: got %d, want %d", got, want)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := br.ReadByte(); err == nil {
+		t.Error("br.ReadByte(): got no error, want closed connection")
+	}
+
+	if got, want := serveAdmin(t, h, "POST", "/connections/unknown/close").Code, 404; got != want {
+		t.Errorf("POST /connections/unknown/close: rw.Code: got %d, want %d", got, want)
+	}
+	rw = serveAdmin(t, h, "GET", "/drain")
+	if got, want := rw.Code, 405; got != want {
+		t.Errorf("GET /drain: rw.Code: got %d, want %d", got, want)
+	}
+	if got, want := rw.Header().Get("Allow"), "POST"; got != want {
+		t.Errorf("GET /drain: rw.Header().Get(%q): got %q, want %q", "Allow", got, want)
+	}
+	if got, want := serveAdmin(t, h, "GET", "/unknown").Code, 404; got != want {
+		t.Errorf("GET /unknown: rw.Code: got %d, want %d", got, want)
+	}
+}
+
+func TestAdminHandlerQueueWaits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer l.Close()
+
+	p := martian.NewProxy()
+	p.SetRoundTripper(&http.Transport{})
+	p.SetTimeout(2 * time.Second)
+	p.SetMaxConnsPerHost(1)
+	go p.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.WriteProxy(conn); err != nil {
+		t.Fatalf("req.WriteProxy(): got %v, want no error", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	res.Body.Close()
+
+	rw := serveAdmin(t, NewAdminHandler(p), "GET", "/metrics")
+	var m metricsJSON
+	if err := json.Unmarshal(rw.Body.Bytes(), &m); err != nil {
+		t.Fatalf("json.Unmarshal(): got %v, want no error", err)
+	}
+	if got, want := len(m.QueueWaits), 1; got != want {
+		t.Fatalf("len(m.QueueWaits): got %d, want %d", got, want)
+	}
+	if got, want := m.QueueWaits[0].Host, req.URL.Hostname(); got != want {
+		t.Errorf("m.QueueWaits[0].Host: got %q, want %q", got, want)
+	}
+	if got, want := m.QueueWaits[0].Requests, int64(1); got != want {
+		t.Errorf("m.QueueWaits[0].Requests: got %d, want %d", got, want)
+	}
+	if _, err := time.ParseDuration(m.QueueWaits[0].Max); err != nil {
+		t.Errorf("time.ParseDuration(m.QueueWaits[0].Max): got %v, want no error", err)
+	}
+}
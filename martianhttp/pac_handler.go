@@ -0,0 +1,60 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martianhttp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type pacHandler struct {
+	pac []byte
+}
+
+// NewPACHandler returns an http.Handler that serves pac as a proxy
+// auto-configuration file.
+func NewPACHandler(pac []byte) http.Handler {
+	return &pacHandler{
+		pac: pac,
+	}
+}
+
+// ServeHTTP writes the PAC file to the client.
+func (h *pacHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+	rw.Header().Set("Content-Length", strconv.Itoa(len(h.pac)))
+	rw.Write(h.pac)
+}
+
+// GeneratePAC returns a PAC file that sends all traffic through the proxy at
+// proxyAddr (host:port), except for requests to hosts matching one of
+// exclusions, which connect directly. Exclusions are shell expressions matched
+// against the host, such as "localhost" or "*.corp.example.com".
+func GeneratePAC(proxyAddr string, exclusions []string) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("function FindProxyForURL(url, host) {\n")
+	for _, ex := range exclusions {
+		fmt.Fprintf(&b, "  if (shExpMatch(host, %s)) {\n", strconv.Quote(ex))
+		b.WriteString("    return \"DIRECT\";\n")
+		b.WriteString("  }\n")
+	}
+	fmt.Fprintf(&b, "  return %s;\n", strconv.Quote("PROXY "+proxyAddr))
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martianhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPACHandler(t *testing.T) {
+	pac := GeneratePAC("proxy.example.com:8080", []string{"localhost", "*.corp.example.com"})
+
+	rw := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/proxy.pac", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	h := NewPACHandler(pac)
+	h.ServeHTTP(rw, req)
+
+	if got, want := rw.Code, 200; got != want {
+		t.Errorf("rw.Code: got %d, want %d", got, want)
+	}
+	if got, want := rw.Header().Get("Content-Type"), "application/x-ns-proxy-autoconfig"; got != want {
+		t.Errorf("rw.Header().Get(%q): got %q, want %q", "Content-Type", got, want)
+	}
+
+	want := `function FindProxyForURL(url, host) {
+  if (shExpMatch(host, "localhost")) {
+    return "DIRECT";
+  }
+  if (shExpMatch(host, "*.corp.example.com")) {
+    return "DIRECT";
+  }
+  return "PROXY proxy.example.com:8080";
+}
+`
+	if got := rw.Body.String(); got != want {
+		t.Errorf("rw.Body: got %q, want %q", got, want)
+	}
+}
+
+func TestGeneratePACEscapesStrings(t *testing.T) {
+	got := string(GeneratePAC("proxy:8080", []string{`evil"); alert("x`}))
+
+	want := `function FindProxyForURL(url, host) {
+  if (shExpMatch(host, "evil\"); alert(\"x")) {
+    return "DIRECT";
+  }
+  return "PROXY proxy:8080";
+}
+`
+	if got != want {
+		t.Errorf("GeneratePAC(): got %q, want %q", got, want)
+	}
+}
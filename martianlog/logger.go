@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/martian/v3"
 	"github.com/google/martian/v3/log"
@@ -34,12 +36,22 @@ type Logger struct {
 	log         func(line string)
 	headersOnly bool
 	decode      bool
+	maxBodySize int64
 }
 
 type loggerJSON struct {
 	Scope       []parse.ModifierType `json:"scope"`
 	HeadersOnly bool                 `json:"headersOnly"`
 	Decode      bool                 `json:"decode"`
+	MaxBodySize int64                `json:"maxBodySize"`
+	File        *rotatingFileJSON    `json:"file"`
+}
+
+type rotatingFileJSON struct {
+	Path       string `json:"path"`
+	MaxSize    int64  `json:"maxSize"`
+	MaxAge     string `json:"maxAge"`
+	MaxBackups int    `json:"maxBackups"`
 }
 
 func init() {
@@ -66,6 +78,12 @@ func (l *Logger) SetDecode(decode bool) {
 	l.decode = decode
 }
 
+// SetMaxBodySize sets the number of bytes of each body to log. Bodies longer
+// than n bytes are truncated in the log. If n is 0, bodies are logged in full.
+func (l *Logger) SetMaxBodySize(n int64) {
+	l.maxBodySize = n
+}
+
 // SetLogFunc sets the logging function for the logger.
 func (l *Logger) SetLogFunc(logFunc func(line string)) {
 	l.log = logFunc
@@ -108,13 +126,10 @@ func (l *Logger) ModifyRequest(req *http.Request) error {
 		opts = append(opts, messageview.Decode())
 	}
 
-	r, err := mv.Reader(opts...)
-	if err != nil {
+	if err := l.writeMessage(b, mv, opts); err != nil {
 		return err
 	}
 
-	io.Copy(b, r)
-
 	fmt.Fprintln(b, "")
 	fmt.Fprintln(b, strings.Repeat("-", 80))
 
@@ -158,13 +173,10 @@ func (l *Logger) ModifyResponse(res *http.Response) error {
 		opts = append(opts, messageview.Decode())
 	}
 
-	r, err := mv.Reader(opts...)
-	if err != nil {
+	if err := l.writeMessage(b, mv, opts); err != nil {
 		return err
 	}
 
-	io.Copy(b, r)
-
 	fmt.Fprintln(b, "")
 	fmt.Fprintln(b, strings.Repeat("-", 80))
 
@@ -173,16 +185,57 @@ func (l *Logger) ModifyResponse(res *http.Response) error {
 	return nil
 }
 
+// writeMessage writes the message of mv to w, truncating the body to
+// l.maxBodySize bytes.
+func (l *Logger) writeMessage(w io.Writer, mv *messageview.MessageView, opts []messageview.Option) error {
+	if l.maxBodySize <= 0 {
+		r, err := mv.Reader(opts...)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		io.Copy(w, r)
+		return nil
+	}
+
+	br, err := mv.BodyReader(opts...)
+	if err != nil {
+		return err
+	}
+	defer br.Close()
+
+	io.Copy(w, mv.HeaderReader())
+	if _, err := io.CopyN(w, br, l.maxBodySize); err == nil {
+		if n, _ := io.Copy(ioutil.Discard, br); n > 0 {
+			fmt.Fprintf(w, "\n[%d more bytes of body not logged]\n", n)
+		}
+	}
+	io.Copy(w, mv.TrailerReader())
+
+	return nil
+}
+
 // loggerFromJSON builds a logger from JSON.
 //
 // Example JSON:
 // {
 //   "log.Logger": {
 //     "scope": ["request", "response"],
-//		 "headersOnly": true,
-//		 "decode": true
+//     "headersOnly": true,
+//     "decode": true,
+//     "maxBodySize": 4096,
+//     "file": {
+//       "path": "/tmp/martian.log",
+//       "maxSize": 104857600,
+//       "maxAge": "24h",
+//       "maxBackups": 7
+//     }
 //   }
 // }
+//
+// When file is set, requests and responses are logged to a RotatingFile rather
+// than the martian log.
 func loggerFromJSON(b []byte) (*parse.Result, error) {
 	msg := &loggerJSON{}
 	if err := json.Unmarshal(b, msg); err != nil {
@@ -192,6 +245,24 @@ func loggerFromJSON(b []byte) (*parse.Result, error) {
 	l := NewLogger()
 	l.SetHeadersOnly(msg.HeadersOnly)
 	l.SetDecode(msg.Decode)
+	l.SetMaxBodySize(msg.MaxBodySize)
+
+	if msg.File != nil {
+		var maxAge time.Duration
+		if msg.File.MaxAge != "" {
+			d, err := time.ParseDuration(msg.File.MaxAge)
+			if err != nil {
+				return nil, fmt.Errorf("log.Logger: invalid maxAge: %v", err)
+			}
+			maxAge = d
+		}
+
+		f, err := NewRotatingFile(msg.File.Path, msg.File.MaxSize, maxAge, msg.File.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.SetLogFunc(f.Log)
+	}
 
 	return parse.NewResult(l, msg.Scope)
 }
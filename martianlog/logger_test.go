@@ -18,7 +18,10 @@ import (
 	"bytes"
 	"compress/gzip"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -134,3 +137,104 @@ func TestLoggerFromJSON(t *testing.T) {
 		t.Error("l.decode: got false, want true")
 	}
 }
+
+func TestLoggerMaxBodySize(t *testing.T) {
+	var got string
+	l := NewLogger()
+	l.SetLogFunc(func(line string) {
+		got = line
+	})
+	l.SetMaxBodySize(5)
+
+	req, err := http.NewRequest("POST", "http://example.com", strings.NewReader("0123456789"))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := l.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	if want := "\r\n\r\n01234\n[5 more bytes of body not logged]\n"; !strings.Contains(got, want) {
+		t.Errorf("log: got %q, want to contain %q", got, want)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if got, want := string(body), "0123456789"; got != want {
+		t.Errorf("req.Body: got %q, want %q", got, want)
+	}
+}
+
+func TestLoggerFromJSONFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "martianlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "martian.log")
+
+	msg := []byte(fmt.Sprintf(`{
+		"log.Logger": {
+			"scope": ["request"],
+			"maxBodySize": 10,
+			"file": {
+				"path": %q,
+				"maxSize": 1024,
+				"maxAge": "1h",
+				"maxBackups": 2
+			}
+		}
+	}`, path))
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+	l, ok := r.RequestModifier().(*Logger)
+	if !ok {
+		t.Fatal("r.RequestModifier(): got not *Logger, want *Logger")
+	}
+	if got, want := l.maxBodySize, int64(10); got != want {
+		t.Errorf("l.maxBodySize: got %d, want %d", got, want)
+	}
+
+	req, err := http.NewRequest("GET", "http://example.com/logged", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := l.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): got %v, want no error", err)
+	}
+	if want := "Request to http://example.com/logged"; !strings.Contains(string(b), want) {
+		t.Errorf("log file: got %q, want to contain %q", b, want)
+	}
+
+	msg = []byte(`{
+		"log.Logger": {
+			"scope": ["request"],
+			"file": {
+				"path": "martian.log",
+				"maxAge": "forever"
+			}
+		}
+	}`)
+	if _, err := parse.FromJSON(msg); err == nil {
+		t.Error("parse.FromJSON(): got no error, want error for invalid maxAge")
+	}
+}
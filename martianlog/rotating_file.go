@@ -0,0 +1,201 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martianlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// backupTimeFormat is the format of the timestamp appended to the names of
+// rotated files. It sorts in time order.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// RotatingFile is a log file that is rotated when it grows too large or too
+// old. Rotated files are renamed with a timestamp suffix, and the oldest are
+// removed. It is safe for concurrent use.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	opened  time.Time
+	timeNow func() time.Time
+}
+
+// NewRotatingFile opens the file at path for appending, creating it if needed.
+// The file is rotated before a write would take it past maxSize bytes, or once
+// it has been open for maxAge; a zero value disables either check. At most
+// maxBackups rotated files are kept; if maxBackups is 0, all are kept.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		timeNow:    time.Now,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write writes b to the file, rotating it first if needed. A single write is
+// never split across files.
+func (f *RotatingFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.size > 0 && (f.maxSize > 0 && f.size+int64(len(b)) > f.maxSize ||
+		f.maxAge > 0 && f.timeNow().Sub(f.opened) >= f.maxAge) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.f.Write(b)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Log writes line to the file followed by a newline. It can be passed to
+// Logger.SetLogFunc.
+func (f *RotatingFile) Log(line string) {
+	if _, err := f.Write([]byte(line + "\n")); err != nil {
+		log.Errorf("martianlog: failed to write to %s: %v", f.path, err)
+	}
+}
+
+// Close closes the file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+
+	return err
+}
+
+// open opens f.path and records its size. It must be called with f.mu held.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.f = file
+	f.size = fi.Size()
+	f.opened = f.timeNow()
+
+	return nil
+}
+
+// rotate renames the current file, opens a new one and removes old backups. It
+// must be called with f.mu held.
+func (f *RotatingFile) rotate() error {
+	if err := f.f.Close(); err != nil {
+		return err
+	}
+	f.f = nil
+
+	backup := fmt.Sprintf("%s.%s", f.path, f.timeNow().UTC().Format(backupTimeFormat))
+	if err := os.Rename(f.path, backup); err != nil {
+		// Keep writing to the current file; rotation is retried on the next
+		// write.
+		if oerr := f.open(); oerr != nil {
+			log.Errorf("martianlog: failed to reopen %s: %v", f.path, oerr)
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if f.maxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > f.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			log.Errorf("martianlog: failed to remove old log file %s: %v", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// backups returns the paths of the rotated files, oldest first. Only files
+// named after f.path with a timestamp suffix are included, so that other
+// files sharing the prefix, such as compressed copies, are left alone.
+func (f *RotatingFile) backups() ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(f.path) + "."
+	var backups []string
+	for _, fi := range fis {
+		name := fi.Name()
+		if !strings.HasPrefix(name, prefix) || fi.IsDir() {
+			continue
+		}
+		suffix := strings.TrimPrefix(name, prefix)
+		if len(suffix) != len(backupTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, suffix); err != nil {
+			continue
+		}
+
+		backups = append(backups, filepath.Join(filepath.Dir(f.path), name))
+	}
+	sort.Strings(backups)
+
+	return backups, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martianlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "martianlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "martian.log")
+
+	f, err := NewRotatingFile(path, 8, time.Hour, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile(): got %v, want no error", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	// Each write fits on its own, but not with the one before it.
+	for _, line := range []string{"aaaa", "bbbb", "cccc", "dddd"} {
+		f.Log(line)
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("filepath.Glob(): got %v, want no error", err)
+	}
+	sort.Strings(backups)
+	if got, want := len(backups), 2; got != want {
+		t.Fatalf("len(backups): got %d, want %d", got, want)
+	}
+
+	for i, want := range []string{"bbbb\n", "cccc\n"} {
+		got, err := ioutil.ReadFile(backups[i])
+		if err != nil {
+			t.Fatalf("ioutil.ReadFile(): got %v, want no error", err)
+		}
+		if string(got) != want {
+			t.Errorf("backup %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): got %v, want no error", err)
+	}
+	if want := "dddd\n"; string(got) != want {
+		t.Errorf("current file: got %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "martianlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "martian.log")
+
+	f, err := NewRotatingFile(path, 0, time.Minute, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingFile(): got %v, want no error", err)
+	}
+	defer f.Close()
+
+	now := time.Now()
+	f.timeNow = func() time.Time { return now }
+
+	f.Log("first")
+	f.Log("second")
+
+	now = now.Add(2 * time.Minute)
+	f.Log("third")
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("filepath.Glob(): got %v, want no error", err)
+	}
+	if got, want := len(backups), 1; got != want {
+		t.Fatalf("len(backups): got %d, want %d", got, want)
+	}
+
+	got, err := ioutil.ReadFile(backups[0])
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): got %v, want no error", err)
+	}
+	if want := "first\nsecond\n"; string(got) != want {
+		t.Errorf("backup: got %q, want %q", got, want)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("f.Close(): got %v, want no error", err)
+	}
+	if _, err := f.Write([]byte("closed")); err == nil {
+		t.Error("f.Write(): got no error after Close, want error")
+	}
+}
+
+func TestRotatingFileKeepsOtherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "martianlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "martian.log")
+
+	others := []string{path + ".gz", path + ".bak", path + ".20250101T000000"}
+	for _, other := range others {
+		if err := ioutil.WriteFile(other, []byte("keep"), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile(): got %v, want no error", err)
+		}
+	}
+
+	f, err := NewRotatingFile(path, 8, 0, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingFile(): got %v, want no error", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, line := range []string{"aaaa", "bbbb", "cccc", "dddd"} {
+		f.Log(line)
+	}
+
+	for _, other := range others {
+		if _, err := os.Stat(other); err != nil {
+			t.Errorf("os.Stat(%q): got %v, want file kept", other, err)
+		}
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		t.Fatalf("f.backups(): got %v, want no error", err)
+	}
+	if got, want := len(backups), 1; got != want {
+		t.Errorf("len(backups): got %d, want %d", got, want)
+	}
+}
+
+func TestRotatingFileRenameError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "martianlog")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): got %v, want no error", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "martian.log")
+
+	f, err := NewRotatingFile(path, 8, 0, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingFile(): got %v, want no error", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.timeNow = func() time.Time { return now }
+
+	// A non-empty directory in place of the backup makes the rename fail.
+	backup := path + "." + now.Format(backupTimeFormat)
+	if err := os.MkdirAll(filepath.Join(backup, "dir"), 0755); err != nil {
+		t.Fatalf("os.MkdirAll(): got %v, want no error", err)
+	}
+
+	if _, err := f.Write([]byte("aaaa\n")); err != nil {
+		t.Fatalf("f.Write(): got %v, want no error", err)
+	}
+	if _, err := f.Write([]byte("bbbb\n")); err == nil {
+		t.Fatal("f.Write(): got no error, want rename error")
+	}
+
+	now = now.Add(time.Second)
+	if _, err := f.Write([]byte("cccc\n")); err != nil {
+		t.Fatalf("f.Write(): got %v after failed rotation, want no error", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): got %v, want no error", err)
+	}
+	if want := "cccc\n"; string(got) != want {
+		t.Errorf("current file: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package method
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+func init() {
+	parse.Register("method.Rewriter", rewriterFromJSON)
+}
+
+// bodyless lists the methods whose requests are sent without a body.
+var bodyless = map[string]bool{
+	"GET":   true,
+	"HEAD":  true,
+	"TRACE": true,
+}
+
+// Rewriter is a martian.RequestModifier that changes the method of requests.
+type Rewriter struct {
+	rewrite func(*http.Request) string
+}
+
+type rewriterJSON struct {
+	Methods map[string]string    `json:"methods"`
+	Scope   []parse.ModifierType `json:"scope"`
+}
+
+// NewMethodRewriter returns a request modifier that changes the method of
+// requests whose method is a key of methods to the corresponding value.
+// Methods are matched ignoring case.
+func NewMethodRewriter(methods map[string]string) *Rewriter {
+	m := make(map[string]string, len(methods))
+	for from, to := range methods {
+		m[strings.ToUpper(from)] = to
+	}
+
+	return NewMethodRewriterFunc(func(req *http.Request) string {
+		return m[strings.ToUpper(req.Method)]
+	})
+}
+
+// NewMethodRewriterFunc returns a request modifier that changes the method of
+// each request to the method returned by rewrite. If rewrite returns an empty
+// string, the method is not changed.
+func NewMethodRewriterFunc(rewrite func(req *http.Request) string) *Rewriter {
+	return &Rewriter{
+		rewrite: rewrite,
+	}
+}
+
+// rewriterFromJSON builds a method.Rewriter from JSON.
+//
+// Example JSON:
+// {
+//   "method.Rewriter": {
+//     "scope": ["request"],
+//     "methods": {
+//       "POST": "GET"
+//     }
+//   }
+// }
+func rewriterFromJSON(b []byte) (*parse.Result, error) {
+	msg := &rewriterJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	return parse.NewResult(NewMethodRewriter(msg.Methods), msg.Scope)
+}
+
+// ModifyRequest changes the method of req. When the new method is GET, HEAD or
+// TRACE, which are sent without a body, the body of req is discarded along
+// with the headers that describe it. When a request without a body is
+// rewritten to a method such as POST, it is sent with an empty body and a
+// Content-Length of 0. Otherwise the body is sent unchanged. CONNECT requests
+// are never rewritten, and requests are never rewritten to CONNECT.
+func (r *Rewriter) ModifyRequest(req *http.Request) error {
+	if req.Method == "CONNECT" {
+		return nil
+	}
+
+	method := strings.ToUpper(r.rewrite(req))
+	if method == "" || method == req.Method || method == "CONNECT" {
+		return nil
+	}
+
+	log.Debugf("method.Rewriter.ModifyRequest: rewriting %s %s to %s", req.Method, req.URL, method)
+
+	req.Method = method
+	if bodyless[method] {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		req.Body = http.NoBody
+		req.ContentLength = 0
+		req.TransferEncoding = nil
+
+		for _, h := range []string{"Content-Length", "Content-Type", "Content-Encoding", "Transfer-Encoding", "Expect"} {
+			req.Header.Del(h)
+		}
+	}
+
+	return nil
+}
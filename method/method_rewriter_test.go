@@ -0,0 +1,145 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package method
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3/parse"
+)
+
+func TestRewriterRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintf(rw, "%s %d %q %s", req.Method, req.ContentLength, req.Header.Get("Content-Type"), body)
+	}))
+	defer srv.Close()
+
+	mod := NewMethodRewriter(map[string]string{
+		"post": "GET",
+		"GET":  "put",
+	})
+
+	tt := []struct {
+		method string
+		body   string
+		want   string
+	}{
+		{"POST", "a=1", `GET 0 "" `},
+		{"GET", "", `PUT 0 "" `},
+		{"PUT", "data", `PUT 4 "text/plain" data`},
+	}
+
+	for _, tc := range tt {
+		req, err := http.NewRequest(tc.method, srv.URL, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "text/plain")
+		}
+
+		if err := mod.ModifyRequest(req); err != nil {
+			t.Fatalf("ModifyRequest(): got %v, want no error", err)
+		}
+
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip(): got %v, want no error", err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+		}
+
+		if string(got) != tc.want {
+			t.Errorf("%s: origin saw %q, want %q", tc.method, got, tc.want)
+		}
+	}
+}
+
+func TestRewriterFunc(t *testing.T) {
+	mod := NewMethodRewriterFunc(func(req *http.Request) string {
+		if req.URL.Path == "/mock" {
+			return "GET"
+		}
+		if req.URL.Path == "/tunnel" {
+			return "CONNECT"
+		}
+		return ""
+	})
+
+	for _, tc := range []struct {
+		method, url, want string
+	}{
+		{"DELETE", "http://example.com/mock", "GET"},
+		{"DELETE", "http://example.com/other", "DELETE"},
+		{"POST", "http://example.com/tunnel", "POST"},
+		{"CONNECT", "//example.com:443", "CONNECT"},
+	} {
+		req, err := http.NewRequest(tc.method, tc.url, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+
+		if err := mod.ModifyRequest(req); err != nil {
+			t.Fatalf("ModifyRequest(): got %v, want no error", err)
+		}
+		if got := req.Method; got != tc.want {
+			t.Errorf("%s %s: req.Method: got %q, want %q", tc.method, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestRewriterFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"method.Rewriter": {
+			"scope": ["request"],
+			"methods": {
+				"POST": "GET"
+			}
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	reqmod := r.RequestModifier()
+	if reqmod == nil {
+		t.Fatal("r.RequestModifier(): got nil, want not nil")
+	}
+
+	req, err := http.NewRequest("POST", "http://example.com", strings.NewReader("body"))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := reqmod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if got, want := req.Method, "GET"; got != want {
+		t.Errorf("req.Method: got %q, want %q", got, want)
+	}
+	if got, want := req.Body, http.NoBody; got != want {
+		t.Errorf("req.Body: got %v, want http.NoBody", got)
+	}
+}
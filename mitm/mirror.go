@@ -0,0 +1,156 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// mirrorHandshakeTimeout bounds the TLS handshake with the origin when
+// fetching its certificate.
+const mirrorHandshakeTimeout = 10 * time.Second
+
+// SetUpstreamCertMirroring enables upstream certificate mirroring. Instead of
+// generating a leaf certificate for the requested host, the proxy connects to
+// the origin with dial, fetches its certificate and signs a copy with the
+// subject, subject alternative names and validity of the original. Mirrored
+// certificates are cached until they expire. If the origin cannot be reached,
+// a generated certificate is used instead. A nil dial disables mirroring.
+//
+// dial is called with network "tcp" and the address of the origin, the host
+// and port of the CONNECT request, or port 443 where it is not known.
+func (c *Config) SetUpstreamCertMirroring(dial func(network, addr string) (net.Conn, error)) {
+	c.mirrorDial = dial
+}
+
+// originAddr returns the address of the origin serving hostname, taking the
+// port from addr if it has one.
+func originAddr(hostname, addr string) string {
+	port := "443"
+	if _, p, err := net.SplitHostPort(addr); err == nil {
+		port = p
+	}
+
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = h
+	}
+
+	return net.JoinHostPort(hostname, port)
+}
+
+// mirroredCert returns a certificate for hostname that mirrors the certificate
+// of the origin at addr.
+func (c *Config) mirroredCert(hostname, addr string) (*tls.Certificate, error) {
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = h
+	}
+	key := hostname + "|" + addr
+
+	c.mirrormu.RLock()
+	tlsc, ok := c.mirrored[key]
+	c.mirrormu.RUnlock()
+
+	if ok && time.Now().Before(tlsc.Leaf.NotAfter) {
+		log.Debugf("mitm: mirrored certificate cache hit for %s", key)
+		return tlsc, nil
+	}
+
+	orig, err := c.fetchCert(hostname, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, MaxSerialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               orig.Subject,
+		DNSNames:              orig.DNSNames,
+		IPAddresses:           orig.IPAddresses,
+		EmailAddresses:        orig.EmailAddresses,
+		URIs:                  orig.URIs,
+		SubjectKeyId:          c.keyID,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		NotBefore:             orig.NotBefore,
+		NotAfter:              orig.NotAfter,
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, c.ca, c.priv.Public(), c.capriv)
+	if err != nil {
+		return nil, err
+	}
+
+	x509c, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsc = &tls.Certificate{
+		Certificate: [][]byte{raw, c.ca.Raw},
+		PrivateKey:  c.priv,
+		Leaf:        x509c,
+	}
+
+	c.mirrormu.Lock()
+	c.mirrored[key] = tlsc
+	c.mirrormu.Unlock()
+
+	if ok {
+		c.certRenewed(hostname, CertExpired)
+	}
+
+	return tlsc, nil
+}
+
+// fetchCert returns the leaf certificate presented by the origin at addr for
+// hostname. The certificate is not verified; it is only copied.
+func (c *Config) fetchCert(hostname, addr string) (*x509.Certificate, error) {
+	log.Debugf("mitm: fetching certificate for %s from %s", hostname, addr)
+
+	conn, err := c.mirrorDial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(mirrorHandshakeTimeout))
+
+	tconn := tls.Client(conn, &tls.Config{
+		ServerName:         hostname,
+		InsecureSkipVerify: true,
+	})
+	if err := tconn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	certs := tconn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("mitm: origin presented no certificate")
+	}
+
+	return certs[0], nil
+}
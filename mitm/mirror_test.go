@@ -0,0 +1,151 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpstreamCertMirroring(t *testing.T) {
+	// The origin presents a certificate from its own authority.
+	oca, opriv, err := NewAuthority("origin", "Origin Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+	oc, err := NewConfig(oca, opriv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+	oc.SetOrganization("Origin Organization")
+	oc.SetValidity(3 * time.Hour)
+
+	ol, err := tls.Listen("tcp", "[::]:0", oc.TLSForHost("example.com"))
+	if err != nil {
+		t.Fatalf("tls.Listen(): got %v, want no error", err)
+	}
+	defer ol.Close()
+
+	go func() {
+		for {
+			conn, err := ol.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+	c, err := NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+
+	var dials int32
+	c.SetUpstreamCertMirroring(func(network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return net.Dial(network, ol.Addr().String())
+	})
+
+	conf := c.TLSForHost("example.com:8443")
+	clientHello := &tls.ClientHelloInfo{
+		ServerName: "example.com",
+	}
+
+	tlsc, err := conf.GetCertificate(clientHello)
+	if err != nil {
+		t.Fatalf("conf.GetCertificate(): got %v, want no error", err)
+	}
+
+	x509c := tlsc.Leaf
+	orig, err := oc.cert("example.com")
+	if err != nil {
+		t.Fatalf("oc.cert(): got %v, want no error", err)
+	}
+
+	if got, want := x509c.Subject.Organization, orig.Leaf.Subject.Organization; !reflect.DeepEqual(got, want) {
+		t.Errorf("x509c.Subject.Organization: got %v, want %v", got, want)
+	}
+	if got, want := x509c.DNSNames, orig.Leaf.DNSNames; !reflect.DeepEqual(got, want) {
+		t.Errorf("x509c.DNSNames: got %v, want %v", got, want)
+	}
+	if got, want := x509c.NotAfter, orig.Leaf.NotAfter; !got.Equal(want) {
+		t.Errorf("x509c.NotAfter: got %v, want %v", got, want)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	if _, err := x509c.Verify(x509.VerifyOptions{
+		DNSName: "example.com",
+		Roots:   roots,
+	}); err != nil {
+		t.Errorf("x509c.Verify(): got %v, want no error", err)
+	}
+
+	// The mirrored certificate is cached.
+	cached, err := conf.GetCertificate(clientHello)
+	if err != nil {
+		t.Fatalf("conf.GetCertificate(): got %v, want no error", err)
+	}
+	if cached != tlsc {
+		t.Error("conf.GetCertificate(): got new certificate, want cached certificate")
+	}
+	if got, want := atomic.LoadInt32(&dials), int32(1); got != want {
+		t.Errorf("dials: got %d, want %d", got, want)
+	}
+}
+
+func TestUpstreamCertMirroringFallback(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+	c, err := NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+	c.SetOrganization("Test Organization")
+
+	var addr string
+	c.SetUpstreamCertMirroring(func(network, a string) (net.Conn, error) {
+		addr = a
+		return nil, errors.New("unreachable")
+	})
+
+	tlsc, err := c.TLS().GetCertificate(&tls.ClientHelloInfo{
+		ServerName: "example.com",
+	})
+	if err != nil {
+		t.Fatalf("conf.GetCertificate(): got %v, want no error", err)
+	}
+
+	if got, want := addr, "example.com:443"; got != want {
+		t.Errorf("dial address: got %q, want %q", got, want)
+	}
+	if got, want := tlsc.Leaf.Subject.Organization, []string{"Test Organization"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("x509c.Subject.Organization: got %v, want %v", got, want)
+	}
+}
@@ -52,12 +52,35 @@ type Config struct {
 	getCertificate         func(*tls.ClientHelloInfo) (*tls.Certificate, error)
 	roots                  *x509.CertPool
 	skipVerify             bool
+	minVersion             uint16
 	handshakeErrorCallback func(*http.Request, error)
+	certCacheCallback      func(string, CertCacheReason)
+	certHostFunc           func(connectHost, sni string) string
+	serverConfigFunc       func(*tls.ClientHelloInfo, *tls.Config) (*tls.Config, error)
+
+	ticketmu        sync.RWMutex
+	ticketKeys      [][32]byte
+	ticketsDisabled bool
 
 	certmu sync.RWMutex
 	certs  map[string]*tls.Certificate
+
+	mirrorDial func(network, addr string) (net.Conn, error)
+	mirrormu   sync.RWMutex
+	mirrored   map[string]*tls.Certificate
 }
 
+// CertCacheReason is the reason a cached certificate was replaced.
+type CertCacheReason string
+
+const (
+	// CertExpired means the cached certificate had expired.
+	CertExpired CertCacheReason = "expired"
+	// CertInvalid means the cached certificate failed verification for a
+	// reason other than expiry, for example because the roots changed.
+	CertInvalid CertCacheReason = "invalid"
+)
+
 // NewAuthority creates a new CA certificate and associated
 // private key.
 func NewAuthority(name, organization string, validity time.Duration) (*x509.Certificate, *rsa.PrivateKey, error) {
@@ -144,6 +167,7 @@ func NewConfig(ca *x509.Certificate, privateKey interface{}) (*Config, error) {
 		validity: time.Hour,
 		org:      "Martian Proxy",
 		certs:    make(map[string]*tls.Certificate),
+		mirrored: make(map[string]*tls.Certificate),
 		roots:    roots,
 	}, nil
 }
@@ -159,6 +183,13 @@ func (c *Config) SkipTLSVerify(skip bool) {
 	c.skipVerify = skip
 }
 
+// SetMinTLSVersion sets the minimum TLS version accepted from clients of
+// MITM'd connections, such as tls.VersionTLS12. Zero, the default, uses the
+// default of crypto/tls.
+func (c *Config) SetMinTLSVersion(version uint16) {
+	c.minVersion = version
+}
+
 // SetOrganization sets the organization of the certificate.
 func (c *Config) SetOrganization(org string) {
 	c.org = org
@@ -169,6 +200,36 @@ func (c *Config) SetHandshakeErrorCallback(cb func(*http.Request, error)) {
 	c.handshakeErrorCallback = cb
 }
 
+// SetCertCacheCallback sets a callback that is called with the host and the
+// reason whenever a cached certificate is replaced by a new one. Frequent
+// renewals for the same host indicate that certificates are being regenerated
+// more often than necessary, for example because the validity is too short.
+func (c *Config) SetCertCacheCallback(cb func(host string, reason CertCacheReason)) {
+	c.certCacheCallback = cb
+}
+
+// SetCertHostFunc sets a function that decides the host, a DNS name or an IP
+// address, that the certificate generated for a connection is issued for. It
+// is called with the host of the CONNECT request, without its port, and the
+// server name sent by the client with SNI; either may be empty. The connect
+// host is only known to configs returned by TLSForHost, and is empty for those
+// returned by TLS. If fn returns an empty string, or no function is set, the
+// certificate is issued for the SNI server name, falling back to the connect
+// host. The function is not consulted by TLSForIP.
+//
+// This resolves cases where the two differ, such as a CONNECT to an IP address
+// with SNI for a name, or a name that should be issued in its punycode form.
+func (c *Config) SetCertHostFunc(fn func(connectHost, sni string) string) {
+	c.certHostFunc = fn
+}
+
+// certRenewed calls the certCacheCallback function, if it is non-nil.
+func (c *Config) certRenewed(host string, reason CertCacheReason) {
+	if c.certCacheCallback != nil {
+		c.certCacheCallback(host, reason)
+	}
+}
+
 // HandshakeErrorCallback calls the handshakeErrorCallback function in this
 // Config, if it is non-nil. Request is the connect request that this handshake
 // is being executed through.
@@ -181,41 +242,53 @@ func (c *Config) HandshakeErrorCallback(r *http.Request, err error) {
 // TLS returns a *tls.Config that will generate certificates on-the-fly using
 // the SNI extension in the TLS ClientHello.
 func (c *Config) TLS() *tls.Config {
-	return &tls.Config{
+	cfg := &tls.Config{
 		InsecureSkipVerify: c.skipVerify,
+		MinVersion:         c.minVersion,
 		GetCertificate: func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-			if clientHello.ServerName == "" {
+			host := c.certHost("", clientHello.ServerName)
+			if host == "" {
 				return nil, errors.New("mitm: SNI not provided, failed to build certificate")
 			}
 
-			return c.cert(clientHello.ServerName)
+			return c.leaf(host, "")
 		},
 		NextProtos: []string{"http/1.1"},
 	}
+	c.applySessionTickets(cfg)
+	c.applyServerConfigFunc(cfg)
+
+	return cfg
 }
 
 // TLSForHost returns a *tls.Config that will generate certificates on-the-fly
 // using SNI from the connection, or fall back to the provided hostname.
 func (c *Config) TLSForHost(hostname string) *tls.Config {
-	return &tls.Config{
+	cfg := &tls.Config{
 		InsecureSkipVerify: c.skipVerify,
+		MinVersion:         c.minVersion,
 		GetCertificate: func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-			host := clientHello.ServerName
-			if host == "" {
-				host = hostname
+			connectHost := hostname
+			if h, _, err := net.SplitHostPort(hostname); err == nil {
+				connectHost = h
 			}
 
-			return c.cert(host)
+			return c.leaf(c.certHost(connectHost, clientHello.ServerName), hostname)
 		},
 		NextProtos: []string{"http/1.1"},
 	}
+	c.applySessionTickets(cfg)
+	c.applyServerConfigFunc(cfg)
+
+	return cfg
 }
 
 // TLSForIP returns a *tls.Config that will generate certificates on-the-fly
 // using SNI from the connection, or fall back to the connections remote IP.
 func (c *Config) TLSForIP() *tls.Config {
-	return &tls.Config{
+	cfg := &tls.Config{
 		InsecureSkipVerify: c.skipVerify,
+		MinVersion:         c.minVersion,
 		GetCertificate: func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 			host := clientHello.ServerName
 			if host == "" {
@@ -226,10 +299,47 @@ func (c *Config) TLSForIP() *tls.Config {
 				host = ip
 			}
 
-			return c.cert(host)
+			return c.leaf(host, "")
 		},
 		NextProtos: []string{"http/1.1"},
 	}
+	c.applySessionTickets(cfg)
+	c.applyServerConfigFunc(cfg)
+
+	return cfg
+}
+
+// certHost returns the host that the certificate for a connection is generated
+// for: the host returned by the function set with SetCertHostFunc, or sni, or,
+// if the client did not send SNI, connectHost.
+func (c *Config) certHost(connectHost, sni string) string {
+	if c.certHostFunc != nil {
+		if host := c.certHostFunc(connectHost, sni); host != "" {
+			return host
+		}
+	}
+	if sni != "" {
+		return sni
+	}
+
+	return connectHost
+}
+
+// leaf returns the certificate presented to clients for hostname, mirroring
+// the certificate of the origin at addr if upstream certificate mirroring is
+// enabled. addr may be empty or lack a port, in which case port 443 is used.
+func (c *Config) leaf(hostname, addr string) (*tls.Certificate, error) {
+	if c.mirrorDial == nil {
+		return c.cert(hostname)
+	}
+
+	tlsc, err := c.mirroredCert(hostname, originAddr(hostname, addr))
+	if err != nil {
+		log.Errorf("mitm: failed to mirror certificate for %s, generating one: %v", hostname, err)
+		return c.cert(hostname)
+	}
+
+	return tlsc, nil
 }
 
 func (c *Config) cert(hostname string) (*tls.Certificate, error) {
@@ -243,6 +353,8 @@ func (c *Config) cert(hostname string) (*tls.Certificate, error) {
 	tlsc, ok := c.certs[hostname]
 	c.certmu.RUnlock()
 
+	var renewal CertCacheReason
+
 	if ok {
 		log.Debugf("mitm: cache hit for %s", hostname)
 
@@ -256,6 +368,11 @@ func (c *Config) cert(hostname string) (*tls.Certificate, error) {
 		}
 
 		log.Debugf("mitm: invalid certificate in cache for %s", hostname)
+
+		renewal = CertInvalid
+		if time.Now().After(tlsc.Leaf.NotAfter) {
+			renewal = CertExpired
+		}
 	}
 
 	log.Debugf("mitm: cache miss for %s", hostname)
@@ -306,5 +423,9 @@ func (c *Config) cert(hostname string) (*tls.Certificate, error) {
 	c.certs[hostname] = tlsc
 	c.certmu.Unlock()
 
+	if renewal != "" {
+		c.certRenewed(hostname, renewal)
+	}
+
 	return tlsc, nil
 }
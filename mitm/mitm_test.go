@@ -203,3 +203,102 @@ func TestCert(t *testing.T) {
 		t.Fatalf("x509c.IPAddresses: got %v, want %v", got, want)
 	}
 }
+
+func TestCertCacheCallback(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+
+	c, err := NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+
+	var hosts []string
+	var reasons []CertCacheReason
+	c.SetCertCacheCallback(func(host string, reason CertCacheReason) {
+		hosts = append(hosts, host)
+		reasons = append(reasons, reason)
+	})
+
+	// Cache miss and cache hit.
+	for i := 0; i < 2; i++ {
+		if _, err := c.cert("example.com"); err != nil {
+			t.Fatalf("c.cert(): got %v, want no error", err)
+		}
+	}
+	if len(hosts) != 0 {
+		t.Fatalf("callback: got %v, want no calls", hosts)
+	}
+
+	// Certificate times have a resolution of a second; a negative validity
+	// yields a certificate that has already expired.
+	c.SetValidity(-time.Minute)
+	if _, err := c.cert("expired.example.com"); err != nil {
+		t.Fatalf("c.cert(): got %v, want no error", err)
+	}
+	c.SetValidity(time.Hour)
+	if _, err := c.cert("expired.example.com:443"); err != nil {
+		t.Fatalf("c.cert(): got %v, want no error", err)
+	}
+
+	if got, want := hosts, []string{"expired.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("callback hosts: got %v, want %v", got, want)
+	}
+	if got, want := reasons, []CertCacheReason{CertExpired}; !reflect.DeepEqual(got, want) {
+		t.Errorf("callback reasons: got %v, want %v", got, want)
+	}
+}
+
+func TestCertHostFunc(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+
+	c, err := NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+
+	var calls [][2]string
+	c.SetCertHostFunc(func(connectHost, sni string) string {
+		calls = append(calls, [2]string{connectHost, sni})
+
+		// Prefer the name of a CONNECT to an IP address over SNI.
+		if net.ParseIP(connectHost) != nil && sni != "" {
+			return sni
+		}
+		if connectHost != "" {
+			return connectHost
+		}
+		return ""
+	})
+
+	tt := []struct {
+		conf     *tls.Config
+		sni      string
+		wantCN   string
+		wantCall [2]string
+	}{
+		{c.TLSForHost("10.0.0.1:443"), "example.com", "example.com", [2]string{"10.0.0.1", "example.com"}},
+		{c.TLSForHost("example.com:443"), "www.example.com", "example.com", [2]string{"example.com", "www.example.com"}},
+		{c.TLS(), "example.org", "example.org", [2]string{"", "example.org"}},
+	}
+
+	for i, tc := range tt {
+		calls = nil
+
+		tlsc, err := tc.conf.GetCertificate(&tls.ClientHelloInfo{ServerName: tc.sni})
+		if err != nil {
+			t.Fatalf("%d. conf.GetCertificate(): got %v, want no error", i, err)
+		}
+		if got := tlsc.Leaf.Subject.CommonName; got != tc.wantCN {
+			t.Errorf("%d. x509c.Subject.CommonName: got %q, want %q", i, got, tc.wantCN)
+		}
+		if got, want := calls, [][2]string{tc.wantCall}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%d. cert host func calls: got %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Options is the configuration of a Config built by NewConfigFromOptions. The
+// zero value of each field other than CA and Key leaves the corresponding
+// default of NewConfig unchanged.
+type Options struct {
+	// CA is the certificate authority that signs generated certificates.
+	CA *x509.Certificate
+	// Key is the private key of CA.
+	Key crypto.Signer
+
+	// Validity is the validity window around the current time of generated
+	// certificates, as set with SetValidity.
+	Validity time.Duration
+	// Organization is the organization of generated certificates, as set
+	// with SetOrganization.
+	Organization string
+	// MinTLSVersion is the minimum TLS version accepted from clients, as set
+	// with SetMinTLSVersion.
+	MinTLSVersion uint16
+	// SkipTLSVerify skips verification of origin certificates, as set with
+	// SkipTLSVerify.
+	SkipTLSVerify bool
+
+	// SessionTicketKeys are the session ticket keys, as set with
+	// SetSessionTicketKeys.
+	SessionTicketKeys [][32]byte
+	// SessionTicketsDisabled disables session tickets, as set with
+	// SetSessionTicketsDisabled.
+	SessionTicketsDisabled bool
+
+	// HandshakeErrorCallback is set with SetHandshakeErrorCallback.
+	HandshakeErrorCallback func(*http.Request, error)
+	// CertCacheCallback is set with SetCertCacheCallback.
+	CertCacheCallback func(host string, reason CertCacheReason)
+	// CertHostFunc is set with SetCertHostFunc.
+	CertHostFunc func(connectHost, sni string) string
+}
+
+// NewConfigFromOptions returns a Config configured with opts in one call. It is
+// equivalent to calling NewConfig followed by the setter of each option, but
+// first validates the options together, returning an error if:
+//   - CA or Key is missing, or Key is not the private key of CA;
+//   - CA is not a certificate authority that may sign certificates, or is
+//     not valid at the current time;
+//   - Validity is negative;
+//   - MinTLSVersion is not a known TLS version;
+//   - SessionTicketKeys are set while session tickets are disabled.
+//
+// The setters remain available to change the Config afterwards.
+func NewConfigFromOptions(opts Options) (*Config, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := NewConfig(opts.CA, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Validity > 0 {
+		c.SetValidity(opts.Validity)
+	}
+	if opts.Organization != "" {
+		c.SetOrganization(opts.Organization)
+	}
+	c.SetMinTLSVersion(opts.MinTLSVersion)
+	c.SkipTLSVerify(opts.SkipTLSVerify)
+
+	if len(opts.SessionTicketKeys) > 0 {
+		if err := c.SetSessionTicketKeys(opts.SessionTicketKeys); err != nil {
+			return nil, err
+		}
+	}
+	c.SetSessionTicketsDisabled(opts.SessionTicketsDisabled)
+
+	c.SetHandshakeErrorCallback(opts.HandshakeErrorCallback)
+	c.SetCertCacheCallback(opts.CertCacheCallback)
+	c.SetCertHostFunc(opts.CertHostFunc)
+
+	return c, nil
+}
+
+// validate returns an error describing the first invalid option in opts.
+func (opts *Options) validate() error {
+	if opts.CA == nil {
+		return errors.New("mitm: no CA certificate")
+	}
+	if opts.Key == nil {
+		return errors.New("mitm: no CA private key")
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(opts.Key.Public())
+	if err != nil {
+		return fmt.Errorf("mitm: invalid CA private key: %v", err)
+	}
+	if !bytes.Equal(pub, opts.CA.RawSubjectPublicKeyInfo) {
+		return errors.New("mitm: CA private key does not match the CA certificate")
+	}
+
+	if !opts.CA.IsCA {
+		return errors.New("mitm: CA certificate is not a certificate authority")
+	}
+	if opts.CA.KeyUsage != 0 && opts.CA.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.New("mitm: CA certificate may not sign certificates")
+	}
+	if now := time.Now(); now.Before(opts.CA.NotBefore) || now.After(opts.CA.NotAfter) {
+		return fmt.Errorf("mitm: CA certificate is only valid from %v to %v", opts.CA.NotBefore, opts.CA.NotAfter)
+	}
+
+	if opts.Validity < 0 {
+		return fmt.Errorf("mitm: negative validity %v", opts.Validity)
+	}
+
+	switch opts.MinTLSVersion {
+	case 0, tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+	default:
+		return fmt.Errorf("mitm: unknown minimum TLS version %#x", opts.MinTLSVersion)
+	}
+
+	if len(opts.SessionTicketKeys) > 0 && opts.SessionTicketsDisabled {
+		return errors.New("mitm: session ticket keys set with session tickets disabled")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestNewConfigFromOptions(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+
+	c, err := NewConfigFromOptions(Options{
+		CA:                     ca,
+		Key:                    priv,
+		Validity:               24 * time.Hour,
+		Organization:           "Test Organization",
+		MinTLSVersion:          tls.VersionTLS12,
+		SkipTLSVerify:          true,
+		SessionTicketsDisabled: true,
+	})
+	if err != nil {
+		t.Fatalf("NewConfigFromOptions(): got %v, want no error", err)
+	}
+
+	if got, want := c.validity, 24*time.Hour; got != want {
+		t.Errorf("c.validity: got %v, want %v", got, want)
+	}
+	if got, want := c.org, "Test Organization"; got != want {
+		t.Errorf("c.org: got %q, want %q", got, want)
+	}
+
+	cfg := c.TLS()
+	if got, want := cfg.MinVersion, uint16(tls.VersionTLS12); got != want {
+		t.Errorf("cfg.MinVersion: got %#x, want %#x", got, want)
+	}
+	if !cfg.InsecureSkipVerify {
+		t.Error("cfg.InsecureSkipVerify: got false, want true")
+	}
+	if !cfg.SessionTicketsDisabled {
+		t.Error("cfg.SessionTicketsDisabled: got false, want true")
+	}
+
+	tlsc, err := c.cert("example.com")
+	if err != nil {
+		t.Fatalf("c.cert(): got %v, want no error", err)
+	}
+	if got, want := tlsc.Leaf.Subject.Organization, []string{"Test Organization"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("tlsc.Leaf.Subject.Organization: got %v, want %v", got, want)
+	}
+}
+
+func TestNewConfigFromOptionsInvalid(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+	_, otherpriv, err := NewAuthority("other.proxy", "Other Authority", time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+
+	tt := []struct {
+		name string
+		opts Options
+	}{
+		{"no CA", Options{Key: priv}},
+		{"no key", Options{CA: ca}},
+		{"mismatched key", Options{CA: ca, Key: otherpriv}},
+		{"negative validity", Options{CA: ca, Key: priv, Validity: -time.Hour}},
+		{"unknown TLS version", Options{CA: ca, Key: priv, MinTLSVersion: 0x0200}},
+		{"tickets disabled with keys", Options{
+			CA:                     ca,
+			Key:                    priv,
+			SessionTicketKeys:      [][32]byte{{1}},
+			SessionTicketsDisabled: true,
+		}},
+	}
+
+	for _, tc := range tt {
+		if _, err := NewConfigFromOptions(tc.opts); err == nil {
+			t.Errorf("%s: NewConfigFromOptions(): got no error, want error", tc.name)
+		}
+	}
+}
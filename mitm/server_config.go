@@ -0,0 +1,60 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/tls"
+)
+
+// SetServerConfigFunc sets a function that builds the TLS config for each
+// handshake with a client, giving full control over the server side of the
+// handshake, such as the cipher suites, curves, versions and ALPN protocols
+// offered, to mimic the TLS fingerprint of a particular server. It is called
+// with the ClientHello and a copy of the config that would otherwise be used,
+// which includes the GetCertificate function that generates the MITM
+// certificate; fn may change and return base, or return a new config. If fn
+// returns nil, base is used unchanged, and if it returns an error, the
+// handshake fails.
+//
+// The function is installed as tls.Config.GetConfigForClient on the configs
+// returned by TLS, TLSForHost and TLSForIP. The returned config must remain
+// usable for the handshake: it needs a certificate for the client, and the
+// parameters it allows must overlap with those the client offers, or the
+// handshake fails. Dropping "http/1.1" from NextProtos may prevent the proxy
+// from reading requests from the connection.
+func (c *Config) SetServerConfigFunc(fn func(hello *tls.ClientHelloInfo, base *tls.Config) (*tls.Config, error)) {
+	c.serverConfigFunc = fn
+}
+
+// applyServerConfigFunc sets cfg to be customized for each client by the
+// function set with SetServerConfigFunc, if any.
+func (c *Config) applyServerConfigFunc(cfg *tls.Config) {
+	fn := c.serverConfigFunc
+	if fn == nil {
+		return
+	}
+
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		base := cfg.Clone()
+		base.GetConfigForClient = nil
+
+		sc, err := fn(hello, base)
+		if err != nil || sc == nil {
+			return base, err
+		}
+
+		return sc, nil
+	}
+}
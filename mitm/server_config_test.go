@@ -0,0 +1,99 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// handshake completes a TLS handshake between a server using the config
+// returned by c.TLS and a client using client, and returns the client's
+// connection state.
+func handshake(t *testing.T, c *Config, client *tls.Config) (tls.ConnectionState, error) {
+	t.Helper()
+
+	sconn, cconn := net.Pipe()
+	defer sconn.Close()
+	defer cconn.Close()
+
+	go func() {
+		tconn := tls.Server(sconn, c.TLS())
+		tconn.SetDeadline(time.Now().Add(5 * time.Second))
+		tconn.Handshake()
+	}()
+
+	tconn := tls.Client(cconn, client)
+	tconn.SetDeadline(time.Now().Add(5 * time.Second))
+	err := tconn.Handshake()
+
+	return tconn.ConnectionState(), err
+}
+
+func TestServerConfigFunc(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+
+	c, err := NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := &tls.Config{
+		ServerName: "example.com",
+		RootCAs:    roots,
+	}
+
+	var sni string
+	c.SetServerConfigFunc(func(hello *tls.ClientHelloInfo, base *tls.Config) (*tls.Config, error) {
+		sni = hello.ServerName
+		if base.GetCertificate == nil {
+			t.Error("base.GetCertificate: got nil, want MITM certificate func")
+		}
+
+		base.MaxVersion = tls.VersionTLS12
+		base.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}
+		return base, nil
+	})
+
+	cs, err := handshake(t, c, client)
+	if err != nil {
+		t.Fatalf("Handshake(): got %v, want no error", err)
+	}
+	if got, want := sni, "example.com"; got != want {
+		t.Errorf("hello.ServerName: got %q, want %q", got, want)
+	}
+	if got, want := cs.Version, uint16(tls.VersionTLS12); got != want {
+		t.Errorf("cs.Version: got %#x, want %#x", got, want)
+	}
+	if got, want := cs.CipherSuite, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256; got != want {
+		t.Errorf("cs.CipherSuite: got %#x, want %#x", got, want)
+	}
+
+	c.SetServerConfigFunc(func(*tls.ClientHelloInfo, *tls.Config) (*tls.Config, error) {
+		return nil, errors.New("refused")
+	})
+	if _, err := handshake(t, c, client); err == nil {
+		t.Error("Handshake(): got no error, want error when the config func fails")
+	}
+}
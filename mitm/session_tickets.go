@@ -0,0 +1,100 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+)
+
+// maxSessionTicketKeys is the number of keys kept by RotateSessionTicketKey:
+// the new key and the previous one, so that tickets issued shortly before a
+// rotation can still be used.
+const maxSessionTicketKeys = 2
+
+// SetSessionTicketKeys sets the keys used to encrypt and decrypt the TLS
+// session tickets issued to clients. The first key encrypts new tickets; all
+// keys decrypt tickets presented by clients. Sharing keys across the configs
+// returned by TLS, TLSForHost and TLSForIP lets a client resume its session on
+// a new connection through the proxy, skipping the certificate exchange and key
+// agreement of a full handshake.
+//
+// Without keys, each generated config encrypts tickets with its own random key,
+// so sessions are never resumed across connections.
+//
+// Session ticket keys weaken forward secrecy: anyone who obtains a key can
+// decrypt the traffic of every session whose ticket was encrypted with it, so
+// keys must be kept secret and rotated often, for example with
+// RotateSessionTicketKey. Keys only affect configs created after the call;
+// connections that are already established are not affected.
+func (c *Config) SetSessionTicketKeys(keys [][32]byte) error {
+	if len(keys) == 0 {
+		return errors.New("mitm: no session ticket keys")
+	}
+
+	c.ticketmu.Lock()
+	defer c.ticketmu.Unlock()
+
+	c.ticketKeys = append([][32]byte(nil), keys...)
+
+	return nil
+}
+
+// RotateSessionTicketKey generates a random session ticket key that encrypts
+// new tickets from now on. The previous key is kept to decrypt tickets issued
+// before the rotation, and older keys are discarded, so the tickets they
+// encrypted can no longer be used to resume sessions. Calling it periodically,
+// such as every hour, limits how much traffic a leaked key exposes.
+func (c *Config) RotateSessionTicketKey() error {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+
+	c.ticketmu.Lock()
+	defer c.ticketmu.Unlock()
+
+	keys := append([][32]byte{key}, c.ticketKeys...)
+	if len(keys) > maxSessionTicketKeys {
+		keys = keys[:maxSessionTicketKeys]
+	}
+	c.ticketKeys = keys
+
+	return nil
+}
+
+// SetSessionTicketsDisabled sets whether session tickets are issued to
+// clients. Disabling them forces a full handshake on every connection.
+func (c *Config) SetSessionTicketsDisabled(disabled bool) {
+	c.ticketmu.Lock()
+	defer c.ticketmu.Unlock()
+
+	c.ticketsDisabled = disabled
+}
+
+// applySessionTickets applies the session ticket configuration to cfg.
+func (c *Config) applySessionTickets(cfg *tls.Config) {
+	c.ticketmu.RLock()
+	defer c.ticketmu.RUnlock()
+
+	if c.ticketsDisabled {
+		cfg.SessionTicketsDisabled = true
+		return
+	}
+	if len(c.ticketKeys) > 0 {
+		cfg.SetSessionTicketKeys(c.ticketKeys)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mitm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+)
+
+// resumes connects to a TLS server using a config freshly generated by conf and
+// returns whether the session was resumed.
+func resumes(t *testing.T, conf func() *tls.Config, client *tls.Config) bool {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		tconn := tls.Server(conn, conf())
+		defer tconn.Close()
+
+		// Write a byte so that the client processes tickets sent after the
+		// handshake.
+		tconn.Write([]byte("x"))
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		t.Fatalf("tls.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("conn.Read(): got %v, want no error", err)
+	}
+
+	return conn.ConnectionState().DidResume
+}
+
+func TestSessionTickets(t *testing.T) {
+	ca, priv, err := NewAuthority("martian.proxy", "Martian Authority", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthority(): got %v, want no error", err)
+	}
+
+	c, err := NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("NewConfig(): got %v, want no error", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := func() *tls.Config {
+		return &tls.Config{
+			ServerName:         "example.com",
+			RootCAs:            roots,
+			ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		}
+	}
+	conf := func() *tls.Config {
+		return c.TLSForHost("example.com")
+	}
+
+	// Each generated config has its own ticket key.
+	cc := client()
+	resumes(t, conf, cc)
+	if resumes(t, conf, cc) {
+		t.Error("without keys: DidResume: got true, want false")
+	}
+
+	if err := c.SetSessionTicketKeys(nil); err == nil {
+		t.Error("c.SetSessionTicketKeys(nil): got no error, want error")
+	}
+	if err := c.RotateSessionTicketKey(); err != nil {
+		t.Fatalf("c.RotateSessionTicketKey(): got %v, want no error", err)
+	}
+
+	cc = client()
+	resumes(t, conf, cc)
+	if !resumes(t, conf, cc) {
+		t.Error("with key: DidResume: got false, want true")
+	}
+
+	// Tickets encrypted with the previous key are still accepted after one
+	// rotation, but not after two.
+	if err := c.RotateSessionTicketKey(); err != nil {
+		t.Fatalf("c.RotateSessionTicketKey(): got %v, want no error", err)
+	}
+	if !resumes(t, conf, cc) {
+		t.Error("after rotation: DidResume: got false, want true")
+	}
+
+	cc = client()
+	resumes(t, conf, cc)
+	c.RotateSessionTicketKey()
+	c.RotateSessionTicketKey()
+	if resumes(t, conf, cc) {
+		t.Error("after two rotations: DidResume: got true, want false")
+	}
+
+	c.SetSessionTicketsDisabled(true)
+	cc = client()
+	resumes(t, conf, cc)
+	if resumes(t, conf, cc) {
+		t.Error("disabled: DidResume: got true, want false")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// mitmProbeTimeout is the time allowed for the TLS handshake of a probe
+// connection made for SetMITMDecision.
+const mitmProbeTimeout = 10 * time.Second
+
+// SetMITMDecision sets a function that decides whether a CONNECT request that
+// would otherwise be MITM'd is MITM'd or tunneled, based on the certificate of
+// the origin; for example, to tunnel connections to sites that pin their
+// certificates. Before the first MITM of a host, the proxy dials the origin,
+// the same way a tunnel would be dialed, and completes a TLS handshake with it
+// to fetch its leaf certificate. decide is called with the authority
+// (host:port) of the CONNECT request and that certificate, and MITM goes
+// ahead if it returns true.
+//
+// The decision is cached for each authority for the life of the proxy, so the
+// origin is only probed once; requests that arrive while a probe is in flight
+// wait for its decision. If the probe fails, decide is called with a nil
+// certificate and its decision is not cached. A nil decide, the default, MITMs
+// without probing.
+func (p *Proxy) SetMITMDecision(decide func(host string, originCert *x509.Certificate) bool) {
+	p.mitmDecidemu.Lock()
+	defer p.mitmDecidemu.Unlock()
+
+	p.mitmDecision = decide
+	p.mitmDecisions = nil
+	p.mitmProbes = nil
+}
+
+// mitmProbe is an in-flight probe of an origin for SetMITMDecision, shared by
+// the CONNECT requests to its authority that arrive while it runs.
+type mitmProbe struct {
+	done chan struct{}
+	mitm bool
+}
+
+// decideMITM returns whether the CONNECT request req is MITM'd according to
+// the function set with SetMITMDecision. Concurrent requests to an authority
+// that has not been decided yet share a single probe.
+func (p *Proxy) decideMITM(req *http.Request) bool {
+	host := hostname(req.URL.Host)
+
+	p.mitmDecidemu.Lock()
+	decide := p.mitmDecision
+	if decide == nil {
+		p.mitmDecidemu.Unlock()
+		return true
+	}
+	if mitm, ok := p.mitmDecisions[req.URL.Host]; ok {
+		p.mitmDecidemu.Unlock()
+		return mitm
+	}
+	if probe, ok := p.mitmProbes[req.URL.Host]; ok {
+		p.mitmDecidemu.Unlock()
+
+		log.HostDebugf(host, "martian: waiting for in-flight MITM probe of %s", req.URL.Host)
+		<-probe.done
+		return probe.mitm
+	}
+
+	probe := &mitmProbe{done: make(chan struct{})}
+	if p.mitmProbes == nil {
+		p.mitmProbes = make(map[string]*mitmProbe)
+	}
+	p.mitmProbes[req.URL.Host] = probe
+	p.mitmDecidemu.Unlock()
+
+	cert, err := p.probeCert(req)
+	if err != nil {
+		log.HostErrorf(host, "martian: failed to probe certificate of %s: %v", req.URL.Host, err)
+		probe.mitm = decide(req.URL.Host, nil)
+	} else {
+		probe.mitm = decide(req.URL.Host, cert)
+		log.HostDebugf(host, "martian: MITM decision for %s: %t", req.URL.Host, probe.mitm)
+	}
+
+	p.mitmDecidemu.Lock()
+	// The decision is dropped if SetMITMDecision was called during the probe.
+	if p.mitmProbes[req.URL.Host] == probe {
+		delete(p.mitmProbes, req.URL.Host)
+
+		if err == nil {
+			if p.mitmDecisions == nil {
+				p.mitmDecisions = make(map[string]bool)
+			}
+			p.mitmDecisions[req.URL.Host] = probe.mitm
+		}
+	}
+	p.mitmDecidemu.Unlock()
+	close(probe.done)
+
+	return probe.mitm
+}
+
+// probeCert connects to the origin of the CONNECT request req and returns the
+// leaf certificate it presents. The certificate is not verified, other than
+// against the pins of the host set with SetUpstreamCertPins.
+func (p *Proxy) probeCert(req *http.Request) (*x509.Certificate, error) {
+	res, conn, err := p.connect(req)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		res.Body.Close()
+		return nil, fmt.Errorf("CONNECT to downstream proxy failed: %s", res.Status)
+	}
+	defer conn.Close()
+
+	host, _, err := net.SplitHostPort(req.URL.Host)
+	if err != nil {
+		host = req.URL.Host
+	}
+
+	tlsconn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		VerifyConnection:   p.verifyHostPins(host),
+	})
+	tlsconn.SetDeadline(time.Now().Add(mitmProbeTimeout))
+	if err := tlsconn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	return tlsconn.ConnectionState().PeerCertificates[0], nil
+}
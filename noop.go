@@ -42,3 +42,8 @@ func (nm *noopModifier) ModifyResponse(*http.Response) error {
 	log.Debugf("%s: no response modifier configured", nm.id)
 	return nil
 }
+
+// ModifierName returns the ID of the modifier.
+func (nm *noopModifier) ModifierName() string {
+	return nm.id
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+// proxyMethods are the methods the proxy forwards, listed in the Allow header
+// of its responses to OPTIONS *.
+var proxyMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH"}
+
+// SetHandleOptionsAsterisk sets whether the proxy answers OPTIONS requests
+// with an asterisk-form request-target ("OPTIONS * HTTP/1.1") sent to the
+// proxy itself, rather than forwarding them. The response is a 200 OK with an
+// Allow header listing the methods the proxy forwards; request and response
+// modifiers are not run.
+//
+// OPTIONS * asks about the server the client is connected to. Sent on a
+// connection to the proxy, it is about the proxy. A client asking about an
+// origin sends "OPTIONS * HTTP/1.1" inside a CONNECT tunnel, or sends an
+// absolute-form request without a path, such as "OPTIONS http://example.com
+// HTTP/1.1", to the proxy; those are forwarded to the origin as usual. With
+// transparent proxying, clients believe they are connected to the origin, so
+// this should be left off, which is the default.
+func (p *Proxy) SetHandleOptionsAsterisk(handle bool) {
+	p.handleOptionsAsterisk = handle
+}
+
+// isOptionsAsterisk returns whether req is an OPTIONS * request for the proxy.
+func isOptionsAsterisk(req *http.Request, tunneled bool) bool {
+	return !tunneled && req.Method == "OPTIONS" && req.RequestURI == "*"
+}
+
+// answerOptions responds to the OPTIONS * request req with the capabilities of
+// the proxy. It returns errClose if the connection is to be closed.
+func (p *Proxy) answerOptions(session *Session, req *http.Request, brw *bufio.ReadWriter) error {
+	log.Debugf("martian: answering OPTIONS * from %s", req.RemoteAddr)
+
+	res := proxyutil.NewResponse(200, nil, req)
+	res.ContentLength = 0
+	res.Header.Set("Allow", strings.Join(proxyMethods, ", "))
+	res.Header.Set("Content-Length", "0")
+	p.setServerHeader(res.Header)
+
+	// A request body is not read, so it cannot be told apart from the next
+	// request.
+	if req.Close || req.ContentLength != 0 || p.recycle(session) {
+		res.Close = true
+	}
+
+	if err := res.Write(brw); err != nil {
+		log.Errorf("martian: got error while writing response back to client: %v", err)
+	}
+	if err := brw.Flush(); err != nil {
+		log.Errorf("martian: got error while flushing response back to client: %v", err)
+		return errClose
+	}
+	if res.Close {
+		return errClose
+	}
+
+	return nil
+}
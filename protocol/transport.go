@@ -0,0 +1,261 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+)
+
+// Transport is an http.RoundTripper that sends requests marked by a
+// VersionModifier as HTTP/1.0, and all other requests with a base round
+// tripper.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport returns a Transport that sends requests that are not marked as
+// HTTP/1.0 with base. HTTP/1.0 requests are sent on a new connection each,
+// dialed with the DialContext, or Dial, and TLSClientConfig of base if it is an
+// *http.Transport; its Proxy is not used. They are read for each request, so
+// that changes made to base, such as those made by martian.Proxy when the
+// Transport is set as its round tripper, apply.
+//
+// HTTP/1.0 has no chunked encoding, so request bodies of unknown length are
+// read into memory to send their Content-Length, and connections are not
+// reused: each request is sent with Connection: close. Since the connection
+// carries a single response, responses are returned as HTTP/1.1, with a
+// response whose length is delimited by the end of the connection chunked
+// instead, so that the proxy keeps the connection with the client open.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		base: base,
+	}
+}
+
+// Base returns the round tripper that sends requests that are not marked as
+// HTTP/1.0. martian.Proxy uses it to configure the *http.Transport beneath t.
+func (t *Transport) Base() http.RoundTripper {
+	return t.base
+}
+
+// RoundTrip sends req as HTTP/1.0 if it was marked by a VersionModifier, or
+// with the base round tripper otherwise.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := martian.NewContext(req)
+	if ctx == nil {
+		return t.base.RoundTrip(req)
+	}
+	if http10, _ := ctx.Get(http10Key); http10 != true {
+		return t.base.RoundTrip(req)
+	}
+
+	return t.roundTrip10(req)
+}
+
+// roundTrip10 sends req as HTTP/1.0 on a new connection.
+func (t *Transport) roundTrip10(req *http.Request) (*http.Response, error) {
+	log.Debugf("protocol.Transport: sending %s %s as HTTP/1.0", req.Method, req.URL)
+
+	body, length, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := t.connect(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Close the connection if the request is canceled before the response
+	// body is closed.
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-req.Context().Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			conn.Close()
+		})
+	}
+
+	if err := writeRequest10(conn, req, body, length); err != nil {
+		release()
+		return nil, err
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &connBody{ReadCloser: res.Body, release: release}
+
+	// The connection only carried this response; nothing about it applies to
+	// the connection with the client.
+	res.Proto = "HTTP/1.1"
+	res.ProtoMajor = 1
+	res.ProtoMinor = 1
+	res.Close = false
+	res.Header.Del("Connection")
+	res.Header.Del("Keep-Alive")
+	if res.ContentLength < 0 && len(res.TransferEncoding) == 0 {
+		res.TransferEncoding = []string{"chunked"}
+	}
+
+	return res, nil
+}
+
+// connect dials the origin of req, completing a TLS handshake for https.
+func (t *Transport) connect(req *http.Request) (net.Conn, error) {
+	host := req.URL.Hostname()
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	dial := (&net.Dialer{}).DialContext
+	var tlsConfig *tls.Config
+	if tr, ok := t.base.(*http.Transport); ok {
+		switch {
+		case tr.DialContext != nil:
+			dial = tr.DialContext
+		case tr.Dial != nil:
+			dial = func(_ context.Context, network, addr string) (net.Conn, error) {
+				return tr.Dial(network, addr)
+			}
+		}
+		tlsConfig = tr.TLSClientConfig
+	}
+
+	conn, err := dial(req.Context(), "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme != "https" {
+		return conn, nil
+	}
+
+	cfg := &tls.Config{}
+	if tlsConfig != nil {
+		cfg = tlsConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	cfg.NextProtos = nil
+
+	tconn := tls.Client(conn, cfg)
+	if err := tconn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tconn, nil
+}
+
+// requestBody returns the body of req and its length, reading bodies of
+// unknown length into memory. As for http.Transport, a ContentLength of 0 with
+// a body means the length is unknown.
+func requestBody(req *http.Request) (io.Reader, int64, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, 0, nil
+	}
+	if req.ContentLength > 0 {
+		return req.Body, req.ContentLength, nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bytes.NewReader(b), int64(len(b)), nil
+}
+
+// writeRequest10 writes req to w as an HTTP/1.0 request with body.
+func writeRequest10(w io.Writer, req *http.Request, body io.Reader, length int64) error {
+	bw := bufio.NewWriter(w)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	h := make(http.Header, len(req.Header))
+	for k, vs := range req.Header {
+		h[k] = append([]string(nil), vs...)
+	}
+	for _, name := range []string{"Host", "Transfer-Encoding", "Keep-Alive", "Te", "Trailer", "Upgrade"} {
+		h.Del(name)
+	}
+	h.Set("Connection", "close")
+	h.Del("Content-Length")
+	switch {
+	case body != nil:
+		h.Set("Content-Length", strconv.FormatInt(length, 10))
+	case req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH":
+		h.Set("Content-Length", "0")
+	}
+
+	fmt.Fprintf(bw, "%s %s HTTP/1.0\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), host)
+	if err := h.Write(bw); err != nil {
+		return err
+	}
+	bw.WriteString("\r\n")
+
+	if body != nil {
+		if _, err := io.CopyN(bw, body, length); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// connBody is a response body that releases its connection when closed.
+type connBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+
+	return err
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/martiantest"
+)
+
+type originRequest struct {
+	req  *http.Request
+	body string
+}
+
+func TestTransportHTTP10(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer l.Close()
+
+	// The origin records the request it reads and responds with an HTTP/1.0
+	// response delimited by the end of the connection.
+	reqc := make(chan originRequest, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		reqc <- originRequest{req: req, body: string(body)}
+
+		conn.Write([]byte("HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\n\r\nhello"))
+	}()
+
+	// The body has an unknown length, which HTTP/1.0 cannot chunk.
+	req, err := http.NewRequest("POST", "http://"+l.Addr().String()+"/path?q=1", ioutil.NopCloser(strings.NewReader("body!")))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	mod, err := NewVersionModifier("HTTP/1.0")
+	if err != nil {
+		t.Fatalf("NewVersionModifier(): got %v, want no error", err)
+	}
+	if err := mod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	base := martiantest.NewTransport()
+	res, err := NewTransport(base).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip(): got %v, want no error", err)
+	}
+	defer res.Body.Close()
+
+	or := <-reqc
+	if got, want := or.req.Proto, "HTTP/1.0"; got != want {
+		t.Errorf("origin req.Proto: got %q, want %q", got, want)
+	}
+	if got, want := or.req.RequestURI, "/path?q=1"; got != want {
+		t.Errorf("origin req.RequestURI: got %q, want %q", got, want)
+	}
+	if !or.req.Close {
+		t.Error("origin req.Close: got false, want true")
+	}
+	if got, want := or.req.Header.Get("Connection"), "close"; got != want {
+		t.Errorf("origin req.Header.Get(%q): got %q, want %q", "Connection", got, want)
+	}
+	if got, want := or.req.ContentLength, int64(5); got != want {
+		t.Errorf("origin req.ContentLength: got %d, want %d", got, want)
+	}
+	if got, want := or.body, "body!"; got != want {
+		t.Errorf("origin body: got %q, want %q", got, want)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := string(body), "hello"; got != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+	if res.Close {
+		t.Error("res.Close: got true, want false so the client connection stays open")
+	}
+	if res.ProtoMinor != 1 {
+		t.Errorf("res.ProtoMinor: got %d, want 1", res.ProtoMinor)
+	}
+	if got, want := res.TransferEncoding, []string{"chunked"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("res.TransferEncoding: got %v, want %v", got, want)
+	}
+}
+
+func TestTransportBase(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	mod, err := NewVersionModifier("HTTP/1.1")
+	if err != nil {
+		t.Fatalf("NewVersionModifier(): got %v, want no error", err)
+	}
+	if err := mod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	base := martiantest.NewTransport()
+	base.Respond(204)
+	res, err := NewTransport(base).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, 204; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestTransportProxyConfiguresBase(t *testing.T) {
+	// The downstream proxy answers every request itself.
+	ds := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("downstream " + req.URL.String()))
+	}))
+	defer ds.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := martian.NewProxy()
+	defer p.Close()
+
+	base := &http.Transport{}
+	p.SetRoundTripper(NewTransport(base))
+	p.SetDownstreamProxy(&url.URL{Scheme: "http", Host: ds.Listener.Addr().String()})
+	p.SetMaxConnsPerHost(3)
+	p.SetTimeout(5 * time.Second)
+	if err := p.SetUpstreamCertPins(map[string][]string{
+		"example.com": {"sha256/" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))},
+	}); err != nil {
+		t.Fatalf("p.SetUpstreamCertPins(): got %v, want no error", err)
+	}
+
+	if base.DialContext == nil {
+		t.Error("base.DialContext: got nil, want the proxy dialer")
+	}
+	if got, want := base.MaxConnsPerHost, 3; got != want {
+		t.Errorf("base.MaxConnsPerHost: got %d, want %d", got, want)
+	}
+	if base.TLSClientConfig == nil || base.TLSClientConfig.VerifyConnection == nil {
+		t.Error("base.TLSClientConfig.VerifyConnection: got nil, want certificate pin check")
+	}
+
+	go p.Serve(l)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: l.Addr().String()}),
+		},
+	}
+	res, err := client.Get("http://example.com/path")
+	if err != nil {
+		t.Fatalf("client.Get(): got %v, want no error", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if got, want := string(body), "downstream http://example.com/path"; got != want {
+		t.Errorf("res.Body: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protocol provides a modifier and transport to change the HTTP
+// version of requests sent upstream.
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+)
+
+// http10Key is the context key that marks requests to be sent as HTTP/1.0.
+const http10Key = "protocol.HTTP10"
+
+func init() {
+	parse.Register("protocol.VersionModifier", versionModifierFromJSON)
+}
+
+// VersionModifier is a request modifier that sets the HTTP version of
+// requests.
+type VersionModifier struct {
+	proto        string
+	major, minor int
+}
+
+type versionModifierJSON struct {
+	Version string               `json:"version"`
+	Scope   []parse.ModifierType `json:"scope"`
+}
+
+// NewVersionModifier returns a request modifier that sets the version of each
+// request to version, either "HTTP/1.0" or "HTTP/1.1", for example to test how
+// an origin treats HTTP/1.0 clients.
+//
+// The http.Transport always sends requests as HTTP/1.1, so requests set to
+// HTTP/1.0 are only sent as such by a Transport returned by NewTransport, which
+// must be set as the round tripper of the proxy. The version only applies to
+// the request sent upstream; the connection with the client is unaffected.
+func NewVersionModifier(version string) (*VersionModifier, error) {
+	major, minor, ok := http.ParseHTTPVersion(version)
+	if !ok || major != 1 || minor > 1 {
+		return nil, fmt.Errorf("protocol.VersionModifier: unsupported version %q, want HTTP/1.0 or HTTP/1.1", version)
+	}
+
+	return &VersionModifier{
+		proto: version,
+		major: major,
+		minor: minor,
+	}, nil
+}
+
+// ModifyRequest sets the version of req and marks it for Transport.
+func (m *VersionModifier) ModifyRequest(req *http.Request) error {
+	log.Debugf("protocol.VersionModifier: sending %s as %s", req.URL, m.proto)
+
+	req.Proto = m.proto
+	req.ProtoMajor = m.major
+	req.ProtoMinor = m.minor
+
+	if ctx := martian.NewContext(req); ctx != nil {
+		ctx.Set(http10Key, m.minor == 0)
+	}
+
+	return nil
+}
+
+// versionModifierFromJSON builds a protocol.VersionModifier from JSON.
+//
+// Example JSON:
+// {
+//   "protocol.VersionModifier": {
+//     "scope": ["request"],
+//     "version": "HTTP/1.0"
+//   }
+// }
+func versionModifierFromJSON(b []byte) (*parse.Result, error) {
+	msg := &versionModifierJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	mod, err := NewVersionModifier(msg.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse.NewResult(mod, msg.Scope)
+}
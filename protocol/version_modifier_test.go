@@ -0,0 +1,91 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/parse"
+)
+
+func TestVersionModifier(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	mod, err := NewVersionModifier("HTTP/1.0")
+	if err != nil {
+		t.Fatalf("NewVersionModifier(): got %v, want no error", err)
+	}
+	if err := mod.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	if got, want := req.Proto, "HTTP/1.0"; got != want {
+		t.Errorf("req.Proto: got %q, want %q", got, want)
+	}
+	if req.ProtoMajor != 1 || req.ProtoMinor != 0 {
+		t.Errorf("req.ProtoMajor, req.ProtoMinor: got %d, %d, want 1, 0", req.ProtoMajor, req.ProtoMinor)
+	}
+	if v, _ := ctx.Get(http10Key); v != true {
+		t.Errorf("ctx.Get(%q): got %v, want true", http10Key, v)
+	}
+
+	for _, v := range []string{"HTTP/2.0", "HTTP/0.9", "bogus"} {
+		if _, err := NewVersionModifier(v); err == nil {
+			t.Errorf("NewVersionModifier(%q): got no error, want error", v)
+		}
+	}
+}
+
+func TestVersionModifierFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"protocol.VersionModifier": {
+			"scope": ["request"],
+			"version": "HTTP/1.0"
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	mod, ok := r.RequestModifier().(*VersionModifier)
+	if !ok {
+		t.Fatal("r.RequestModifier(): got not *VersionModifier, want *VersionModifier")
+	}
+	if got, want := mod.proto, "HTTP/1.0"; got != want {
+		t.Errorf("mod.proto: got %q, want %q", got, want)
+	}
+
+	msg = []byte(`{
+		"protocol.VersionModifier": {
+			"scope": ["request"],
+			"version": "HTTP/3"
+		}
+	}`)
+	if _, err := parse.FromJSON(msg); err == nil {
+		t.Error("parse.FromJSON(): got no error, want error for unsupported version")
+	}
+}
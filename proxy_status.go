@@ -0,0 +1,210 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// defaultProxyStatusName is the name of the proxy in Proxy-Status headers
+// unless one is set with SetProxyStatusName.
+const defaultProxyStatusName = "martian"
+
+// headerCountError is returned by handle when a response has more header
+// fields than allowed by SetMaxHeaderCount.
+type headerCountError struct {
+	n, max int
+}
+
+func (e *headerCountError) Error() string {
+	return fmt.Sprintf("martian: response has %d header fields, more than the maximum of %d", e.n, e.max)
+}
+
+// SetEmitProxyStatus sets whether a Proxy-Status header (RFC 9209) is added to
+// responses to describe how the proxy handled the request. For a response
+// from the next hop, the origin or the downstream proxy, it names the next
+// hop and the status received from it. For a 502 Bad Gateway generated by the
+// proxy when the round trip or the CONNECT fails, it holds the type of the
+// error, such as connection_refused, connection_timeout or dns_error, and its
+// details. The header is added, after any from proxies closer to the origin,
+// before the response modifiers run.
+func (p *Proxy) SetEmitProxyStatus(emit bool) {
+	p.emitProxyStatus = emit
+}
+
+// SetProxyStatusName sets the name that identifies the proxy in Proxy-Status
+// headers, "martian" by default.
+func (p *Proxy) SetProxyStatusName(name string) {
+	p.proxyStatusName = name
+}
+
+// proxyStatus adds a Proxy-Status header to h for the response to req, which
+// failed with err if it is not nil, unless Proxy-Status headers are disabled.
+// nextHop reports whether the response came from the next hop.
+func (p *Proxy) proxyStatus(h http.Header, req *http.Request, res *http.Response, err error, nextHop bool) {
+	if !p.emitProxyStatus {
+		return
+	}
+
+	name := p.proxyStatusName
+	if name == "" {
+		name = defaultProxyStatusName
+	}
+
+	params := []string{sfItem(name)}
+	if hop := p.nextHop(req); hop != "" && (nextHop || err != nil) {
+		params = append(params, "next-hop="+sfItem(hop))
+	}
+	if err != nil {
+		params = append(params, "error="+proxyStatusError(err), "details="+sfString(err.Error()))
+	} else if nextHop && res != nil {
+		params = append(params, fmt.Sprintf("received-status=%d", res.StatusCode))
+	}
+
+	h.Add("Proxy-Status", strings.Join(params, "; "))
+}
+
+// nextHop returns the host of the next hop of req: the downstream proxy, if
+// any, or the origin.
+func (p *Proxy) nextHop(req *http.Request) string {
+	if p.proxyURL != nil {
+		return p.proxyURL.Hostname()
+	}
+
+	return req.URL.Hostname()
+}
+
+// proxyStatusError returns the RFC 9209 error type of err.
+func proxyStatusError(err error) string {
+	if _, ok := err.(*headerCountError); ok {
+		return "http_response_header_size"
+	}
+
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+
+	switch e := err.(type) {
+	case *net.DNSError:
+		return "dns_error"
+	case *net.OpError:
+		if e.Op == "dial" {
+			switch {
+			case e.Timeout():
+				return "connection_timeout"
+			case isErrno(e.Err, syscall.ECONNREFUSED):
+				return "connection_refused"
+			case isErrno(e.Err, syscall.EHOSTUNREACH), isErrno(e.Err, syscall.ENETUNREACH):
+				return "destination_ip_unroutable"
+			}
+			if _, ok := e.Err.(*net.DNSError); ok {
+				return "dns_error"
+			}
+			return "destination_unavailable"
+		}
+		if e.Timeout() {
+			return "http_response_timeout"
+		}
+		return "connection_terminated"
+	case x509.CertificateInvalidError, x509.HostnameError, x509.UnknownAuthorityError, *CertPinError:
+		return "tls_certificate_error"
+	case tls.RecordHeaderError:
+		return "tls_protocol_error"
+	}
+
+	switch {
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return "connection_terminated"
+	case err == gocontext.DeadlineExceeded:
+		return "http_response_timeout"
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return "http_response_timeout"
+	}
+
+	return "proxy_internal_error"
+}
+
+// isErrno returns whether err is the system call error errno.
+func isErrno(err error, errno syscall.Errno) bool {
+	if se, ok := err.(*os.SyscallError); ok {
+		err = se.Err
+	}
+
+	return err == errno
+}
+
+// sfItem returns s as a Structured Field (RFC 8941) token if it is a valid
+// token, or as a string otherwise.
+func sfItem(s string) string {
+	if isSFToken(s) {
+		return s
+	}
+
+	return sfString(s)
+}
+
+// isSFToken returns whether s is a valid Structured Field token.
+func isSFToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	if c := s[0]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*') {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			continue
+		}
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~:/", rune(c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sfString returns s as a Structured Field string, replacing characters that
+// cannot be represented with "?".
+func sfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
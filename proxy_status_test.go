@@ -0,0 +1,153 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+func TestProxyStatusError(t *testing.T) {
+	tt := []struct {
+		err  error
+		want string
+	}{
+		{&net.OpError{Op: "dial", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, "connection_refused"},
+		{&net.OpError{Op: "dial", Err: &timeoutError{}}, "connection_timeout"},
+		{&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "example.test"}}, "dns_error"},
+		{&net.OpError{Op: "dial", Err: errors.New("dial failed")}, "destination_unavailable"},
+		{&net.OpError{Op: "read", Err: &timeoutError{}}, "http_response_timeout"},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset")}, "connection_terminated"},
+		{&net.DNSError{Err: "no such host", Name: "example.test"}, "dns_error"},
+		{io.ErrUnexpectedEOF, "connection_terminated"},
+		{gocontext.DeadlineExceeded, "http_response_timeout"},
+		{&headerCountError{n: 10, max: 5}, "http_response_header_size"},
+		{errors.New("unknown"), "proxy_internal_error"},
+	}
+
+	for i, tc := range tt {
+		if got := proxyStatusError(tc.err); got != tc.want {
+			t.Errorf("%d. proxyStatusError(%v): got %q, want %q", i, tc.err, got, tc.want)
+		}
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (*timeoutError) Error() string   { return "i/o timeout" }
+func (*timeoutError) Timeout() bool   { return true }
+func (*timeoutError) Temporary() bool { return true }
+
+func TestSFItem(t *testing.T) {
+	tt := []struct {
+		s, want string
+	}{
+		{"martian", "martian"},
+		{"example.com:8080", "example.com:8080"},
+		{"Example Proxy", `"Example Proxy"`},
+		{"[::1]", `"[::1]"`},
+		{`say "hi"\`, `"say \"hi\"\\"`},
+		{"caf\xc3\xa9", `"caf??"`},
+	}
+
+	for _, tc := range tt {
+		if got := sfItem(tc.s); got != tc.want {
+			t.Errorf("sfItem(%q): got %s, want %s", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestIntegrationProxyStatus(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	p.SetTimeout(5 * time.Second)
+	p.SetEmitProxyStatus(true)
+	p.SetProxyStatusName("Example Proxy")
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	tr := martiantest.NewTransport()
+	tr.Func(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/refused" {
+			return nil, refused
+		}
+		res := proxyutil.NewResponse(201, nil, req)
+		res.Header.Set("Proxy-Status", "upstream; received-status=201")
+		return res, nil
+	})
+	p.SetRoundTripper(tr)
+
+	go p.Serve(l)
+
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		{"/created", []string{
+			"upstream; received-status=201",
+			`"Example Proxy"; next-hop=example.com; received-status=201`,
+		}},
+		{"/refused", []string{
+			fmt.Sprintf(`"Example Proxy"; next-hop=example.com; error=connection_refused; details=%s`, sfString(refused.Error())),
+		}},
+	} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("net.Dial(): got %v, want no error", err)
+		}
+		defer conn.Close()
+
+		req, err := http.NewRequest("GET", "http://example.com"+tc.path, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+
+		if err := req.WriteProxy(conn); err != nil {
+			t.Fatalf("req.WriteProxy(): got %v, want no error", err)
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+		}
+		res.Body.Close()
+
+		got := strings.Join(res.Header["Proxy-Status"], ", ")
+		if want := strings.Join(tc.want, ", "); got != want {
+			t.Errorf("%s: res.Header[%q]: got %q, want %q", tc.path, "Proxy-Status", got, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"net"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueueWait reports the time requests to an upstream host waited for a
+// connection, as limited by SetMaxConnsPerHost.
+//
+// This is the per-host queue: a request waits in it from when the round
+// tripper asks for a connection to the host until it gets an idle connection
+// or starts dialing a new one, so the time spent dialing is not included. The
+// proxy has no queue for client connections; each connection is served as
+// soon as it is accepted, and its requests as soon as they are read. Waits for
+// the rate limits set with SetUpstreamRateLimit happen before the request
+// joins the per-host queue, and are reported by UpstreamRateLimits instead.
+type QueueWait struct {
+	// Host is the host the requests were sent to.
+	Host string
+	// Requests is the number of requests that got a connection to the host.
+	Requests int64
+	// Total is the time these requests waited in total.
+	Total time.Duration
+	// Max is the longest time a request waited.
+	Max time.Duration
+}
+
+// QueueWaits returns the time requests waited for connections to each
+// upstream host since the proxy was created, sorted by host. Waits are only
+// measured while a limit is set with SetMaxConnsPerHost.
+func (p *Proxy) QueueWaits() []QueueWait {
+	return p.queueWaits.stats()
+}
+
+// QueueWait returns the time the request waited for a connection to its
+// origin, as described for QueueWait, or zero if it did not wait or no limit
+// is set with SetMaxConnsPerHost. It is available to response modifiers once
+// the round trip is complete.
+func (ctx *Context) QueueWait() time.Duration {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+
+	return ctx.queueWait
+}
+
+// traceQueueWait returns a copy of gctx that measures the time the request of
+// ctx waits for a connection to its origin.
+func (p *Proxy) traceQueueWait(gctx gocontext.Context, ctx *Context) gocontext.Context {
+	return httptrace.WithClientTrace(gctx, &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			host, _, err := net.SplitHostPort(hostPort)
+			if err != nil {
+				host = hostPort
+			}
+
+			ctx.mu.Lock()
+			defer ctx.mu.Unlock()
+
+			ctx.queueHost = strings.ToLower(host)
+			ctx.queueStart = p.clock.Now()
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			p.endQueueWait(gctx)
+		},
+	})
+}
+
+// endQueueWait ends the wait for a connection of the request carried by gctx,
+// if it is waiting, and records the time it waited.
+func (p *Proxy) endQueueWait(gctx gocontext.Context) {
+	ctx, ok := gctx.Value(martianContextKey{}).(*Context)
+	if !ok {
+		return
+	}
+
+	ctx.mu.Lock()
+	if ctx.queueStart.IsZero() {
+		ctx.mu.Unlock()
+		return
+	}
+	host := ctx.queueHost
+	wait := p.clock.Now().Sub(ctx.queueStart)
+	ctx.queueStart = time.Time{}
+	ctx.queueWait += wait
+	ctx.mu.Unlock()
+
+	p.queueWaits.record(host, wait)
+}
+
+// queueWaits holds the time requests waited for connections, keyed by host.
+type queueWaits struct {
+	mu    sync.Mutex
+	hosts map[string]*QueueWait
+}
+
+func (q *queueWaits) record(host string, wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.hosts == nil {
+		q.hosts = make(map[string]*QueueWait)
+	}
+	qw, ok := q.hosts[host]
+	if !ok {
+		qw = &QueueWait{Host: host}
+		q.hosts[host] = qw
+	}
+
+	qw.Requests++
+	qw.Total += wait
+	if wait > qw.Max {
+		qw.Max = wait
+	}
+}
+
+func (q *queueWaits) stats() []QueueWait {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := make([]QueueWait, 0, len(q.hosts))
+	for _, qw := range q.hosts {
+		stats = append(stats, *qw)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Host < stats[j].Host
+	})
+
+	return stats
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIntegrationQueueWait(t *testing.T) {
+	t.Parallel()
+
+	// The origin holds the first request until released.
+	ol, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer ol.Close()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var once sync.Once
+	go http.Serve(ol, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		once.Do(func() {
+			<-release
+		})
+	}))
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	p.SetRoundTripper(&http.Transport{})
+	p.SetTimeout(5 * time.Second)
+	p.SetMaxConnsPerHost(1)
+
+	var mu sync.Mutex
+	var waits []time.Duration
+	p.SetResponseModifier(ResponseModifierFunc(func(res *http.Response) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		waits = append(waits, NewContext(res.Request).QueueWait())
+		return nil
+	}))
+
+	go p.Serve(l)
+
+	errc := make(chan error, 2)
+	send := func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+
+		req, err := http.NewRequest("GET", "http://"+ol.Addr().String(), nil)
+		if err != nil {
+			errc <- err
+			return
+		}
+		if err := req.WriteProxy(conn); err != nil {
+			errc <- err
+			return
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			errc <- err
+			return
+		}
+		res.Body.Close()
+		errc <- nil
+	}
+
+	go send()
+	<-started
+	// The second request waits for the connection of the first.
+	go send()
+	const held = 200 * time.Millisecond
+	time.Sleep(held)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("request: got %v, want no error", err)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(ol.Addr().String())
+	if err != nil {
+		t.Fatalf("net.SplitHostPort(): got %v, want no error", err)
+	}
+
+	qws := p.QueueWaits()
+	if got, want := len(qws), 1; got != want {
+		t.Fatalf("len(p.QueueWaits()): got %d, want %d", got, want)
+	}
+	qw := qws[0]
+	if got, want := qw.Host, host; got != want {
+		t.Errorf("qw.Host: got %q, want %q", got, want)
+	}
+	if got, want := qw.Requests, int64(2); got != want {
+		t.Errorf("qw.Requests: got %d, want %d", got, want)
+	}
+	if qw.Max < held {
+		t.Errorf("qw.Max: got %v, want at least %v", qw.Max, held)
+	}
+	if qw.Total < qw.Max {
+		t.Errorf("qw.Total: got %v, want at least %v", qw.Total, qw.Max)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if got, want := len(waits), 2; got != want {
+		t.Fatalf("len(waits): got %d, want %d", got, want)
+	}
+	if waits[0] >= held {
+		t.Errorf("QueueWait() of first request: got %v, want less than %v", waits[0], held)
+	}
+	if waits[1] < held {
+		t.Errorf("QueueWait() of second request: got %v, want at least %v", waits[1], held)
+	}
+}
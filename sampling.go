@@ -0,0 +1,49 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"math/rand"
+)
+
+// SetSampleRate sets the fraction of client connections, from 0 to 1, whose
+// requests go through the modifiers. The rest are passed through untouched:
+// their requests, responses and WebSocket frames skip all modifiers, and their
+// CONNECT requests are tunneled without MITM. The choice is made at random for
+// each connection as it is accepted, and can be read with Session.Passthrough.
+// The default rate is 1, so that every connection is modified.
+func (p *Proxy) SetSampleRate(rate float64) {
+	switch {
+	case rate < 0:
+		rate = 0
+	case rate > 1:
+		rate = 1
+	}
+
+	p.passthroughRate = 1 - rate
+}
+
+// passthrough returns whether a newly accepted connection is passed through
+// untouched.
+func (p *Proxy) passthrough() bool {
+	return p.passthroughRate > 0 && rand.Float64() < p.passthroughRate
+}
+
+// Passthrough returns whether the connection of the session was not sampled by
+// Proxy.SetSampleRate, so that its requests skip all modifiers. Since session
+// modifiers run before any request, they see the value for the connection.
+func (s *Session) Passthrough() bool {
+	return s.passthrough
+}
@@ -0,0 +1,299 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schema provides a modifier that validates JSON message bodies
+// against a JSON Schema.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a compiled JSON Schema document. The following keywords are
+// supported: type, enum, const, properties, required, additionalProperties,
+// items, minItems, maxItems, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minLength, maxLength, pattern, allOf, anyOf, oneOf and
+// not. Unsupported keywords are ignored.
+type Schema struct {
+	doc interface{}
+}
+
+// ValidationError describes a single location in a JSON document that failed
+// validation.
+type ValidationError struct {
+	// Path is a JSON pointer to the failing value.
+	Path string
+	// Message describes the failure.
+	Message string
+}
+
+// Error returns the path and message of the validation error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Compile parses b as a JSON Schema document.
+func Compile(b []byte) (*Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("schema: invalid schema: %v", err)
+	}
+
+	switch doc.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("schema: schema must be an object or a boolean")
+	}
+
+	return &Schema{doc: doc}, nil
+}
+
+// Validate unmarshals b and validates it against the schema. It returns the
+// list of validation failures, or nil if b is valid.
+func (s *Schema) Validate(b []byte) ([]*ValidationError, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	return validate(s.doc, v, ""), nil
+}
+
+func validate(sch interface{}, v interface{}, path string) []*ValidationError {
+	if path == "" {
+		path = "/"
+	}
+
+	switch sch := sch.(type) {
+	case bool:
+		if !sch {
+			return []*ValidationError{{Path: path, Message: "no value is allowed"}}
+		}
+		return nil
+	case map[string]interface{}:
+		return validateObject(sch, v, path)
+	}
+
+	return nil
+}
+
+func validateObject(sch map[string]interface{}, v interface{}, path string) []*ValidationError {
+	var errs []*ValidationError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, ok := sch["type"]; ok && !matchesType(t, v) {
+		fail("got type %s, want %v", typeOf(v), t)
+		return errs
+	}
+
+	if enum, ok := sch["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the enumerated values")
+		}
+	}
+
+	if c, ok := sch["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("value does not match const")
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		props, _ := sch["properties"].(map[string]interface{})
+
+		if req, ok := sch["required"].([]interface{}); ok {
+			for _, r := range req {
+				name, _ := r.(string)
+				if _, ok := v[name]; !ok {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			child := joinPath(path, k)
+			if ps, ok := props[k]; ok {
+				errs = append(errs, validate(ps, v[k], child)...)
+				continue
+			}
+			if ap, ok := sch["additionalProperties"]; ok {
+				if allowed, ok := ap.(bool); ok && !allowed {
+					fail("additional property %q is not allowed", k)
+					continue
+				}
+				errs = append(errs, validate(ap, v[k], child)...)
+			}
+		}
+	case []interface{}:
+		if n, ok := number(sch["minItems"]); ok && float64(len(v)) < n {
+			fail("got %d items, want at least %v", len(v), n)
+		}
+		if n, ok := number(sch["maxItems"]); ok && float64(len(v)) > n {
+			fail("got %d items, want at most %v", len(v), n)
+		}
+		if items, ok := sch["items"]; ok {
+			for i, item := range v {
+				errs = append(errs, validate(items, item, joinPath(path, fmt.Sprint(i)))...)
+			}
+		}
+	case string:
+		l := float64(len([]rune(v)))
+		if n, ok := number(sch["minLength"]); ok && l < n {
+			fail("got length %v, want at least %v", l, n)
+		}
+		if n, ok := number(sch["maxLength"]); ok && l > n {
+			fail("got length %v, want at most %v", l, n)
+		}
+		if p, ok := sch["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				fail("invalid pattern %q: %v", p, err)
+			} else if !re.MatchString(v) {
+				fail("value %q does not match pattern %q", v, p)
+			}
+		}
+	case float64:
+		if n, ok := number(sch["minimum"]); ok && v < n {
+			fail("got %v, want at least %v", v, n)
+		}
+		if n, ok := number(sch["maximum"]); ok && v > n {
+			fail("got %v, want at most %v", v, n)
+		}
+		if n, ok := number(sch["exclusiveMinimum"]); ok && v <= n {
+			fail("got %v, want greater than %v", v, n)
+		}
+		if n, ok := number(sch["exclusiveMaximum"]); ok && v >= n {
+			fail("got %v, want less than %v", v, n)
+		}
+	}
+
+	if all, ok := sch["allOf"].([]interface{}); ok {
+		for _, s := range all {
+			errs = append(errs, validate(s, v, path)...)
+		}
+	}
+
+	if any, ok := sch["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, s := range any {
+			if len(validate(s, v, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value does not match any schema in anyOf")
+		}
+	}
+
+	if one, ok := sch["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, s := range one {
+			if len(validate(s, v, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("value matches %d schemas in oneOf, want exactly 1", matched)
+		}
+	}
+
+	if not, ok := sch["not"]; ok && len(validate(not, v, path)) == 0 {
+		fail("value must not match schema in not")
+	}
+
+	return errs
+}
+
+func matchesType(t interface{}, v interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(t, v)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func matchesTypeName(name string, v interface{}) bool {
+	switch name {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	default:
+		return typeOf(v) == name
+	}
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return "unknown"
+}
+
+func number(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+// joinPath appends a JSON pointer reference token to path.
+func joinPath(path, token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	token = strings.Replace(token, "/", "~1", -1)
+
+	if path == "/" {
+		return "/" + token
+	}
+
+	return path + "/" + token
+}
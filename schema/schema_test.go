@@ -0,0 +1,73 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	s, err := Compile([]byte(`{
+		"type": "object",
+		"required": ["id", "name"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"name": {"type": "string", "minLength": 2, "pattern": "^[a-z]+$"},
+			"tags": {"type": "array", "maxItems": 2, "items": {"enum": ["a", "b"]}},
+			"kind": {"oneOf": [{"const": "x"}, {"const": "y"}]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Compile(): got %v, want no error", err)
+	}
+
+	tt := []struct {
+		doc   string
+		paths []string
+	}{
+		{`{"id": 1, "name": "ok", "tags": ["a"], "kind": "x"}`, nil},
+		{`{"id": 1.5, "name": "ok"}`, []string{"/id"}},
+		{`{"id": 0, "name": "ok"}`, []string{"/id"}},
+		{`{"name": "ok"}`, []string{"/"}},
+		{`{"id": 1, "name": "A"}`, []string{"/name", "/name"}},
+		{`{"id": 1, "name": "ok", "tags": ["a", "c", "b"]}`, []string{"/tags", "/tags/1"}},
+		{`{"id": 1, "name": "ok", "extra": true}`, []string{"/"}},
+		{`{"id": 1, "name": "ok", "kind": "z"}`, []string{"/kind"}},
+		{`[]`, []string{"/"}},
+	}
+
+	for i, tc := range tt {
+		errs, err := s.Validate([]byte(tc.doc))
+		if err != nil {
+			t.Fatalf("%d. Validate(): got %v, want no error", i, err)
+		}
+
+		if got, want := len(errs), len(tc.paths); got != want {
+			t.Fatalf("%d. len(errs): got %d, want %d: %v", i, got, want, errs)
+		}
+		for j, e := range errs {
+			if got, want := e.Path, tc.paths[j]; got != want {
+				t.Errorf("%d. errs[%d].Path: got %q, want %q", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, doc := range []string{`{`, `"string"`, `1`} {
+		if _, err := Compile([]byte(doc)); err == nil {
+			t.Errorf("Compile(%q): got nil, want error", doc)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/log"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+// ErrorsKey is the context key under which the []*ValidationError for a
+// request or response that failed validation is stored.
+const ErrorsKey = "schema.ValidationErrors"
+
+const rejectKey = "schema.RejectRequest"
+
+// DefaultMaxBodySize is the default maximum number of bytes that are buffered
+// for validation.
+const DefaultMaxBodySize = 1 << 20
+
+// Mode determines the action taken when a body fails validation.
+type Mode int
+
+const (
+	// Annotate stores the validation errors on the context and adds a Warning
+	// header to the message.
+	Annotate Mode = iota
+	// Reject skips the round trip for invalid requests and responds with a 400,
+	// and replaces invalid responses with a 502.
+	Reject
+)
+
+func init() {
+	parse.Register("schema.Validator", validatorFromJSON)
+}
+
+// Validator is a modifier that validates JSON request and response bodies
+// against a schema.
+type Validator struct {
+	schema  *Schema
+	mode    Mode
+	maxSize int64
+}
+
+type validatorJSON struct {
+	Schema      json.RawMessage      `json:"schema"`
+	Mode        string               `json:"mode"`
+	MaxBodySize int64                `json:"maxBodySize"`
+	Scope       []parse.ModifierType `json:"scope"`
+}
+
+// NewSchemaValidatorModifier returns a Validator that validates JSON bodies
+// against schema, taking the action specified by mode on failure.
+func NewSchemaValidatorModifier(schema *Schema, mode Mode) *Validator {
+	return &Validator{
+		schema:  schema,
+		mode:    mode,
+		maxSize: DefaultMaxBodySize,
+	}
+}
+
+// SetMaxBodySize sets the maximum number of bytes buffered for validation.
+// Bodies larger than size are passed through unvalidated.
+func (v *Validator) SetMaxBodySize(size int64) {
+	v.maxSize = size
+}
+
+// ModifyRequest validates the request body if it is JSON. Invalid requests are
+// annotated or, in Reject mode, skip the round trip and receive a 400.
+func (v *Validator) ModifyRequest(req *http.Request) error {
+	errs, err := v.check(req.Header, &req.Body)
+	if err != nil || len(errs) == 0 {
+		return err
+	}
+
+	verr := validationError(errs)
+	ctx := martian.NewContext(req)
+	ctx.Set(ErrorsKey, errs)
+
+	if v.mode == Reject {
+		ctx.Set(rejectKey, true)
+		ctx.SkipRoundTrip()
+		return verr
+	}
+
+	proxyutil.Warning(req.Header, verr)
+	return nil
+}
+
+// ModifyResponse validates the response body if it is JSON. Invalid responses
+// are annotated or, in Reject mode, replaced with a 502. Responses to requests
+// rejected by ModifyRequest are set to 400.
+func (v *Validator) ModifyResponse(res *http.Response) error {
+	ctx := martian.NewContext(res.Request)
+
+	if rejected, _ := ctx.Get(rejectKey); rejected == true {
+		val, _ := ctx.Get(ErrorsKey)
+		verr := validationError(val.([]*ValidationError))
+		reject(res, 400, verr)
+		return verr
+	}
+
+	errs, err := v.check(res.Header, &res.Body)
+	if err != nil || len(errs) == 0 {
+		return err
+	}
+
+	verr := validationError(errs)
+	ctx.Set(ErrorsKey, errs)
+
+	if v.mode == Reject {
+		reject(res, 502, verr)
+		return verr
+	}
+
+	proxyutil.Warning(res.Header, verr)
+	return nil
+}
+
+// check buffers body and validates it if header declares a JSON content type.
+// body is replaced so that it may be read again.
+func (v *Validator) check(header http.Header, body *io.ReadCloser) ([]*ValidationError, error) {
+	if *body == nil || !isJSON(header.Get("Content-Type")) {
+		return nil, nil
+	}
+
+	rc := *body
+	buf, err := ioutil.ReadAll(io.LimitReader(rc, v.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buf)) > v.maxSize {
+		log.Debugf("schema: body exceeds %d bytes, skipping validation", v.maxSize)
+		*body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), rc), rc}
+		return nil, nil
+	}
+	rc.Close()
+	*body = ioutil.NopCloser(bytes.NewReader(buf))
+
+	data, ok, err := decode(header.Get("Content-Encoding"), buf)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		log.Debugf("schema: cannot decode Content-Encoding %q, skipping validation", header.Get("Content-Encoding"))
+		return nil, nil
+	}
+
+	errs, err := v.schema.Validate(data)
+	if err != nil {
+		return []*ValidationError{{Path: "/", Message: fmt.Sprintf("invalid JSON: %v", err)}}, nil
+	}
+
+	return errs, nil
+}
+
+// decode returns the decoded body for the given Content-Encoding, or false if
+// the encoding is not supported, like the decoding of messageview: identity,
+// gzip and deflate.
+func decode(encoding string, buf []byte) ([]byte, bool, error) {
+	var r io.Reader
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return buf, true, nil
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, true, err
+		}
+		defer gr.Close()
+		r = gr
+	case "deflate":
+		fr := flate.NewReader(bytes.NewReader(buf))
+		defer fr.Close()
+		r = fr
+	default:
+		return nil, false, nil
+	}
+
+	data, err := ioutil.ReadAll(r)
+	return data, true, err
+}
+
+func isJSON(ct string) bool {
+	ct = strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+func reject(res *http.Response, code int, err error) {
+	res.Body.Close()
+
+	body := []byte(err.Error())
+	res.StatusCode = code
+	res.Status = fmt.Sprintf("%d %s", code, http.StatusText(code))
+	res.Header = http.Header{}
+	res.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	res.ContentLength = int64(len(body))
+	res.TransferEncoding = nil
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+}
+
+func validationError(errs []*ValidationError) error {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+
+	return fmt.Errorf("schema: validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// validatorFromJSON builds a schema.Validator from JSON.
+//
+// Example JSON:
+// {
+//   "schema.Validator": {
+//     "scope": ["request", "response"],
+//     "mode": "reject",
+//     "maxBodySize": 1048576,
+//     "schema": {
+//       "type": "object",
+//       "required": ["id"]
+//     }
+//   }
+// }
+func validatorFromJSON(b []byte) (*parse.Result, error) {
+	msg := &validatorJSON{}
+	if err := json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+
+	s, err := Compile(msg.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var mode Mode
+	switch msg.Mode {
+	case "", "annotate":
+		mode = Annotate
+	case "reject":
+		mode = Reject
+	default:
+		return nil, fmt.Errorf("schema: unknown mode %q", msg.Mode)
+	}
+
+	v := NewSchemaValidatorModifier(s, mode)
+	if msg.MaxBodySize > 0 {
+		v.SetMaxBodySize(msg.MaxBodySize)
+	}
+
+	return parse.NewResult(v, msg.Scope)
+}
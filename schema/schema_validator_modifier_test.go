@@ -0,0 +1,246 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/martian/v3"
+	"github.com/google/martian/v3/parse"
+	"github.com/google/martian/v3/proxyutil"
+)
+
+const testSchema = `{"type": "object", "required": ["id"]}`
+
+func TestValidatorAnnotate(t *testing.T) {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Compile(): got %v, want no error", err)
+	}
+	m := NewSchemaValidatorModifier(s, Annotate)
+
+	req, err := http.NewRequest("POST", "http://example.com", strings.NewReader(`{"name": "x"}`))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+	if got, want := req.Header.Get("Warning"), `missing required property \"id\"`; !strings.Contains(got, want) {
+		t.Errorf("req.Header.Get(%q): got %q, want to contain %q", "Warning", got, want)
+	}
+	if ctx.SkippingRoundTrip() {
+		t.Error("ctx.SkippingRoundTrip(): got true, want false")
+	}
+	if _, ok := ctx.Get(ErrorsKey); !ok {
+		t.Errorf("ctx.Get(%q): got !ok, want ok", ErrorsKey)
+	}
+
+	got, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if want := `{"name": "x"}`; string(got) != want {
+		t.Errorf("req.Body: got %q, want %q", got, want)
+	}
+}
+
+func TestValidatorRejectRequest(t *testing.T) {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Compile(): got %v, want no error", err)
+	}
+	m := NewSchemaValidatorModifier(s, Reject)
+
+	req, err := http.NewRequest("POST", "http://example.com", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	ctx, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err == nil {
+		t.Fatal("ModifyRequest(): got nil, want validation error")
+	}
+	if !ctx.SkippingRoundTrip() {
+		t.Error("ctx.SkippingRoundTrip(): got false, want true")
+	}
+
+	res := proxyutil.NewResponse(200, nil, req)
+	if err := m.ModifyResponse(res); err == nil {
+		t.Fatal("ModifyResponse(): got nil, want validation error")
+	}
+	if got, want := res.StatusCode, 400; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestValidatorRejectCompressedResponse(t *testing.T) {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Compile(): got %v, want no error", err)
+	}
+	m := NewSchemaValidatorModifier(s, Reject)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	gw.Write([]byte(`{"id": 1}`))
+	gw.Close()
+
+	res := proxyutil.NewResponse(200, bytes.NewReader(buf.Bytes()), req)
+	res.Header.Set("Content-Type", "application/json")
+	res.Header.Set("Content-Encoding", "gzip")
+
+	if err := m.ModifyResponse(res); err != nil {
+		t.Fatalf("ModifyResponse(): got %v, want no error", err)
+	}
+	if got, want := res.StatusCode, 200; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+
+	res = proxyutil.NewResponse(200, strings.NewReader(`"not an object"`), req)
+	res.Header.Set("Content-Type", "application/problem+json")
+
+	if err := m.ModifyResponse(res); err == nil {
+		t.Fatal("ModifyResponse(): got nil, want validation error")
+	}
+	if got, want := res.StatusCode, 502; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+}
+
+func TestValidatorSkipsUnsupportedEncodings(t *testing.T) {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Compile(): got %v, want no error", err)
+	}
+	m := NewSchemaValidatorModifier(s, Reject)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	for _, enc := range []string{"br", "zstd", "gzip, br"} {
+		res := proxyutil.NewResponse(200, strings.NewReader("\x8b\x03binary"), req)
+		res.Header.Set("Content-Type", "application/json")
+		res.Header.Set("Content-Encoding", enc)
+
+		if err := m.ModifyResponse(res); err != nil {
+			t.Errorf("%s: ModifyResponse(): got %v, want no error", enc, err)
+		}
+		if got, want := res.StatusCode, 200; got != want {
+			t.Errorf("%s: res.StatusCode: got %d, want %d", enc, got, want)
+		}
+
+		got, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("%s: ioutil.ReadAll(): got %v, want no error", enc, err)
+		}
+		if want := "\x8b\x03binary"; string(got) != want {
+			t.Errorf("%s: res.Body: got %q, want %q", enc, got, want)
+		}
+	}
+}
+
+func TestValidatorSkipsLargeBodies(t *testing.T) {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Compile(): got %v, want no error", err)
+	}
+	m := NewSchemaValidatorModifier(s, Reject)
+	m.SetMaxBodySize(4)
+
+	req, err := http.NewRequest("POST", "http://example.com", strings.NewReader(`{"name": "x"}`))
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, remove, err := martian.TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("martian.TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if err := m.ModifyRequest(req); err != nil {
+		t.Fatalf("ModifyRequest(): got %v, want no error", err)
+	}
+
+	got, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll(): got %v, want no error", err)
+	}
+	if want := `{"name": "x"}`; string(got) != want {
+		t.Errorf("req.Body: got %q, want %q", got, want)
+	}
+}
+
+func TestValidatorFromJSON(t *testing.T) {
+	msg := []byte(`{
+		"schema.Validator": {
+			"scope": ["response"],
+			"mode": "reject",
+			"schema": {"type": "object"}
+		}
+	}`)
+
+	r, err := parse.FromJSON(msg)
+	if err != nil {
+		t.Fatalf("parse.FromJSON(): got %v, want no error", err)
+	}
+
+	if _, ok := r.ResponseModifier().(*Validator); !ok {
+		t.Fatal("r.ResponseModifier().(*Validator): got !ok, want ok")
+	}
+	if r.RequestModifier() != nil {
+		t.Error("r.RequestModifier(): got modifier, want nil")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// errSocketMarkUnsupported is returned when dialing a connection with a socket
+// mark on a platform without SO_MARK.
+var errSocketMarkUnsupported = errors.New("martian: socket marks are only supported on Linux")
+
+// martianContextKey is the key of the martian Context of a request in the
+// context.Context passed to the dial func.
+type martianContextKey struct{}
+
+// SetSocketMark sets the mark (SO_MARK) of the connection dialed to the origin
+// for the request, for use with fwmark-based policy routing on Linux. Call it
+// from a request modifier, before the round trip; for a CONNECT request, it
+// marks the tunnel's connection. Setting a mark requires CAP_NET_ADMIN, and
+// dials with a mark fail on other platforms.
+//
+// A marked connection is dialed with the dial func set with SetDial or
+// SetDialContext, and the mark is set on the connection it returns, so the
+// packets of the TCP handshake are not marked; without a dial func, the proxy
+// dials with its own net.Dialer, which marks the socket before connecting.
+// Either way, the address is resolved with the DNS-over-HTTPS resolver or the
+// DNS hook if one is set.
+//
+// An http.Transport pools connections by host, not by mark, so if the round
+// tripper is an *http.Transport, a marked request is sent on a connection of
+// its own that is closed after the response, and is not coalesced with other
+// requests. Other round trippers, including protocol.Transport, must keep
+// connections with different marks apart themselves.
+func (ctx *Context) SetSocketMark(mark uint32) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.socketMark = mark
+	ctx.hasSocketMark = true
+}
+
+// SocketMark returns the mark set with SetSocketMark and whether one was set.
+func (ctx *Context) SocketMark() (uint32, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+
+	return ctx.socketMark, ctx.hasSocketMark
+}
+
+// socketMark returns the socket mark set on the martian Context carried by
+// gctx, if any.
+func socketMark(gctx gocontext.Context) (uint32, bool) {
+	ctx, ok := gctx.Value(martianContextKey{}).(*Context)
+	if !ok {
+		return 0, false
+	}
+
+	return ctx.SocketMark()
+}
+
+// markedDialContext returns a dial func for connections with mark.
+func (p *Proxy) markedDialContext(mark uint32) func(gocontext.Context, string, string) (net.Conn, error) {
+	if !p.customDial {
+		return p.resolvingDialContext((&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   socketMarkControl(mark),
+		}).DialContext)
+	}
+
+	dial := p.baseDialContext
+	return p.resolvingDialContext(func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := setSocketMark(conn, mark); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	})
+}
+
+// setSocketMark sets the mark of the socket of conn, which must be a
+// syscall.Conn such as a *net.TCPConn.
+func setSocketMark(conn net.Conn, mark uint32) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("martian: cannot set socket mark on connection of type %T", conn)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return socketMarkControl(mark)("", "", rc)
+}
+
+// markedRoundTripper returns the round tripper for a request with a socket
+// mark. If the round tripper of the proxy is an *http.Transport, it is a copy
+// of it that does not keep connections alive, so that the request neither
+// reuses an idle connection dialed with another mark nor leaves its own for
+// other requests.
+func (p *Proxy) markedRoundTripper() http.RoundTripper {
+	tr, ok := p.roundTripper.(*http.Transport)
+	if !ok {
+		return p.roundTripper
+	}
+
+	tr = tr.Clone()
+	tr.DisableKeepAlives = true
+
+	return unpooledTransport{tr}
+}
+
+// unpooledTransport is an *http.Transport that does not keep connections
+// alive. The origin closes each connection at its request, which only
+// concerns the connection to the origin, so the responses are not marked to
+// close the connection with the client.
+type unpooledTransport struct {
+	tr *http.Transport
+}
+
+func (t unpooledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.tr.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Close = req.Close
+
+	return res, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	gocontext "context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/martiantest"
+)
+
+func TestSocketMarkDial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer l.Close()
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+
+	if _, ok := ctx.SocketMark(); ok {
+		t.Fatal("ctx.SocketMark(): got mark, want none")
+	}
+	ctx.SetSocketMark(42)
+
+	p := NewProxy()
+	gctx := gocontext.WithValue(gocontext.Background(), martianContextKey{}, ctx)
+	conn, err := p.dialContext(gctx, "tcp", l.Addr().String())
+	if isPermission(err) {
+		t.Skipf("setting SO_MARK needs CAP_NET_ADMIN: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("p.dialContext(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	rc, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn(): got %v, want no error", err)
+	}
+	var mark int
+	var gerr error
+	rc.Control(func(fd uintptr) {
+		mark, gerr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK)
+	})
+	if gerr != nil {
+		t.Fatalf("syscall.GetsockoptInt(): got %v, want no error", gerr)
+	}
+	if got, want := mark, 42; got != want {
+		t.Errorf("SO_MARK: got %d, want %d", got, want)
+	}
+}
+
+func TestSocketMarkCustomDial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer l.Close()
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	ctx, remove, err := TestContext(req, nil, nil)
+	if err != nil {
+		t.Fatalf("TestContext(): got %v, want no error", err)
+	}
+	defer remove()
+	ctx.SetSocketMark(42)
+
+	p := NewProxy()
+
+	// The marked connection is dialed with the dial func of the proxy.
+	var dials int
+	p.SetDial(func(network, addr string) (net.Conn, error) {
+		dials++
+		return net.Dial(network, addr)
+	})
+
+	gctx := gocontext.WithValue(gocontext.Background(), martianContextKey{}, ctx)
+	conn, err := p.dialContext(gctx, "tcp", l.Addr().String())
+	if isPermission(err) {
+		t.Skipf("setting SO_MARK needs CAP_NET_ADMIN: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("p.dialContext(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	if got, want := dials, 1; got != want {
+		t.Errorf("dials: got %d, want %d", got, want)
+	}
+
+	rc, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn(): got %v, want no error", err)
+	}
+	var mark int
+	var gerr error
+	rc.Control(func(fd uintptr) {
+		mark, gerr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK)
+	})
+	if gerr != nil {
+		t.Fatalf("syscall.GetsockoptInt(): got %v, want no error", gerr)
+	}
+	if got, want := mark, 42; got != want {
+		t.Errorf("SO_MARK: got %d, want %d", got, want)
+	}
+}
+
+func TestIntegrationSocketMarkConnections(t *testing.T) {
+	// The origin records the client address of the connection of each
+	// request.
+	var addrs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		addrs = append(addrs, req.RemoteAddr)
+	}))
+	defer srv.Close()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	p.SetRoundTripper(&http.Transport{})
+	p.SetTimeout(2 * time.Second)
+	p.SetRequestModifier(RequestModifierFunc(func(req *http.Request) error {
+		if req.Header.Get("X-Mark") != "" {
+			NewContext(req).SetSocketMark(7)
+		}
+		return nil
+	}))
+
+	go p.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	for i, mark := range []bool{false, true, false, true} {
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(): got %v, want no error", err)
+		}
+		if mark {
+			req.Header.Set("X-Mark", "true")
+		}
+		if err := req.WriteProxy(conn); err != nil {
+			t.Fatalf("%d: req.WriteProxy(): got %v, want no error", i, err)
+		}
+		res, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatalf("%d: http.ReadResponse(): got %v, want no error", i, err)
+		}
+		res.Body.Close()
+
+		if mark && res.StatusCode == 502 {
+			t.Skip("setting SO_MARK needs CAP_NET_ADMIN")
+		}
+		if got, want := res.StatusCode, 200; got != want {
+			t.Fatalf("%d: res.StatusCode: got %d, want %d", i, got, want)
+		}
+	}
+
+	// Unmarked requests share a connection; each marked request gets its own.
+	if len(addrs) != 4 {
+		t.Fatalf("addrs: got %d requests, want 4", len(addrs))
+	}
+	if addrs[0] != addrs[2] {
+		t.Errorf("unmarked requests: got connections %s and %s, want the same", addrs[0], addrs[2])
+	}
+	for _, i := range []int{1, 3} {
+		for j, addr := range addrs {
+			if j != i && addr == addrs[i] {
+				t.Errorf("marked request %d: got connection %s of request %d, want its own", i, addrs[i], j)
+			}
+		}
+	}
+}
+
+// isPermission returns whether err was caused by a missing privilege.
+func isPermission(err error) bool {
+	for err != nil {
+		if err == syscall.EPERM {
+			return true
+		}
+		switch e := err.(type) {
+		case *net.OpError:
+			err = e.Err
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+func TestSocketMarkRequestContext(t *testing.T) {
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	tr := martiantest.NewTransport()
+	p.SetRoundTripper(tr)
+	p.SetTimeout(2 * time.Second)
+
+	// The dial func reads the mark set by request modifiers through the context
+	// of the request.
+	var got uint32
+	var ok bool
+	tr.Func(func(req *http.Request) (*http.Response, error) {
+		got, ok = socketMark(req.Context())
+		return &http.Response{StatusCode: 204, Body: http.NoBody, Request: req}, nil
+	})
+	p.SetRequestModifier(RequestModifierFunc(func(req *http.Request) error {
+		NewContext(req).SetSocketMark(7)
+		return nil
+	}))
+
+	go p.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest(): got %v, want no error", err)
+	}
+	if err := req.WriteProxy(conn); err != nil {
+		t.Fatalf("req.WriteProxy(): got %v, want no error", err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatalf("http.ReadResponse(): got %v, want no error", err)
+	}
+	res.Body.Close()
+
+	if !ok || got != 7 {
+		t.Errorf("socketMark(req.Context()): got %d, %t, want 7, true", got, ok)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import "sync"
+
+// Store persists state across connections. Unlike Session values, which are
+// discarded when the connection closes, values in a Store are shared by every
+// connection handled by the proxy, so modifiers typically key them by client
+// identity (such as the host of Session.RemoteAddr or an authenticated user).
+//
+// The proxy never removes values from a Store; eviction and expiry are the
+// responsibility of the Store implementation. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the value associated with key, if any.
+	Get(key string) (interface{}, bool)
+	// Set associates val with key, replacing any existing value.
+	Set(key string, val interface{})
+	// Delete removes the value associated with key.
+	Delete(key string)
+}
+
+// SetSessionStore sets the store shared by the sessions of all connections
+// accepted after the call. Modifiers access it with Context.Store.
+func (p *Proxy) SetSessionStore(store Store) {
+	p.store = store
+}
+
+// MemoryStore is an in-memory Store. Values are kept until they are deleted;
+// it performs no eviction.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	vals map[string]interface{}
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		vals: make(map[string]interface{}),
+	}
+}
+
+// Get returns the value associated with key, if any.
+func (s *MemoryStore) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, ok := s.vals[key]
+
+	return val, ok
+}
+
+// Set associates val with key.
+func (s *MemoryStore) Set(key string, val interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.vals[key] = val
+}
+
+// Delete removes the value associated with key.
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.vals, key)
+}
@@ -0,0 +1,39 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import "testing"
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+
+	if _, ok := s.Get("key"); ok {
+		t.Errorf("s.Get(%q): got ok, want not ok", "key")
+	}
+
+	s.Set("key", "value")
+	got, ok := s.Get("key")
+	if !ok {
+		t.Fatalf("s.Get(%q): got not ok, want ok", "key")
+	}
+	if want := "value"; got != want {
+		t.Errorf("s.Get(%q): got %v, want %v", "key", got, want)
+	}
+
+	s.Delete("key")
+	if _, ok := s.Get("key"); ok {
+		t.Errorf("s.Get(%q): got ok, want not ok", "key")
+	}
+}
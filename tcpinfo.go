@@ -0,0 +1,60 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// errTCPInfoUnsupported is returned by readTCPInfo where TCP_INFO is not
+// available.
+var errTCPInfoUnsupported = errors.New("martian: TCP info is not supported on this platform")
+
+// TCPInfo holds statistics kept by the kernel for a TCP connection, as
+// reported by the TCP_INFO socket option on Linux.
+type TCPInfo struct {
+	// RTT is the smoothed round trip time estimated for the connection.
+	RTT time.Duration
+	// RTTVar is the variation of RTT.
+	RTTVar time.Duration
+	// Retransmits is the total number of segments retransmitted.
+	Retransmits uint32
+	// Lost is the number of segments currently thought to be lost.
+	Lost uint32
+	// SendCongestionWindow is the congestion window, in segments.
+	SendCongestionWindow uint32
+}
+
+// TCPInfo returns the TCP statistics of the client connection of the session,
+// read from the kernel when it is called. It returns an error if the client
+// did not connect over TCP, or on platforms other than Linux.
+func (s *Session) TCPInfo() (*TCPInfo, error) {
+	s.mu.RLock()
+	conn := s.conn
+	if s.stats != nil {
+		// The connection as accepted, before any TLS handshake.
+		conn = s.stats.conn
+	}
+	s.mu.RUnlock()
+
+	tconn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil, errors.New("martian: client connection is not a TCP connection")
+	}
+
+	return readTCPInfo(tconn)
+}
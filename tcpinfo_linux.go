@@ -0,0 +1,57 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && !386
+// +build linux,!386
+
+package martian
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// readTCPInfo reads the TCP_INFO socket option of conn.
+func readTCPInfo(conn *net.TCPConn) (*TCPInfo, error) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ti syscall.TCPInfo
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		size := uint32(syscall.SizeofTCPInfo)
+		_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, fd, syscall.IPPROTO_TCP, syscall.TCP_INFO,
+			uintptr(unsafe.Pointer(&ti)), uintptr(unsafe.Pointer(&size)), 0)
+		if errno != 0 {
+			serr = errno
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if serr != nil {
+		return nil, serr
+	}
+
+	return &TCPInfo{
+		RTT:                  time.Duration(ti.Rtt) * time.Microsecond,
+		RTTVar:               time.Duration(ti.Rttvar) * time.Microsecond,
+		Retransmits:          ti.Total_retrans,
+		Lost:                 ti.Lost,
+		SendCongestionWindow: ti.Snd_cwnd,
+	}, nil
+}
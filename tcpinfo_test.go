@@ -0,0 +1,68 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestSessionTCPInfo(t *testing.T) {
+	client, server := tcpPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	// Exchange some data so the kernel has measured the round trip time.
+	go io.Copy(server, server)
+	buf := make([]byte, 4)
+	for i := 0; i < 3; i++ {
+		client.Write([]byte("ping"))
+		if _, err := io.ReadFull(client, buf); err != nil {
+			t.Fatalf("io.ReadFull(): got %v, want no error", err)
+		}
+	}
+
+	s, err := newSession(server, nil)
+	if err != nil {
+		t.Fatalf("newSession(): got %v, want no error", err)
+	}
+
+	info, err := s.TCPInfo()
+	if err == errTCPInfoUnsupported {
+		t.Skip("TCP info is not supported on this platform")
+	}
+	if err != nil {
+		t.Fatalf("s.TCPInfo(): got %v, want no error", err)
+	}
+	if info.RTT <= 0 {
+		t.Errorf("info.RTT: got %v, want > 0", info.RTT)
+	}
+	if info.SendCongestionWindow == 0 {
+		t.Error("info.SendCongestionWindow: got 0, want > 0")
+	}
+
+	pc, ps := net.Pipe()
+	defer pc.Close()
+	defer ps.Close()
+
+	s, err = newSession(ps, nil)
+	if err != nil {
+		t.Fatalf("newSession(): got %v, want no error", err)
+	}
+	if _, err := s.TCPInfo(); err == nil {
+		t.Error("s.TCPInfo(): got no error for non-TCP connection, want error")
+	}
+}
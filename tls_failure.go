@@ -0,0 +1,111 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+)
+
+// TLSFailureReason classifies why a MITM'd TLS connection failed.
+type TLSFailureReason int
+
+const (
+	// TLSFailureHandshake is a failed TLS handshake, such as a client that
+	// rejects the MITM certificate.
+	TLSFailureHandshake TLSFailureReason = iota
+	// TLSFailureClientClosed is a client that closed or reset the connection
+	// before sending a request, during or after the handshake.
+	TLSFailureClientClosed
+	// TLSFailureProtocol is an error reading the first request after a
+	// successful handshake, such as a malformed record or request.
+	TLSFailureProtocol
+)
+
+// String returns the name of the reason.
+func (r TLSFailureReason) String() string {
+	switch r {
+	case TLSFailureHandshake:
+		return "handshake"
+	case TLSFailureClientClosed:
+		return "client closed"
+	case TLSFailureProtocol:
+		return "protocol"
+	}
+
+	return "unknown"
+}
+
+// TLSFailure describes a MITM'd TLS connection that failed before a request
+// was read from it.
+type TLSFailure struct {
+	// Reason classifies the failure.
+	Reason TLSFailureReason
+	// ServerName is the SNI sent by the client, or the host of the CONNECT
+	// request if the client did not send one.
+	ServerName string
+	// Version is the negotiated TLS version, such as tls.VersionTLS13, or 0 if
+	// the handshake failed before a version was negotiated.
+	Version uint16
+	// Err is the underlying error.
+	Err error
+}
+
+// SetOnTLSFailure sets a callback that is called when a MITM'd TLS connection
+// fails before its first request is read, with the reason for the failure.
+func (p *Proxy) SetOnTLSFailure(cb func(gocontext.Context, TLSFailure)) {
+	p.onTLSFailure = cb
+}
+
+// reportTLSFailure calls the TLS failure callback, if set, for err on tlsconn.
+// host is the CONNECT host, used when the client did not send an SNI.
+func (p *Proxy) reportTLSFailure(gctx gocontext.Context, tlsconn *tls.Conn, host string, err error) {
+	if p.onTLSFailure == nil {
+		return
+	}
+
+	cs := tlsconn.ConnectionState()
+
+	f := TLSFailure{
+		Reason:     classifyTLSFailure(cs.HandshakeComplete, err),
+		ServerName: cs.ServerName,
+		Version:    cs.Version,
+		Err:        err,
+	}
+	if f.ServerName == "" {
+		if h, _, serr := net.SplitHostPort(host); serr == nil {
+			host = h
+		}
+		f.ServerName = host
+	}
+
+	p.onTLSFailure(gctx, f)
+}
+
+// classifyTLSFailure returns the reason for err, given whether the handshake
+// had completed.
+func classifyTLSFailure(handshakeComplete bool, err error) TLSFailureReason {
+	switch {
+	case err == io.EOF, err == io.ErrUnexpectedEOF, strings.Contains(err.Error(), "connection reset by peer"):
+		return TLSFailureClientClosed
+	case !handshakeComplete:
+		return TLSFailureHandshake
+	}
+
+	return TLSFailureProtocol
+}
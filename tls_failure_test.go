@@ -0,0 +1,138 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	gocontext "context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/martiantest"
+	"github.com/google/martian/v3/mitm"
+)
+
+func TestIntegrationTLSFailure(t *testing.T) {
+	t.Parallel()
+
+	ca, priv, err := mitm.NewAuthority("martian.proxy", "Martian Authority", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("mitm.NewAuthority(): got %v, want no error", err)
+	}
+	mc, err := mitm.NewConfig(ca, priv)
+	if err != nil {
+		t.Fatalf("mitm.NewConfig(): got %v, want no error", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	tt := []struct {
+		name        string
+		roots       *x509.CertPool
+		after       func(*tls.Conn)
+		want        TLSFailureReason
+		wantVersion bool
+	}{
+		{
+			name:  "untrusted certificate",
+			roots: x509.NewCertPool(),
+			want:  TLSFailureHandshake,
+		},
+		{
+			name:        "client closed",
+			roots:       roots,
+			after:       func(c *tls.Conn) { c.Close() },
+			want:        TLSFailureClientClosed,
+			wantVersion: true,
+		},
+		{
+			name:  "malformed request",
+			roots: roots,
+			after: func(c *tls.Conn) {
+				c.Write([]byte("not http\r\n\r\n"))
+			},
+			want:        TLSFailureProtocol,
+			wantVersion: true,
+		},
+	}
+
+	for _, tc := range tt {
+		l, err := net.Listen("tcp", "[::]:0")
+		if err != nil {
+			t.Fatalf("%s: net.Listen(): got %v, want no error", tc.name, err)
+		}
+
+		p := NewProxy()
+		p.SetRoundTripper(martiantest.NewTransport())
+		p.SetTimeout(5 * time.Second)
+		p.SetMITM(mc)
+
+		failc := make(chan TLSFailure, 1)
+		p.SetOnTLSFailure(func(ctx gocontext.Context, f TLSFailure) {
+			failc <- f
+		})
+
+		go p.Serve(l)
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("%s: net.Dial(): got %v, want no error", tc.name, err)
+		}
+
+		req, err := http.NewRequest("CONNECT", "//example.com:443", nil)
+		if err != nil {
+			t.Fatalf("%s: http.NewRequest(): got %v, want no error", tc.name, err)
+		}
+		if err := req.Write(conn); err != nil {
+			t.Fatalf("%s: req.Write(): got %v, want no error", tc.name, err)
+		}
+		res, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			t.Fatalf("%s: http.ReadResponse(): got %v, want no error", tc.name, err)
+		}
+		res.Body.Close()
+
+		tlsconn := tls.Client(conn, &tls.Config{
+			ServerName: "example.com",
+			RootCAs:    tc.roots,
+		})
+		if err := tlsconn.Handshake(); err == nil && tc.after != nil {
+			tc.after(tlsconn)
+		}
+
+		select {
+		case f := <-failc:
+			if got, want := f.Reason, tc.want; got != want {
+				t.Errorf("%s: f.Reason: got %v, want %v (err: %v)", tc.name, got, want, f.Err)
+			}
+			if got, want := f.ServerName, "example.com"; got != want {
+				t.Errorf("%s: f.ServerName: got %q, want %q", tc.name, got, want)
+			}
+			if tc.wantVersion && f.Version == 0 {
+				t.Errorf("%s: f.Version: got 0, want negotiated version", tc.name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: OnTLSFailure: got no call, want %v", tc.name, tc.want)
+		}
+
+		tlsconn.Close()
+		l.Close()
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/google/martian/v3/internal/clock"
 	"github.com/google/martian/v3/log"
 )
 
@@ -30,7 +31,7 @@ type Bucket struct {
 	fill     int64 // atomic
 	mu       sync.Mutex
 
-	t      *time.Ticker
+	t      clock.Ticker
 	closec chan struct{}
 }
 
@@ -44,9 +45,14 @@ var (
 // NewBucket returns a new leaky bucket with capacity that is drained
 // at interval.
 func NewBucket(capacity int64, interval time.Duration) *Bucket {
+	return newBucket(capacity, interval, clock.Real)
+}
+
+// newBucket returns a new leaky bucket drained at interval as measured by clk.
+func newBucket(capacity int64, interval time.Duration, clk clock.Clock) *Bucket {
 	b := &Bucket{
 		capacity: capacity,
-		t:        time.NewTicker(interval),
+		t:        clk.NewTicker(interval),
 		closec:   make(chan struct{}),
 	}
 
@@ -195,7 +201,7 @@ func (b *Bucket) loop() {
 
 	for {
 		select {
-		case t := <-b.t.C:
+		case t := <-b.t.C():
 			atomic.StoreInt64(&b.fill, 0)
 			log.Debugf("trafficshape: fill reset @ %s", t)
 		case <-b.closec:
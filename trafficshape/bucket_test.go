@@ -20,6 +20,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/martian/v3/internal/clock"
 )
 
 func TestBucket(t *testing.T) {
@@ -186,3 +188,31 @@ func TestBucketFillThrottleCloseBeforeTick(t *testing.T) {
 		t.Errorf("b.FillThrottle(): got nil, want errFillClosedBucket")
 	}
 }
+
+func TestBucketDrainFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+
+	b := newBucket(10, time.Second, fake)
+	defer b.Close()
+
+	fill := func(n int64) {
+		if _, err := b.Fill(func(int64) (int64, error) { return n, nil }); err != nil {
+			t.Fatalf("Fill(): got %v, want no error", err)
+		}
+	}
+
+	fill(10)
+	fake.Advance(999 * time.Millisecond)
+	if got, want := atomic.LoadInt64(&b.fill), int64(10); got != want {
+		t.Fatalf("b.fill before drain: got %d, want %d", got, want)
+	}
+
+	fake.Advance(time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&b.fill) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("b.fill: not drained after interval")
+		}
+		runtime.Gosched()
+	}
+}
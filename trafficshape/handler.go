@@ -211,7 +211,7 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	// Update the Listener with the new traffic shape.
 	h.l.Shapes.Lock()
 
-	h.l.Shapes.LastModifiedTime = time.Now()
+	h.l.Shapes.LastModifiedTime = h.l.clock.Now()
 	h.l.ReadBucket.SetCapacity(defaults.Bandwidth.Down)
 	h.l.WriteBucket.SetCapacity(defaults.Bandwidth.Up)
 	h.l.SetLatency(time.Duration(defaults.Latency) * time.Millisecond)
@@ -222,7 +222,7 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		h.l.Shapes.M[shape.URLRegex] = &urlShape{Shape: shape}
 	}
 	// Update the time that the map was last modified to the current time.
-	h.l.Shapes.LastModifiedTime = time.Now()
+	h.l.Shapes.LastModifiedTime = h.l.clock.Now()
 	h.l.Shapes.Unlock()
 
 	rw.WriteHeader(http.StatusOK)
@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/martian/v3/internal/clock"
 	"github.com/google/martian/v3/log"
 )
 
@@ -116,6 +117,7 @@ type Listener struct {
 	GlobalBuckets map[string]*Bucket
 	Shapes        *urlShapes
 	defaults      *Default
+	clock         clock.Clock
 }
 
 // Conn wraps a net.Conn and simulates connection latency and bandwidth
@@ -147,6 +149,7 @@ func NewListener(l net.Listener) *Listener {
 		WriteBucket:   NewBucket(DefaultBitrate/8, time.Second),
 		Shapes:        &urlShapes{M: make(map[string]*urlShape)},
 		GlobalBuckets: make(map[string]*Bucket),
+		clock:         clock.Real,
 		defaults: &Default{
 			Bandwidth: Bandwidth{
 				Up:   DefaultBitrate / 8,
@@ -157,6 +160,12 @@ func NewListener(l net.Listener) *Listener {
 	}
 }
 
+// setClock sets the clock used for latency, halts and timestamps, in place of
+// the real clock. It is for tests.
+func (l *Listener) setClock(c clock.Clock) {
+	l.clock = c
+}
+
 // ReadBitrate returns the bitrate in bits per second for reads.
 func (l *Listener) ReadBitrate() int64 {
 	return l.ReadBucket.Capacity() * 8
@@ -240,7 +249,7 @@ func (l *Listener) GetTrafficShapedConn(oc net.Conn) *Conn {
 		GlobalBuckets:    globalurlbuckets,
 		LocalBuckets:     urlbuckets,
 		Context:          curinfo,
-		Established:      time.Now(),
+		Established:      l.clock.Now(),
 		DefaultBandwidth: defaultBandwidth,
 		Listener:         l,
 	}
@@ -626,7 +635,7 @@ func (c *Conn) Write(b []byte) (int, error) {
 						d, c.Context.URLRegex, c.Context.ByteOffset)
 					c.Shapes.M[c.Context.URLRegex].Unlock()
 					c.Shapes.RUnlock()
-					time.Sleep(time.Duration(d) * time.Millisecond)
+					c.Listener.clock.Sleep(time.Duration(d) * time.Millisecond)
 				case *CloseConnection:
 					log.Infof("trafficshape: Closing connection for urlregex %s at byte offset %d",
 						c.Context.URLRegex, c.Context.ByteOffset)
@@ -657,5 +666,5 @@ func (c *Conn) Write(b []byte) (int, error) {
 
 func (c *Conn) sleepLatency() {
 	log.Debugf("trafficshape: simulating latency: %s", c.latency)
-	time.Sleep(c.latency)
+	c.Listener.clock.Sleep(c.latency)
 }
@@ -24,6 +24,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/google/martian/v3/internal/clock"
 )
 
 func TestListenerRead(t *testing.T) {
@@ -122,6 +124,61 @@ func TestListenerRead(t *testing.T) {
 	}
 }
 
+func TestListenerLatencyFakeClock(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	fake := clock.NewFake(time.Now())
+
+	tsl := NewListener(l)
+	defer tsl.Close()
+	tsl.setClock(fake)
+	tsl.SetLatency(time.Hour)
+
+	c, err := net.Dial("tcp", tsl.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+	defer c.Close()
+	c.Write([]byte("x"))
+
+	conn, err := tsl.Accept()
+	if err != nil {
+		t.Fatalf("tsl.Accept(): got %v, want no error", err)
+	}
+	defer conn.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	// The first read waits for the latency to pass on the fake clock.
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("conn.Read(): returned before latency elapsed")
+	default:
+	}
+
+	fake.Advance(time.Hour)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("conn.Read(): got %v, want no error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("conn.Read(): did not return after latency elapsed")
+	}
+}
+
 func TestListenerWrite(t *testing.T) {
 	t.Parallel()
 
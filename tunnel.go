@@ -0,0 +1,52 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// tunnelBufferPools holds a *sync.Pool of *[]byte buffers for each tunnel
+// buffer size in use.
+var tunnelBufferPools sync.Map
+
+// copyConn copies from src to dst until EOF or an error. If dst implements
+// io.ReaderFrom or src implements io.WriterTo, as *net.TCPConn does, the
+// connections copy the data themselves, with splice(2) between TCP
+// connections on Linux. Otherwise the data is copied through a pooled buffer
+// of bufSize bytes.
+func copyConn(dst, src net.Conn, bufSize int) (int64, error) {
+	if _, ok := src.(io.WriterTo); ok {
+		return io.Copy(dst, src)
+	}
+	if _, ok := dst.(io.ReaderFrom); ok {
+		return io.Copy(dst, src)
+	}
+
+	v, _ := tunnelBufferPools.LoadOrStore(bufSize, &sync.Pool{
+		New: func() interface{} {
+			buf := make([]byte, bufSize)
+			return &buf
+		},
+	})
+	pool := v.(*sync.Pool)
+
+	buf := pool.Get().(*[]byte)
+	defer pool.Put(buf)
+
+	return io.CopyBuffer(dst, src, *buf)
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/martian/v3/internal/clock"
+)
+
+// errTunnelIdle is returned by reads from an idleConn once no data has flowed
+// through the tunnel in either direction for the idle timeout.
+var errTunnelIdle = errors.New("martian: CONNECT tunnel idle")
+
+// idleConn is one side of a CONNECT tunnel with an idle timeout. Both sides of
+// a tunnel share the time of the last activity, so that a tunnel with traffic
+// in only one direction is not considered idle.
+//
+// The activity is timed with clk, while the read deadline that wakes up a
+// waiting Read to check for idleness is set on the connection in wall clock
+// time.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+	clk     clock.Clock
+	last    *int64
+}
+
+// newIdleConns wraps both sides of a CONNECT tunnel to close it once it has
+// been idle for timeout, as measured by clk.
+func newIdleConns(a, b net.Conn, timeout time.Duration, clk clock.Clock) (net.Conn, net.Conn) {
+	last := clk.Now().UnixNano()
+
+	return &idleConn{Conn: a, timeout: timeout, clk: clk, last: &last},
+		&idleConn{Conn: b, timeout: timeout, clk: clk, last: &last}
+}
+
+// Read reads from the connection, returning errTunnelIdle if neither side of
+// the tunnel has read any data for the idle timeout.
+func (c *idleConn) Read(b []byte) (int, error) {
+	for {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+
+		n, err := c.Conn.Read(b)
+		if n > 0 {
+			atomic.StoreInt64(c.last, c.clk.Now().UnixNano())
+		}
+
+		if neterr, ok := err.(net.Error); ok && neterr.Timeout() && n == 0 {
+			last := time.Unix(0, atomic.LoadInt64(c.last))
+			if c.clk.Now().Sub(last) < c.timeout {
+				// The other side of the tunnel is active.
+				continue
+			}
+
+			return 0, errTunnelIdle
+		}
+
+		return n, err
+	}
+}
+
+// Write writes to the connection, extending the write deadline so that an
+// active tunnel outlives the deadline of the client connection.
+func (c *idleConn) Write(b []byte) (int, error) {
+	c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/internal/clock"
+)
+
+func TestIdleConnUsesClock(t *testing.T) {
+	a, apeer := net.Pipe()
+	b, bpeer := net.Pipe()
+	defer apeer.Close()
+	defer bpeer.Close()
+
+	fake := clock.NewFake(time.Now())
+	ia, _ := newIdleConns(a, b, 20*time.Millisecond, fake)
+	defer ia.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := ia.Read(make([]byte, 1))
+		errc <- err
+	}()
+
+	// The read deadline passes in wall clock time, but the tunnel is not idle
+	// until the clock says so.
+	select {
+	case err := <-errc:
+		t.Fatalf("ia.Read(): got %v before the clock advanced, want it to block", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fake.Advance(20 * time.Millisecond)
+
+	select {
+	case err := <-errc:
+		if err != errTunnelIdle {
+			t.Fatalf("ia.Read(): got %v, want %v", err, errTunnelIdle)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ia.Read(): timed out waiting for errTunnelIdle")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(t testing.TB) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+	defer l.Close()
+
+	connc := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			connc <- nil
+			return
+		}
+		connc <- conn
+	}()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial(): got %v, want no error", err)
+	}
+
+	server := <-connc
+	if server == nil {
+		t.Fatal("l.Accept(): failed to accept connection")
+	}
+
+	return client, server
+}
+
+func TestCopyConn(t *testing.T) {
+	want := make([]byte, 1<<20)
+	if _, err := rand.Read(want); err != nil {
+		t.Fatalf("rand.Read(): got %v, want no error", err)
+	}
+
+	tt := []struct {
+		name string
+		wrap func(net.Conn) net.Conn
+	}{
+		{"tcp", func(conn net.Conn) net.Conn { return conn }},
+		{"buffered", func(conn net.Conn) net.Conn { return plainConn{conn} }},
+	}
+
+	for _, tc := range tt {
+		// src -> (srcPeer -> dstPeer) -> dst
+		src, srcPeer := tcpPair(t)
+		dstPeer, dst := tcpPair(t)
+		defer srcPeer.Close()
+		defer dstPeer.Close()
+		defer dst.Close()
+
+		go func() {
+			src.Write(want)
+			src.Close()
+		}()
+
+		errc := make(chan error, 1)
+		go func() {
+			_, err := copyConn(tc.wrap(dstPeer), tc.wrap(srcPeer), 4<<10)
+			dstPeer.Close()
+			errc <- err
+		}()
+
+		got, err := ioutil.ReadAll(dst)
+		if err != nil {
+			t.Fatalf("%s: ioutil.ReadAll(): got %v, want no error", tc.name, err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("%s: copyConn(): got %v, want no error", tc.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: copyConn(): got %d bytes, want %d identical bytes", tc.name, len(got), len(want))
+		}
+	}
+}
+
+func benchmarkTunnel(b *testing.B, copy func(dst, src net.Conn) (int64, error)) {
+	const size = 1 << 20
+	buf := make([]byte, 32*1024)
+
+	src, srcPeer := tcpPair(b)
+	dstPeer, dst := tcpPair(b)
+	defer src.Close()
+	defer srcPeer.Close()
+	defer dstPeer.Close()
+	defer dst.Close()
+
+	go copy(dstPeer, srcPeer)
+
+	b.SetBytes(size)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		go func() {
+			for n := 0; n < size; n += len(buf) {
+				src.Write(buf)
+			}
+		}()
+
+		if _, err := io.CopyN(ioutil.Discard, dst, size); err != nil {
+			b.Fatalf("io.CopyN(): got %v, want no error", err)
+		}
+	}
+}
+
+func BenchmarkTunnelCopyConn(b *testing.B) {
+	benchmarkTunnel(b, func(dst, src net.Conn) (int64, error) {
+		return copyConn(dst, src, defaultTunnelBufferSize)
+	})
+}
+
+// plainConn hides the net.TCPConn implementation, as the wrappers of tunnels
+// with an idle timeout do, so that copyConn copies through a pooled buffer.
+type plainConn struct {
+	net.Conn
+}
+
+func BenchmarkTunnelBufferSize(b *testing.B) {
+	for _, size := range []int{4 << 10, 32 << 10, 128 << 10, 512 << 10} {
+		size := size
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			benchmarkTunnel(b, func(dst, src net.Conn) (int64, error) {
+				return copyConn(plainConn{dst}, plainConn{src}, size)
+			})
+		})
+	}
+}
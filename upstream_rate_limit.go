@@ -0,0 +1,228 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// UpstreamRateLimit reports the state of the rate limit of an upstream host,
+// as set with SetUpstreamRateLimit.
+type UpstreamRateLimit struct {
+	// Host is the host the limit applies to.
+	Host string
+	// RPS is the number of requests per second allowed to the host.
+	RPS float64
+	// Waiting is the number of requests waiting for the limit.
+	Waiting int
+	// Utilization is the fraction of the allowed burst of requests in use,
+	// from 0 when the host is idle to 1 when requests must wait.
+	Utilization float64
+}
+
+// rateLimitError is the error of requests that would wait for the rate limit
+// of their host beyond their deadline.
+type rateLimitError struct {
+	host string
+	wait time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("martian: rate limit of %s exceeded, retry in %v", e.host, e.wait)
+}
+
+// retryAfter returns the value of the Retry-After header for e, in whole
+// seconds.
+func (e *rateLimitError) retryAfter() string {
+	return strconv.Itoa(int((e.wait + time.Second - 1) / time.Second))
+}
+
+// SetUpstreamRateLimit limits the rate of round trips to host to rps
+// requests per second, to spare the origin, with bursts of up to rps
+// requests, and at least one, after a quiet period. Round trips over the
+// limit wait for their turn; those that would wait beyond the request
+// timeout are answered with a 503 Service Unavailable and a Retry-After
+// header without reaching the origin. Unlike limits on clients, the limit is
+// shared by all clients of the proxy. host is matched against the host of
+// request URLs, without the port. A rate of zero or less removes the limit of
+// host.
+//
+// The state of the limits is reported by UpstreamRateLimits.
+func (p *Proxy) SetUpstreamRateLimit(host string, rps float64) {
+	p.rateLimits.set(strings.ToLower(host), rps, p.clock.Now())
+}
+
+// UpstreamRateLimits returns the state of the upstream rate limits set with
+// SetUpstreamRateLimit, sorted by host.
+func (p *Proxy) UpstreamRateLimits() []UpstreamRateLimit {
+	return p.rateLimits.stats(p.clock.Now())
+}
+
+// waitRateLimit waits until the round trip of req is allowed by the rate
+// limit of its host. It returns a *rateLimitError, without waiting, if the
+// wait would exceed the deadline of req.
+func (p *Proxy) waitRateLimit(req *http.Request) error {
+	host := strings.ToLower(req.URL.Hostname())
+	now := p.clock.Now()
+	b, wait := p.rateLimits.reserve(host, now)
+	if b == nil || wait <= 0 {
+		return nil
+	}
+
+	ctx := req.Context()
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < wait {
+		p.rateLimits.cancel(b, false)
+		return &rateLimitError{host: host, wait: wait}
+	}
+
+	log.HostDebugf(host, "martian: waiting %v for rate limit of %s", wait, host)
+	t := p.clock.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-t.C():
+		p.rateLimits.cancel(b, true)
+		return nil
+	case <-ctx.Done():
+		p.rateLimits.cancel(b, false)
+		return ctx.Err()
+	}
+}
+
+// rateLimits holds token buckets keyed by host.
+type rateLimits struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket allows rps requests per second with bursts of burst requests.
+// Tokens go negative as requests reserve them ahead of time.
+type tokenBucket struct {
+	rps     float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiting int
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rps
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+func (l *rateLimits) set(host string, rps float64, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rps <= 0 {
+		delete(l.buckets, host)
+		return
+	}
+
+	burst := float64(int(rps))
+	if burst < 1 {
+		burst = 1
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+	l.buckets[host] = &tokenBucket{
+		rps:    rps,
+		burst:  burst,
+		tokens: burst,
+		last:   now,
+	}
+}
+
+// enabled returns whether any host is rate limited.
+func (l *rateLimits) enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets) > 0
+}
+
+// reserve takes a token for a request to host and returns the bucket it was
+// taken from and how long the request must wait for it. It returns a nil
+// bucket if host is not rate limited.
+func (l *rateLimits) reserve(host string, now time.Time) (*tokenBucket, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+	if !ok {
+		return nil, 0
+	}
+
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return b, 0
+	}
+	b.waiting++
+
+	return b, time.Duration(-b.tokens / b.rps * float64(time.Second))
+}
+
+// cancel ends the wait of a request for a token of b, returning the token
+// unless the request proceeds. b may have been replaced by a later call to
+// SetUpstreamRateLimit, in which case only b is affected.
+func (l *rateLimits) cancel(b *tokenBucket, proceed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b.waiting--
+	if !proceed {
+		b.tokens++
+	}
+}
+
+func (l *rateLimits) stats(now time.Time) []UpstreamRateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]UpstreamRateLimit, 0, len(l.buckets))
+	for host, b := range l.buckets {
+		b.refill(now)
+
+		util := 1 - b.tokens/b.burst
+		if util > 1 {
+			util = 1
+		}
+		stats = append(stats, UpstreamRateLimit{
+			Host:        host,
+			RPS:         b.rps,
+			Waiting:     b.waiting,
+			Utilization: util,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Host < stats[j].Host
+	})
+
+	return stats
+}
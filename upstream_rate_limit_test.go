@@ -0,0 +1,227 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/martian/v3/internal/clock"
+	"github.com/google/martian/v3/martiantest"
+)
+
+// rateLimitRoundTrip sends a GET request for url through the proxy at addr
+// and returns the response.
+func rateLimitRoundTrip(t *testing.T, addr, url string) *http.Response {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Errorf("net.Dial(): got %v, want no error", err)
+		return nil
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Errorf("http.NewRequest(): got %v, want no error", err)
+		return nil
+	}
+	if err := req.WriteProxy(conn); err != nil {
+		t.Errorf("req.WriteProxy(): got %v, want no error", err)
+		return nil
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Errorf("http.ReadResponse(): got %v, want no error", err)
+		return nil
+	}
+	res.Body.Close()
+
+	return res
+}
+
+func TestIntegrationUpstreamRateLimit(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	fake := clock.NewFake(time.Now())
+	p.setClock(fake)
+
+	tr := martiantest.NewTransport()
+	tr.Respond(200)
+	p.SetRoundTripper(tr)
+	p.SetUpstreamRateLimit("Example.com", 1)
+
+	go p.Serve(l)
+
+	if res := rateLimitRoundTrip(t, l.Addr().String(), "http://example.com"); res == nil || res.StatusCode != 200 {
+		t.Fatalf("first request: got %v, want 200 OK", res)
+	}
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		done <- rateLimitRoundTrip(t, l.Addr().String(), "http://example.com:80/second")
+	}()
+
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Other hosts are not limited.
+	if res := rateLimitRoundTrip(t, l.Addr().String(), "http://example.org"); res == nil || res.StatusCode != 200 {
+		t.Fatalf("request to other host: got %v, want 200 OK", res)
+	}
+
+	limits := p.UpstreamRateLimits()
+	if got, want := len(limits), 1; got != want {
+		t.Fatalf("len(p.UpstreamRateLimits()): got %d, want %d", got, want)
+	}
+	if got, want := limits[0], (UpstreamRateLimit{Host: "example.com", RPS: 1, Waiting: 1, Utilization: 1}); got != want {
+		t.Errorf("p.UpstreamRateLimits()[0]: got %+v, want %+v", got, want)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("second request: got response before the rate limit allowed it")
+	default:
+	}
+
+	fake.Advance(time.Second)
+
+	if res := <-done; res == nil || res.StatusCode != 200 {
+		t.Fatalf("second request: got %v, want 200 OK", res)
+	}
+	if got, want := p.UpstreamRateLimits()[0].Waiting, 0; got != want {
+		t.Errorf("p.UpstreamRateLimits()[0].Waiting: got %d, want %d", got, want)
+	}
+
+	fake.Advance(time.Second)
+	if got, want := p.UpstreamRateLimits()[0].Utilization, 0.0; got != want {
+		t.Errorf("p.UpstreamRateLimits()[0].Utilization: got %v, want %v", got, want)
+	}
+}
+
+func TestIntegrationUpstreamRateLimitDeadline(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	var trips int32
+	tr := martiantest.NewTransport()
+	tr.Func(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&trips, 1)
+		return &http.Response{StatusCode: 200, Body: http.NoBody, Request: req}, nil
+	})
+	p.SetRoundTripper(tr)
+	p.SetTimeout(time.Second)
+	p.SetUpstreamRateLimit("example.com", 0.1)
+
+	go p.Serve(l)
+
+	if res := rateLimitRoundTrip(t, l.Addr().String(), "http://example.com"); res == nil || res.StatusCode != 200 {
+		t.Fatalf("first request: got %v, want 200 OK", res)
+	}
+
+	start := time.Now()
+	res := rateLimitRoundTrip(t, l.Addr().String(), "http://example.com")
+	if res == nil {
+		t.FailNow()
+	}
+	if got, want := res.StatusCode, 503; got != want {
+		t.Errorf("res.StatusCode: got %d, want %d", got, want)
+	}
+	if got, want := res.Header.Get("Retry-After"), "10"; got != want {
+		t.Errorf("res.Header.Get(%q): got %q, want %q", "Retry-After", got, want)
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Errorf("request took %v, want less than the timeout", d)
+	}
+	if got, want := atomic.LoadInt32(&trips), int32(1); got != want {
+		t.Errorf("round trips: got %d, want %d", got, want)
+	}
+	if got, want := p.UpstreamRateLimits()[0].Waiting, 0; got != want {
+		t.Errorf("p.UpstreamRateLimits()[0].Waiting: got %d, want %d", got, want)
+	}
+
+	p.SetUpstreamRateLimit("example.com", 0)
+	if got := len(p.UpstreamRateLimits()); got != 0 {
+		t.Errorf("len(p.UpstreamRateLimits()): got %d after removing the limit, want 0", got)
+	}
+}
+
+func TestIntegrationUpstreamRateLimitReplaced(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): got %v, want no error", err)
+	}
+
+	p := NewProxy()
+	defer p.Close()
+
+	fake := clock.NewFake(time.Now())
+	p.setClock(fake)
+
+	tr := martiantest.NewTransport()
+	tr.Respond(200)
+	p.SetRoundTripper(tr)
+	p.SetUpstreamRateLimit("example.com", 1)
+
+	go p.Serve(l)
+
+	if res := rateLimitRoundTrip(t, l.Addr().String(), "http://example.com"); res == nil || res.StatusCode != 200 {
+		t.Fatalf("first request: got %v, want 200 OK", res)
+	}
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		done <- rateLimitRoundTrip(t, l.Addr().String(), "http://example.com")
+	}()
+
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Replacing the limit does not count the request waiting for the old one.
+	p.SetUpstreamRateLimit("example.com", 2)
+	fake.Advance(time.Second)
+
+	if res := <-done; res == nil || res.StatusCode != 200 {
+		t.Fatalf("second request: got %v, want 200 OK", res)
+	}
+	if got, want := p.UpstreamRateLimits()[0].Waiting, 0; got != want {
+		t.Errorf("p.UpstreamRateLimits()[0].Waiting: got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/martian/v3/log"
+)
+
+// WebSocket frame opcodes.
+//
+// https://tools.ietf.org/html/rfc6455#section-5.2
+const (
+	WebSocketContinuation byte = 0x0
+	WebSocketText         byte = 0x1
+	WebSocketBinary       byte = 0x2
+	WebSocketClose        byte = 0x8
+	WebSocketPing         byte = 0x9
+	WebSocketPong         byte = 0xA
+)
+
+// maxWebSocketFramePayload is the largest frame payload read when frames are
+// passed to a FrameModifier. Larger frames close the connection.
+const maxWebSocketFramePayload = 32 << 20
+
+// maxWebSocketControlPayload is the largest payload allowed in a control frame.
+const maxWebSocketControlPayload = 125
+
+// WebSocketFrame is a single frame of a WebSocket connection. Payload is never
+// masked; the proxy unmasks client frames before they are modified and masks
+// them again, with a new key, when they are forwarded.
+type WebSocketFrame struct {
+	// Fin is set on the final frame of a message.
+	Fin bool
+	// Rsv holds the RSV1, RSV2 and RSV3 bits in its three low bits.
+	Rsv byte
+	// Opcode is the frame opcode, such as WebSocketText.
+	Opcode byte
+	// Payload is the unmasked application data of the frame.
+	Payload []byte
+}
+
+// IsControl returns whether the frame is a close, ping or pong frame.
+func (f *WebSocketFrame) IsControl() bool {
+	return f.Opcode&0x8 != 0
+}
+
+// FrameModifier inspects and modifies the frames of WebSocket connections
+// relayed by the proxy.
+//
+// Frames are passed one at a time as they are copied: a fragmented message is
+// seen as a first frame carrying the message opcode followed by
+// WebSocketContinuation frames, and control frames may arrive between the
+// fragments. The payload of a frame may be replaced with one of any length,
+// except that control frames are limited to 125 bytes; a control frame whose
+// payload is made larger is forwarded unmodified. Errors are logged and the
+// frame is forwarded as modified.
+//
+// ModifyClientFrame and ModifyServerFrame are called concurrently from the
+// goroutines copying each direction of the connection. req is the upgrade
+// request, and NewContext(req) returns its context.
+type FrameModifier interface {
+	// ModifyClientFrame modifies a frame sent by the client.
+	ModifyClientFrame(req *http.Request, frame *WebSocketFrame) error
+	// ModifyServerFrame modifies a frame sent by the server.
+	ModifyServerFrame(req *http.Request, frame *WebSocketFrame) error
+}
+
+// SetFrameModifier sets the modifier called with each frame of the WebSocket
+// connections relayed by the proxy. Since compressed payloads cannot be
+// modified, the Sec-WebSocket-Extensions header is removed from upgrade
+// requests while a frame modifier is set.
+func (p *Proxy) SetFrameModifier(framemod FrameModifier) {
+	p.framemod = framemod
+}
+
+// isWebSocketUpgrade returns whether h requests or accepts an upgrade to the
+// WebSocket protocol.
+func isWebSocketUpgrade(h http.Header) bool {
+	return strings.EqualFold(h.Get("Upgrade"), "websocket")
+}
+
+// switchProtocols writes the 101 Switching Protocols response res to the client
+// and relays data between the client and the upgraded upstream connection until
+// either side closes. WebSocket frames are passed through framemod, if not nil.
+func (p *Proxy) switchProtocols(req *http.Request, res *http.Response, conn net.Conn, brw *bufio.ReadWriter, framemod FrameModifier) error {
+	host := hostname(req.Host)
+
+	uconn, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		log.HostErrorf(host, "martian: switching protocols response body is not writable")
+		return errClose
+	}
+	defer uconn.Close()
+
+	if err := write1xx(brw, res.StatusCode, res.Header); err != nil {
+		log.HostErrorf(host, "martian: got error while writing response back to client: %v", err)
+		return errClose
+	}
+
+	// The upgraded connection is long-lived; the request timeout no longer
+	// applies.
+	conn.SetDeadline(time.Time{})
+
+	if !isWebSocketUpgrade(res.Header) {
+		framemod = nil
+	}
+
+	copySync := func(dst io.Writer, src io.Reader, client bool, donec chan<- bool) {
+		var err error
+		switch {
+		case framemod == nil:
+			_, err = io.Copy(dst, src)
+		case client:
+			err = copyFrames(dst, src, true, func(f *WebSocketFrame) error {
+				return framemod.ModifyClientFrame(req, f)
+			})
+		default:
+			err = copyFrames(dst, src, false, func(f *WebSocketFrame) error {
+				return framemod.ModifyServerFrame(req, f)
+			})
+		}
+		if err != nil && err != io.EOF && !isClosedConnError(err) {
+			log.HostErrorf(host, "martian: failed to copy upgraded connection: %v", err)
+		}
+
+		donec <- true
+	}
+
+	donec := make(chan bool, 2)
+	go copySync(uconn, brw.Reader, true, donec)
+	go copySync(conn, uconn, false, donec)
+
+	log.HostDebugf(host, "martian: switched protocols to %s, proxying traffic", res.Header.Get("Upgrade"))
+	<-donec
+
+	// Unblock the other direction.
+	uconn.Close()
+	conn.SetReadDeadline(time.Now())
+
+	<-donec
+	log.HostDebugf(host, "martian: closed upgraded connection")
+
+	return errClose
+}
+
+// isClosedConnError returns whether err is the result of using a connection
+// that has been closed or whose deadline has passed.
+func isClosedConnError(err error) bool {
+	if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// copyFrames copies WebSocket frames from src to dst, calling modify with each
+// frame. Frames read from the client are masked and are masked again with a
+// new key when written.
+func copyFrames(dst io.Writer, src io.Reader, client bool, modify func(*WebSocketFrame) error) error {
+	br, ok := src.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(src)
+	}
+	bw := bufio.NewWriter(dst)
+
+	for {
+		f, err := readFrame(br)
+		if err != nil {
+			return err
+		}
+
+		orig := f.Payload
+		if err := modify(f); err != nil {
+			log.Errorf("martian: error modifying WebSocket frame: %v", err)
+		}
+		if f.IsControl() {
+			if len(f.Payload) > maxWebSocketControlPayload {
+				log.Errorf("martian: modified WebSocket control frame payload too large, forwarding original")
+				f.Payload = orig
+			}
+			f.Fin = true
+		}
+
+		if err := writeFrame(bw, f, client); err != nil {
+			return err
+		}
+		// Only flush once no further frames are buffered, so that bursts of
+		// small frames are written together.
+		if br.Buffered() == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readFrame reads a single WebSocket frame from br, unmasking its payload.
+func readFrame(br *bufio.Reader) (*WebSocketFrame, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	f := &WebSocketFrame{
+		Fin:    hdr[0]&0x80 != 0,
+		Rsv:    (hdr[0] >> 4) & 0x7,
+		Opcode: hdr[0] & 0xf,
+	}
+	masked := hdr[1]&0x80 != 0
+
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxWebSocketFramePayload {
+		return nil, fmt.Errorf("martian: WebSocket frame payload of %d bytes exceeds limit of %d bytes", n, maxWebSocketFramePayload)
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(br, key[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	f.Payload = make([]byte, n)
+	if _, err := io.ReadFull(br, f.Payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		maskBytes(key, f.Payload)
+	}
+
+	return f, nil
+}
+
+// writeFrame writes f to w, masking its payload with a random key if masked is
+// set. The payload of f is not modified.
+func writeFrame(w io.Writer, f *WebSocketFrame, masked bool) error {
+	var hdr [14]byte
+	hdr[0] = (f.Rsv&0x7)<<4 | f.Opcode&0xf
+	if f.Fin {
+		hdr[0] |= 0x80
+	}
+
+	n := len(f.Payload)
+	i := 2
+	switch {
+	case n <= 125:
+		hdr[1] = byte(n)
+	case n <= 0xffff:
+		hdr[1] = 126
+		binary.BigEndian.PutUint16(hdr[2:], uint16(n))
+		i += 2
+	default:
+		hdr[1] = 127
+		binary.BigEndian.PutUint64(hdr[2:], uint64(n))
+		i += 8
+	}
+
+	payload := f.Payload
+	if masked {
+		hdr[1] |= 0x80
+
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		copy(hdr[i:], key[:])
+		i += 4
+
+		payload = make([]byte, n)
+		copy(payload, f.Payload)
+		maskBytes(key, payload)
+	}
+
+	if _, err := w.Write(hdr[:i]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+
+	return err
+}
+
+// maskBytes masks or unmasks b in place with key.
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
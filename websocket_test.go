@@ -0,0 +1,203 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package martian
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type frameModifier struct{}
+
+func (frameModifier) ModifyClientFrame(req *http.Request, f *WebSocketFrame) error {
+	if !f.IsControl() {
+		f.Payload = bytes.ToUpper(f.Payload)
+	}
+	return nil
+}
+
+func (frameModifier) ModifyServerFrame(req *http.Request, f *WebSocketFrame) error {
+	if !f.IsControl() {
+		f.Payload = append(f.Payload, '!')
+	}
+	return nil
+}
+
+// newWebSocketEchoServer returns a server that accepts WebSocket upgrades and
+// echoes every frame it receives.
+func newWebSocketEchoServer(t *testing.T, extc chan<- string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		extc <- req.Header.Get("Sec-WebSocket-Extensions")
+
+		conn, brw, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack(): got %v, want no error", err)
+			return
+		}
+		defer conn.Close()
+
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n\r\n")
+		brw.Flush()
+
+		for {
+			f, err := readFrame(brw.Reader)
+			if err != nil {
+				return
+			}
+			if err := writeFrame(conn, f, false); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestIntegrationWebSocketFrames(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		framemod FrameModifier
+		wantExt  string
+		want     []*WebSocketFrame
+	}{
+		{
+			name:    "relay",
+			wantExt: "permessage-deflate",
+			want: []*WebSocketFrame{
+				{Fin: false, Opcode: WebSocketText, Payload: []byte("he")},
+				{Fin: true, Opcode: WebSocketPing, Payload: []byte("ping")},
+				{Fin: true, Opcode: WebSocketContinuation, Payload: []byte("llo")},
+			},
+		},
+		{
+			name:     "frame modifier",
+			framemod: frameModifier{},
+			wantExt:  "",
+			want: []*WebSocketFrame{
+				{Fin: false, Opcode: WebSocketText, Payload: []byte("HE!")},
+				{Fin: true, Opcode: WebSocketPing, Payload: []byte("ping")},
+				{Fin: true, Opcode: WebSocketContinuation, Payload: []byte("LLO!")},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		extc := make(chan string, 1)
+		server := newWebSocketEchoServer(t, extc)
+		defer server.Close()
+
+		l, err := net.Listen("tcp", "[::]:0")
+		if err != nil {
+			t.Fatalf("%s: net.Listen(): got %v, want no error", tc.name, err)
+		}
+
+		p := NewProxy()
+		defer p.Close()
+
+		p.SetTimeout(time.Second)
+		if tc.framemod != nil {
+			p.SetFrameModifier(tc.framemod)
+		}
+
+		go p.Serve(l)
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("%s: net.Dial(): got %v, want no error", tc.name, err)
+		}
+		defer conn.Close()
+
+		req, err := http.NewRequest("GET", server.URL+"/socket", nil)
+		if err != nil {
+			t.Fatalf("%s: http.NewRequest(): got %v, want no error", tc.name, err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate")
+
+		if err := req.WriteProxy(conn); err != nil {
+			t.Fatalf("%s: req.WriteProxy(): got %v, want no error", tc.name, err)
+		}
+
+		br := bufio.NewReader(conn)
+		res, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatalf("%s: http.ReadResponse(): got %v, want no error", tc.name, err)
+		}
+		if got, want := res.StatusCode, 101; got != want {
+			t.Fatalf("%s: res.StatusCode: got %d, want %d", tc.name, got, want)
+		}
+		if got := <-extc; got != tc.wantExt {
+			t.Errorf("%s: Sec-WebSocket-Extensions: got %q, want %q", tc.name, got, tc.wantExt)
+		}
+
+		// A fragmented message with a ping between its fragments.
+		frames := []*WebSocketFrame{
+			{Fin: false, Opcode: WebSocketText, Payload: []byte("he")},
+			{Fin: true, Opcode: WebSocketPing, Payload: []byte("ping")},
+			{Fin: true, Opcode: WebSocketContinuation, Payload: []byte("llo")},
+		}
+		for _, f := range frames {
+			if err := writeFrame(conn, f, true); err != nil {
+				t.Fatalf("%s: writeFrame(): got %v, want no error", tc.name, err)
+			}
+		}
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for i, want := range tc.want {
+			got, err := readFrame(br)
+			if err != nil {
+				t.Fatalf("%s: %d: readFrame(): got %v, want no error", tc.name, i, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %d: readFrame(): got %+v, want %+v", tc.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestWebSocketFrameRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xffff, 0x10000} {
+		for _, masked := range []bool{false, true} {
+			want := &WebSocketFrame{
+				Fin:     true,
+				Rsv:     0x4,
+				Opcode:  WebSocketBinary,
+				Payload: bytes.Repeat([]byte("x"), n),
+			}
+
+			var buf bytes.Buffer
+			if err := writeFrame(&buf, want, masked); err != nil {
+				t.Fatalf("writeFrame(%d, %t): got %v, want no error", n, masked, err)
+			}
+
+			got, err := readFrame(bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatalf("readFrame(%d, %t): got %v, want no error", n, masked, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("readFrame(%d, %t): got %+v, want %+v", n, masked, got, want)
+			}
+		}
+	}
+}